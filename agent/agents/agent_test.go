@@ -2,11 +2,26 @@ package agents
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/lex00/wetwire-core-go/agent/events"
+	"github.com/lex00/wetwire-core-go/agent/history"
+	"github.com/lex00/wetwire-core-go/agent/permissions"
 	"github.com/lex00/wetwire-core-go/agent/results"
+	"github.com/lex00/wetwire-core-go/domain"
 	"github.com/lex00/wetwire-core-go/mcp"
 	"github.com/lex00/wetwire-core-go/providers"
+	"github.com/lex00/wetwire-core-go/providers/fake"
+	"github.com/lex00/wetwire-core-go/providers/kiro"
 )
 
 // testAgentProvider is a test provider that returns predefined responses.
@@ -273,3 +288,1540 @@ func TestMCPServerAdapter(t *testing.T) {
 		t.Error("expected error for non-existent tool")
 	}
 }
+
+func TestAgent_Run_FakeProvider(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("write_file", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+		return fmt.Sprintf("wrote %v", args["path"]), nil
+	})
+	server.RegisterTool("run_lint", "Run the linter", func(ctx context.Context, args map[string]any) (string, error) {
+		return "", fmt.Errorf("lint failed")
+	})
+
+	provider := fake.New().
+		ExpectSystem("test agent").
+		ExpectTools("write_file", "run_lint").
+		CallTools(
+			fake.Call("write_file", map[string]any{"path": "main.go"}),
+			fake.Call("run_lint", nil),
+		).
+		ExpectToolResult("wrote main.go").
+		ReplyText("Done")
+
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		SystemPrompt: "You are a test agent",
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	if err := agent.Run(context.Background(), "test prompt"); err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	if provider.Remaining() != 0 {
+		t.Errorf("expected script to be exhausted, %d turns remaining", provider.Remaining())
+	}
+
+	toolResults := provider.ToolResults()
+	if len(toolResults) != 2 {
+		t.Fatalf("expected 2 tool results, got %d", len(toolResults))
+	}
+	if toolResults[0].IsError {
+		t.Error("expected write_file result to succeed")
+	}
+	if !toolResults[1].IsError {
+		t.Error("expected run_lint result to be an error")
+	}
+}
+
+func TestAgent_Run_RecordsUsage(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+
+	provider := fake.New().Respond(&providers.MessageResponse{
+		Content:    []providers.ContentBlock{{Type: "text", Text: "Done"}},
+		StopReason: providers.StopReasonEndTurn,
+		Usage:      providers.Usage{InputTokens: 1000, OutputTokens: 200},
+	})
+
+	session := results.NewSession("test", "test-scenario")
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		Model:        "claude-sonnet-4-20250514",
+		MCPServer:    NewMCPServerAdapter(server),
+		Session:      session,
+		SystemPrompt: "You are a test agent",
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	if err := agent.Run(context.Background(), "test prompt"); err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	if len(session.Usage) != 1 {
+		t.Fatalf("expected 1 usage entry, got %d", len(session.Usage))
+	}
+	if got := session.TotalUsage().TotalTokens(); got != 1200 {
+		t.Errorf("expected 1200 tokens, got %d", got)
+	}
+	if session.TotalCost() <= 0 {
+		t.Error("expected non-zero cost for a priced model")
+	}
+}
+
+func TestAgent_Run_Thinking(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("write_file", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+		return "wrote", nil
+	})
+
+	provider := fake.New().
+		Respond(&providers.MessageResponse{
+			Content: []providers.ContentBlock{
+				{Type: "thinking", Thinking: "A bucket needs versioning", Signature: "sig-1"},
+				{Type: "tool_use", ID: "tool_1", Name: "write_file", Input: json.RawMessage(`{}`)},
+			},
+			StopReason: providers.StopReasonToolUse,
+		}).
+		ReplyText("Done")
+
+	session := results.NewSession("test", "test-scenario")
+	agent, err := NewAgent(AgentConfig{
+		Provider:       provider,
+		MCPServer:      NewMCPServerAdapter(server),
+		Session:        session,
+		SystemPrompt:   "You are a test agent",
+		ThinkingBudget: 2048,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	if err := agent.Run(context.Background(), "test prompt"); err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	requests := provider.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	if requests[0].ThinkingBudget != 2048 {
+		t.Errorf("expected thinking budget 2048, got %d", requests[0].ThinkingBudget)
+	}
+
+	// The thinking block is echoed back in the tool-use continuation
+	assistant := requests[1].Messages[1]
+	if len(assistant.Content) == 0 || assistant.Content[0].Signature != "sig-1" {
+		t.Errorf("expected thinking block to be echoed back, got %+v", assistant.Content)
+	}
+
+	if len(session.Thinking) != 1 || session.Thinking[0].Content != "A bucket needs versioning" {
+		t.Errorf("expected thinking to be recorded, got %+v", session.Thinking)
+	}
+}
+
+func TestNewAgent_ValidatesCapabilities(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("run_lint", "Run the linter", func(ctx context.Context, args map[string]any) (string, error) {
+		return "ok", nil
+	})
+
+	tests := []struct {
+		name      string
+		caps      providers.Capabilities
+		maxTokens int
+		wantError bool
+		wantMax   int
+	}{
+		{
+			name:    "raw provider uses default max tokens",
+			caps:    providers.Capabilities{ToolCalling: true, SystemPrompt: true, MaxOutputTokens: 64000},
+			wantMax: providers.DefaultMaxTokens,
+		},
+		{
+			name:    "default capped at model limit",
+			caps:    providers.Capabilities{ToolCalling: true, SystemPrompt: true, MaxOutputTokens: 2048},
+			wantMax: 2048,
+		},
+		{
+			name:      "explicit max tokens above model limit",
+			caps:      providers.Capabilities{ToolCalling: true, SystemPrompt: true, MaxOutputTokens: 8192},
+			maxTokens: 16000,
+			wantError: true,
+		},
+		{
+			name:      "no tool calling",
+			caps:      providers.Capabilities{SystemPrompt: true},
+			wantError: true,
+		},
+		{
+			name:      "no system prompt",
+			caps:      providers.Capabilities{ToolCalling: true},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, err := NewAgent(AgentConfig{
+				Provider:     fake.New().WithCapabilities(tt.caps),
+				MCPServer:    NewMCPServerAdapter(server),
+				SystemPrompt: "You are a test agent",
+				MaxTokens:    tt.maxTokens,
+			})
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if agent.maxTokens != tt.wantMax {
+				t.Errorf("expected max tokens %d, got %d", tt.wantMax, agent.maxTokens)
+			}
+		})
+	}
+}
+
+func TestNewAgent_Kiro(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("run_lint", "Run the linter", func(ctx context.Context, args map[string]any) (string, error) {
+		return "ok", nil
+	})
+
+	provider, err := kiro.New(kiro.Config{AgentName: "wetwire-runner"})
+	if err != nil {
+		t.Fatalf("kiro.New() error = %v", err)
+	}
+
+	if _, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		SystemPrompt: "You are a test agent",
+	}); err != nil {
+		t.Errorf("NewAgent() with kiro error = %v", err)
+	}
+
+	if _, err := NewRunnerAgent(RunnerConfig{
+		Domain:   DomainConfig{Name: "aws", CLICommand: "wetwire-aws", SystemPrompt: "You generate AWS infrastructure"},
+		Provider: provider,
+		WorkDir:  t.TempDir(),
+	}); err != nil {
+		t.Errorf("NewRunnerAgent() with kiro error = %v", err)
+	}
+}
+
+func TestAgent_Run_CompactsHistory(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("run_build", "Build the template", func(ctx context.Context, args map[string]any) (string, error) {
+		return strings.Repeat("Resources: ", 1000), nil
+	})
+
+	provider := fake.New()
+	for i := 0; i < 6; i++ {
+		provider.CallTool("run_build", nil)
+	}
+	provider.ReplyText("Done")
+
+	session := results.NewSession("test", "test-scenario")
+	agent, err := NewAgent(AgentConfig{
+		Provider:         provider,
+		MCPServer:        NewMCPServerAdapter(server),
+		Session:          session,
+		SystemPrompt:     "You are a test agent",
+		HistoryThreshold: 8000,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	if err := agent.Run(context.Background(), "test prompt"); err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	if len(session.Compactions) == 0 {
+		t.Fatal("expected the history to be compacted")
+	}
+	c := session.Compactions[0]
+	if c.Policy != "truncate_tool_results+drop_old_turns" {
+		t.Errorf("unexpected policy %q", c.Policy)
+	}
+	if c.TokensAfter >= c.TokensBefore {
+		t.Errorf("expected fewer tokens after compaction, got %d -> %d", c.TokensBefore, c.TokensAfter)
+	}
+
+	// Every request stays within the threshold
+	for i, req := range provider.Requests() {
+		if tokens := history.EstimateTokens(req.Messages); tokens > 8000 {
+			t.Errorf("request %d has %d tokens of history", i, tokens)
+		}
+	}
+}
+
+func TestAgent_Run_Middleware(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("write_file", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+		return "wrote", nil
+	})
+
+	provider := fake.New().
+		CallTool("write_file", map[string]any{"path": "main.go"}).
+		ReplyText("Done")
+
+	var latencies []time.Duration
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		SystemPrompt: "You are a test agent",
+		Middleware: providers.Chain(
+			providers.Intercept(providers.Interceptor{
+				BeforeRequest: func(ctx context.Context, req *providers.MessageRequest) error {
+					req.Model = "haiku"
+					return nil
+				},
+			}),
+			providers.Intercept(providers.Interceptor{
+				AfterResponse: func(ctx context.Context, req providers.MessageRequest, resp *providers.MessageResponse, err error, elapsed time.Duration) {
+					latencies = append(latencies, elapsed)
+				},
+			}),
+		),
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	if err := agent.Run(context.Background(), "test prompt"); err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	if len(latencies) != 2 {
+		t.Errorf("expected 2 responses through the middleware, got %d", len(latencies))
+	}
+	for i, req := range provider.Requests() {
+		if req.Model != "haiku" {
+			t.Errorf("request %d: expected model override, got %q", i, req.Model)
+		}
+	}
+}
+
+// blockingProvider waits until the request context ends.
+type blockingProvider struct{}
+
+func (blockingProvider) CreateMessage(ctx context.Context, req providers.MessageRequest) (*providers.MessageResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (p blockingProvider) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
+	return p.CreateMessage(ctx, req)
+}
+
+func (blockingProvider) Name() string {
+	return "blocking"
+}
+
+func TestAgent_Run_Budget(t *testing.T) {
+	newServer := func() *mcp.Server {
+		server := mcp.NewServer(mcp.Config{Name: "test"})
+		server.RegisterTool("run_lint", "Run the linter", func(ctx context.Context, args map[string]any) (string, error) {
+			return "1 issue", nil
+		})
+		server.RegisterTool("write_file", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+			return "wrote", nil
+		})
+		return server
+	}
+
+	// loop scripts a model that keeps calling run_lint
+	loop := func(n int) *fake.Provider {
+		provider := fake.New()
+		for i := 0; i < n; i++ {
+			provider.Respond(&providers.MessageResponse{
+				Content:    []providers.ContentBlock{{Type: "tool_use", ID: fmt.Sprintf("t%d", i), Name: "run_lint", Input: json.RawMessage(`{}`)}},
+				StopReason: providers.StopReasonToolUse,
+				Usage:      providers.Usage{InputTokens: 100, OutputTokens: 50},
+			})
+		}
+		return provider
+	}
+
+	tests := []struct {
+		name     string
+		provider providers.Provider
+		budget   Budget
+		limit    BudgetLimit
+		tool     string
+		calls    int
+	}{
+		{name: "turns", provider: loop(10), budget: Budget{MaxTurns: 3}, limit: BudgetTurns, calls: 3},
+		{name: "default turns", provider: loop(DefaultMaxTurns + 1), limit: BudgetTurns, calls: DefaultMaxTurns},
+		{name: "tokens", provider: loop(10), budget: Budget{MaxTotalTokens: 400}, limit: BudgetTokens, calls: 3},
+		{name: "tool calls", provider: loop(10), budget: Budget{MaxToolCalls: 2}, limit: BudgetToolCalls, tool: "run_lint", calls: 3},
+		{name: "tool call override", provider: loop(10), budget: Budget{MaxToolCalls: 5, ToolCallLimits: map[string]int{"run_lint": 1}}, limit: BudgetToolCalls, tool: "run_lint", calls: 2},
+		{name: "duration", provider: blockingProvider{}, budget: Budget{MaxDuration: 20 * time.Millisecond}, limit: BudgetDuration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := results.NewSession("test", "test")
+			agent, err := NewAgent(AgentConfig{
+				Provider:     tt.provider,
+				MCPServer:    NewMCPServerAdapter(newServer()),
+				SystemPrompt: "You are a test agent",
+				Session:      session,
+				Budget:       tt.budget,
+			})
+			if err != nil {
+				t.Fatalf("failed to create agent: %v", err)
+			}
+
+			err = agent.Run(context.Background(), "test prompt")
+			if !errors.Is(err, ErrBudgetExceeded) {
+				t.Fatalf("expected budget error, got %v", err)
+			}
+
+			var budgetErr *BudgetExceededError
+			if !errors.As(err, &budgetErr) {
+				t.Fatalf("expected *BudgetExceededError, got %T", err)
+			}
+			if budgetErr.Limit != tt.limit || budgetErr.Tool != tt.tool {
+				t.Errorf("expected %s limit on %q, got %s on %q", tt.limit, tt.tool, budgetErr.Limit, budgetErr.Tool)
+			}
+
+			if session.BudgetExceeded == nil || session.BudgetExceeded.Limit != string(tt.limit) {
+				t.Errorf("expected budget recorded in session, got %+v", session.BudgetExceeded)
+			}
+
+			if f, ok := tt.provider.(*fake.Provider); ok && len(f.Requests()) != tt.calls {
+				t.Errorf("expected %d requests, got %d", tt.calls, len(f.Requests()))
+			}
+		})
+	}
+}
+
+func TestAgent_Run_StopReasons(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+
+	t.Run("max tokens continues", func(t *testing.T) {
+		provider := fake.New().
+			Respond(&providers.MessageResponse{
+				Content: []providers.ContentBlock{
+					{Type: "text", Text: "Here is the first half"},
+					{Type: "tool_use", ID: "t1", Name: "write_file", Input: json.RawMessage(`{"path":`)},
+				},
+				StopReason: providers.StopReasonMaxTokens,
+			}).
+			ReplyText("and the second half")
+
+		agent, err := NewAgent(AgentConfig{
+			Provider:     provider,
+			MCPServer:    NewMCPServerAdapter(server),
+			SystemPrompt: "You are a test agent",
+		})
+		if err != nil {
+			t.Fatalf("failed to create agent: %v", err)
+		}
+		if err := agent.Run(context.Background(), "test prompt"); err != nil {
+			t.Fatalf("agent run failed: %v", err)
+		}
+
+		req, _ := provider.LastRequest()
+		if len(req.Messages) != 3 {
+			t.Fatalf("expected 3 messages, got %d", len(req.Messages))
+		}
+		truncated := req.Messages[1].Content
+		if len(truncated) != 1 || truncated[0].Text != "Here is the first half" {
+			t.Errorf("expected incomplete tool call to be dropped, got %+v", truncated)
+		}
+		if !strings.Contains(req.Messages[2].Content[0].Text, "cut off") {
+			t.Errorf("expected continuation prompt, got %q", req.Messages[2].Content[0].Text)
+		}
+	})
+
+	t.Run("max tokens gives up", func(t *testing.T) {
+		provider := fake.New().
+			StopMaxTokens("a").
+			StopMaxTokens("b").
+			StopMaxTokens("c")
+
+		agent, err := NewAgent(AgentConfig{
+			Provider:     provider,
+			MCPServer:    NewMCPServerAdapter(server),
+			SystemPrompt: "You are a test agent",
+			Budget:       Budget{MaxContinuations: 2},
+		})
+		if err != nil {
+			t.Fatalf("failed to create agent: %v", err)
+		}
+
+		err = agent.Run(context.Background(), "test prompt")
+		var budgetErr *BudgetExceededError
+		if !errors.As(err, &budgetErr) || budgetErr.Limit != BudgetContinuations {
+			t.Fatalf("expected continuations budget error, got %v", err)
+		}
+	})
+
+	t.Run("stop sequence ends the run", func(t *testing.T) {
+		provider := fake.New().Respond(&providers.MessageResponse{
+			Content:    []providers.ContentBlock{{Type: "text", Text: "Done"}},
+			StopReason: providers.StopReasonStopSequence,
+		})
+
+		agent, _ := NewAgent(AgentConfig{
+			Provider:     provider,
+			MCPServer:    NewMCPServerAdapter(server),
+			SystemPrompt: "You are a test agent",
+		})
+		if err := agent.Run(context.Background(), "test prompt"); err != nil {
+			t.Fatalf("agent run failed: %v", err)
+		}
+		if provider.Remaining() != 0 {
+			t.Errorf("expected script to be exhausted")
+		}
+	})
+
+	t.Run("unknown stop reason", func(t *testing.T) {
+		provider := fake.New().Respond(&providers.MessageResponse{
+			Content:    []providers.ContentBlock{{Type: "text", Text: "I can't help with that"}},
+			StopReason: "refusal",
+		})
+
+		agent, _ := NewAgent(AgentConfig{
+			Provider:     provider,
+			MCPServer:    NewMCPServerAdapter(server),
+			SystemPrompt: "You are a test agent",
+		})
+		err := agent.Run(context.Background(), "test prompt")
+		if err == nil || !strings.Contains(err.Error(), `unexpected stop reason "refusal"`) {
+			t.Fatalf("expected stop reason error, got %v", err)
+		}
+	})
+}
+
+func TestAgent_RunWithResult(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("write_file", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+		return fmt.Sprintf("wrote %v", args["path"]), nil
+	})
+	server.RegisterTool("run_lint", "Run the linter", func(ctx context.Context, args map[string]any) (string, error) {
+		return "", fmt.Errorf("main.go:3: missing tag")
+	})
+
+	provider := fake.New().
+		CallToolsWithText("Writing the file",
+			fake.Call("write_file", map[string]any{"path": "main.go"}),
+			fake.Call("run_lint", nil),
+		).
+		Respond(&providers.MessageResponse{
+			Content:    []providers.ContentBlock{{Type: "text", Text: "Created main.go"}},
+			StopReason: providers.StopReasonEndTurn,
+			Usage:      providers.Usage{InputTokens: 200, OutputTokens: 20},
+		})
+
+	session := results.NewSession("test", "test")
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		SystemPrompt: "You are a test agent",
+		Session:      session,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	result, err := agent.RunWithResult(context.Background(), "create main.go")
+	if err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	if result.Termination != TerminationCompleted || result.StopReason != providers.StopReasonEndTurn {
+		t.Errorf("expected completed run, got %s (%s)", result.Termination, result.StopReason)
+	}
+	if result.FinalText != "Created main.go" {
+		t.Errorf("unexpected final text %q", result.FinalText)
+	}
+	if len(result.Messages) != 4 {
+		t.Errorf("expected 4 transcript messages, got %d", len(result.Messages))
+	}
+	if len(result.Turns) != 2 || result.Turns[1].Provider != "fake" || result.Turns[1].Usage.InputTokens != 200 {
+		t.Errorf("unexpected turns %+v", result.Turns)
+	}
+	if result.Usage.TotalTokens() != 220 {
+		t.Errorf("expected 220 tokens, got %d", result.Usage.TotalTokens())
+	}
+
+	if len(result.ToolCalls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %d", len(result.ToolCalls))
+	}
+	if call := result.ToolCalls[0]; call.Name != "write_file" || call.Output != "wrote main.go" || call.IsError || call.Turn != 1 {
+		t.Errorf("unexpected write_file call %+v", call)
+	}
+	if call := result.ToolCalls[1]; call.Name != "run_lint" || !call.IsError || !strings.Contains(call.Output, "missing tag") {
+		t.Errorf("unexpected run_lint call %+v", call)
+	}
+
+	// The model sees the tool error
+	if results := provider.ToolResults(); !strings.Contains(results[1].Content, "missing tag") {
+		t.Errorf("expected tool error in result, got %q", results[1].Content)
+	}
+
+	if session.InitialPrompt != "create main.go" {
+		t.Errorf("expected initial prompt in session, got %q", session.InitialPrompt)
+	}
+	if len(session.Messages) != 2 {
+		t.Fatalf("expected 2 session messages, got %d", len(session.Messages))
+	}
+	calls := session.Messages[0].ToolCalls
+	if session.Messages[0].Content != "Writing the file" || len(calls) != 2 {
+		t.Fatalf("unexpected first session message %+v", session.Messages[0])
+	}
+	if calls[0].Input != `{"path":"main.go"}` || calls[0].Output != "wrote main.go" || !calls[1].IsError {
+		t.Errorf("unexpected session tool calls %+v", calls)
+	}
+	if session.Messages[1].Content != "Created main.go" {
+		t.Errorf("unexpected final session message %q", session.Messages[1].Content)
+	}
+}
+
+func TestAgent_RunWithResult_Termination(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		provider    *fake.Provider
+		budget      Budget
+		termination Termination
+	}{
+		{name: "cancelled", ctx: ctx, provider: fake.New(), termination: TerminationCancelled},
+		{name: "error", ctx: context.Background(), provider: fake.New().Fail(fmt.Errorf("overloaded")), termination: TerminationError},
+		{name: "budget", ctx: context.Background(), provider: fake.New().StopMaxTokens("part one, ").StopMaxTokens("part two"), budget: Budget{MaxContinuations: 1}, termination: TerminationBudgetExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, err := NewAgent(AgentConfig{
+				Provider:     tt.provider,
+				MCPServer:    NewMCPServerAdapter(server),
+				SystemPrompt: "You are a test agent",
+				Budget:       tt.budget,
+			})
+			if err != nil {
+				t.Fatalf("failed to create agent: %v", err)
+			}
+
+			result, err := agent.RunWithResult(tt.ctx, "test prompt")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if result == nil || result.Termination != tt.termination {
+				t.Fatalf("expected %s, got %+v", tt.termination, result)
+			}
+			if tt.termination == TerminationBudgetExceeded && result.FinalText != "part one, part two" {
+				t.Errorf("expected continued text, got %q", result.FinalText)
+			}
+		})
+	}
+}
+
+func TestAgent_Run_ParallelTools(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	var inFlight, maxInFlight, finished int
+	writing := make(map[string]bool)
+
+	enter := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		events = append(events, event)
+	}
+	leave := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		inFlight--
+		finished++
+	}
+
+	// a.go and b.go only return once both reads are running
+	var reads sync.WaitGroup
+	reads.Add(2)
+
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("read_file", "Read a file", func(ctx context.Context, args map[string]any) (string, error) {
+		path := args["path"].(string)
+		enter("read " + path)
+		defer leave()
+
+		if path != "c.go" {
+			reads.Done()
+			both := make(chan struct{})
+			go func() {
+				reads.Wait()
+				close(both)
+			}()
+			select {
+			case <-both:
+			case <-time.After(5 * time.Second):
+				return "", fmt.Errorf("read %s ran alone", path)
+			}
+		}
+		return "contents of " + path, nil
+	}, mcp.Parallel)
+	server.RegisterTool("write_file", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+		path := args["path"].(string)
+		enter("write " + path)
+		defer leave()
+
+		mu.Lock()
+		overlapped := writing[path]
+		writing[path] = true
+		mu.Unlock()
+		if overlapped {
+			return "", fmt.Errorf("overlapping writes to %s", path)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		writing[path] = false
+		mu.Unlock()
+		return "wrote " + path, nil
+	}, mcp.ConflictKey("path"))
+	server.RegisterTool("run_lint", "Run the linter", func(ctx context.Context, args map[string]any) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, "lint")
+		if inFlight != 0 || finished != 4 {
+			return "", fmt.Errorf("lint ran with %d calls in flight and %d finished", inFlight, finished)
+		}
+		return "lint passed", nil
+	})
+
+	provider := fake.New().
+		CallTools(
+			fake.Call("read_file", map[string]any{"path": "a.go"}),
+			fake.Call("read_file", map[string]any{"path": "b.go"}),
+			fake.Call("write_file", map[string]any{"path": "x.go"}),
+			fake.Call("write_file", map[string]any{"path": "x.go"}),
+			fake.Call("run_lint", nil),
+			fake.Call("read_file", map[string]any{"path": "c.go"}),
+		).
+		ReplyText("Done")
+
+	agent, err := NewAgent(AgentConfig{
+		Provider:         provider,
+		MCPServer:        NewMCPServerAdapter(server),
+		SystemPrompt:     "You are a test agent",
+		MaxParallelTools: 2,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	result, err := agent.RunWithResult(context.Background(), "test prompt")
+	if err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	// Results come back in the original order
+	want := []string{"contents of a.go", "contents of b.go", "wrote x.go", "wrote x.go", "lint passed", "contents of c.go"}
+	toolResults := provider.ToolResults()
+	if len(toolResults) != len(want) {
+		t.Fatalf("expected %d tool results, got %d", len(want), len(toolResults))
+	}
+	for i, block := range toolResults {
+		if block.Content != want[i] || block.ToolUseID != result.ToolCalls[i].ID {
+			t.Errorf("result %d: expected %q for %s, got %q for %s", i, want[i], result.ToolCalls[i].ID, block.Content, block.ToolUseID)
+		}
+	}
+
+	if maxInFlight != 2 {
+		t.Errorf("expected 2 calls in flight at most, got %d", maxInFlight)
+	}
+	if events[len(events)-2] != "lint" || events[len(events)-1] != "read c.go" {
+		t.Errorf("expected lint then read c.go last, got %v", events)
+	}
+}
+
+func TestAgent_Run_Permissions(t *testing.T) {
+	executed := map[string]int{}
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	for _, name := range []string{"write_file", "run_lint", "delete_file"} {
+		server.RegisterTool(name, name, func(ctx context.Context, args map[string]any) (string, error) {
+			executed[name]++
+			return name + " ok", nil
+		})
+	}
+
+	provider := fake.New().
+		CallTools(
+			fake.Call("write_file", map[string]any{"path": "main.go"}),
+			fake.Call("delete_file", map[string]any{"path": "go.mod"}),
+			fake.Call("run_lint", nil),
+			fake.Call("run_lint", nil),
+		).
+		ReplyText("Done")
+
+	// The developer approves the first lint and rejects the second
+	developer := &testAgentDeveloper{answers: []string{"yes", "no"}}
+	session := results.NewSession("test", "test")
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		SystemPrompt: "You are a test agent",
+		Session:      session,
+		Permissions: permissions.All(
+			permissions.DenyTools("delete_file"),
+			permissions.DryRun("write_file"),
+			&permissions.Approval{Approver: permissions.Ask(developer), Tools: []string{"run_lint"}},
+		),
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	result, err := agent.RunWithResult(context.Background(), "test prompt")
+	if err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	if executed["write_file"] != 0 || executed["delete_file"] != 0 || executed["run_lint"] != 1 {
+		t.Errorf("unexpected executions %v", executed)
+	}
+
+	calls := result.ToolCalls
+	if len(calls) != 4 {
+		t.Fatalf("expected 4 tool calls, got %d", len(calls))
+	}
+	if !calls[0].DryRun || calls[0].IsError || !strings.Contains(calls[0].Output, "Dry run") {
+		t.Errorf("expected a dry run write, got %+v", calls[0])
+	}
+	if !calls[1].IsError || calls[1].Output != "permission denied: delete_file: tool is not allowed" {
+		t.Errorf("expected a denied delete, got %+v", calls[1])
+	}
+	if calls[2].IsError || calls[2].Output != "run_lint ok" {
+		t.Errorf("expected an approved lint, got %+v", calls[2])
+	}
+	if !calls[3].IsError || !strings.Contains(calls[3].Output, "rejected by the user") {
+		t.Errorf("expected a rejected lint, got %+v", calls[3])
+	}
+
+	// Denials reach the model as tool errors
+	toolResults := provider.ToolResults()
+	if !toolResults[1].IsError || !toolResults[3].IsError || toolResults[0].IsError {
+		t.Errorf("unexpected tool results %+v", toolResults)
+	}
+
+	// Dry runs are recorded in the session
+	if recorded := session.Messages[0].ToolCalls; !recorded[0].DryRun {
+		t.Errorf("expected dry run in session, got %+v", recorded[0])
+	}
+}
+
+func TestAgent_Run_LintGuardrail(t *testing.T) {
+	lintOutputs := []string{
+		`{"success":false,"errors":[{"message":"missing tag"},{"message":"unused variable"}]}`,
+		`{"success":true}`,
+	}
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("wetwire_write", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+		return "wrote main.go", nil
+	})
+	server.RegisterTool("wetwire_lint", "Lint the code", func(ctx context.Context, args map[string]any) (string, error) {
+		output := lintOutputs[0]
+		lintOutputs = lintOutputs[1:]
+		return output, nil
+	})
+
+	provider := fake.New().
+		CallTool("wetwire_write", map[string]any{"path": "main.go"}).
+		ReplyText("Done").
+		CallTool("wetwire_lint", nil).
+		ReplyText("Done").
+		CallTools(
+			fake.Call("wetwire_write", map[string]any{"path": "main.go"}),
+			fake.Call("wetwire_lint", nil),
+		).
+		ReplyText("All done")
+
+	session := results.NewSession("test", "test")
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		SystemPrompt: "You are a test agent",
+		Session:      session,
+		Guardrails:   []Guardrail{LintGuardrail(session)},
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	result, err := agent.RunWithResult(context.Background(), "test prompt")
+	if err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+	if result.FinalText != "All done" || provider.Remaining() != 0 {
+		t.Errorf("expected the run to finish after lint passed, got %q with %d turns left", result.FinalText, provider.Remaining())
+	}
+
+	// Both early finishes were vetoed
+	requests := provider.Requests()
+	for i, want := range map[int]string{2: lintPendingMessage, 4: lintFailedMessage} {
+		messages := requests[i].Messages
+		if got := messages[len(messages)-1].Content[0].Text; got != want {
+			t.Errorf("request %d: expected veto %q, got %q", i, want, got)
+		}
+	}
+
+	if len(session.LintCycles) != 2 {
+		t.Fatalf("expected 2 lint cycles, got %d", len(session.LintCycles))
+	}
+	if cycle := session.LintCycles[0]; cycle.Passed || cycle.IssueCount != 2 || cycle.Issues[0] != "missing tag" {
+		t.Errorf("unexpected first lint cycle %+v", cycle)
+	}
+	if cycle := session.LintCycles[1]; !cycle.Passed || cycle.FixedCount != 2 {
+		t.Errorf("unexpected second lint cycle %+v", cycle)
+	}
+}
+
+func TestAgent_LintGuardrail_State(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("wetwire_write", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+		return "wrote main.go", nil
+	})
+	server.RegisterTool("wetwire_lint", "Lint the code", func(ctx context.Context, args map[string]any) (string, error) {
+		return `{"success":false,"errors":[{"message":"missing tag"}]}`, nil
+	})
+
+	newAgent := func(provider providers.Provider) *Agent {
+		agent, err := NewAgent(AgentConfig{
+			Provider:       provider,
+			MCPServer:      NewMCPServerAdapter(server),
+			SystemPrompt:   "You are a test agent",
+			CheckpointPath: path,
+			Guardrails:     []Guardrail{LintGuardrail(nil)},
+		})
+		if err != nil {
+			t.Fatalf("failed to create agent: %v", err)
+		}
+		return agent
+	}
+
+	// A failed lint in one run does not block the next run of the same agent
+	provider := fake.New().
+		CallTools(
+			fake.Call("wetwire_write", map[string]any{"path": "main.go"}),
+			fake.Call("wetwire_lint", nil),
+		).
+		Fail(errors.New("service unavailable")).
+		ReplyText("Nothing to do")
+	agent := newAgent(provider)
+	if _, err := agent.RunWithResult(context.Background(), "create main.go"); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+	if result, err := agent.RunWithResult(context.Background(), "answer a question"); err != nil || result.FinalText != "Nothing to do" {
+		t.Errorf("expected the second run to finish, got %v, %q", err, result.FinalText)
+	}
+
+	// A resumed run still needs lint after a write made before the checkpoint
+	first := fake.New().
+		CallTool("wetwire_write", map[string]any{"path": "main.go"}).
+		Fail(errors.New("service unavailable"))
+	if _, err := newAgent(first).RunWithResult(context.Background(), "create main.go"); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+	second := fake.New().ReplyText("Done").Fail(errors.New("service unavailable"))
+	_, _ = newAgent(second).Resume(context.Background())
+	requests := second.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected the resumed finish to be vetoed, got %d requests", len(requests))
+	}
+	messages := requests[1].Messages
+	if got := messages[len(messages)-1].Content[0].Text; got != lintPendingMessage {
+		t.Errorf("expected veto %q, got %q", lintPendingMessage, got)
+	}
+}
+
+func TestAgent_Run_GuardrailHooks(t *testing.T) {
+	executed := 0
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("deploy", "Deploy the stack", func(ctx context.Context, args map[string]any) (string, error) {
+		executed++
+		return "deployed", nil
+	})
+	server.RegisterTool("build", "Build the stack", func(ctx context.Context, args map[string]any) (string, error) {
+		return "built", nil
+	})
+
+	provider := fake.New().
+		CallTools(fake.Call("build", nil), fake.Call("deploy", nil)).
+		ReplyText("Done")
+
+	var seen []string
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		SystemPrompt: "You are a test agent",
+		Guardrails: []Guardrail{{
+			BeforeTool: func(ctx context.Context, call ToolCallRecord) error {
+				if call.Name == "deploy" {
+					return errors.New("deploys are not allowed in tests")
+				}
+				return nil
+			},
+			AfterTool: func(ctx context.Context, call ToolCallRecord) string {
+				seen = append(seen, call.Name+": "+call.Output)
+				if call.Name == "build" {
+					return "Remember to validate the build output."
+				}
+				return ""
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	if err := agent.Run(context.Background(), "test prompt"); err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	if executed != 0 {
+		t.Errorf("expected deploy to be skipped, ran %d times", executed)
+	}
+	if want := []string{"build: built", "deploy: deploys are not allowed in tests"}; !slices.Equal(seen, want) {
+		t.Errorf("expected AfterTool calls %v, got %v", want, seen)
+	}
+
+	// The corrective message follows the tool results
+	last, _ := provider.LastRequest()
+	content := last.Messages[len(last.Messages)-1].Content
+	if len(content) != 3 || !content[1].IsError || content[2].Text != "Remember to validate the build output." {
+		t.Errorf("unexpected tool result message %+v", content)
+	}
+}
+
+func TestAgent_Resume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	writes := 0
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("write_file", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+		writes++
+		return fmt.Sprintf("wrote %v", args["path"]), nil
+	})
+
+	newAgent := func(provider providers.Provider, session *results.Session) *Agent {
+		agent, err := NewAgent(AgentConfig{
+			Provider:       provider,
+			MCPServer:      NewMCPServerAdapter(server),
+			SystemPrompt:   "You are a test agent",
+			Session:        session,
+			CheckpointPath: path,
+		})
+		if err != nil {
+			t.Fatalf("failed to create agent: %v", err)
+		}
+		return agent
+	}
+
+	// The provider fails for good after the first turn
+	first := fake.New().
+		CallToolsWithText("Writing", fake.Call("write_file", map[string]any{"path": "main.go"})).
+		Fail(errors.New("service unavailable"))
+	result, err := newAgent(first, results.NewSession("test", "test")).RunWithResult(context.Background(), "create main.go")
+	if err == nil || result.Termination != TerminationError {
+		t.Fatalf("expected the first run to fail, got %v (%s)", err, result.Termination)
+	}
+
+	cp, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if cp.Prompt != "create main.go" || len(cp.Messages) != 3 || len(cp.Result.ToolCalls) != 1 || cp.Session == nil {
+		t.Fatalf("unexpected checkpoint %+v", cp)
+	}
+
+	// A new process resumes without repeating the write
+	second := fake.New().ReplyText("Created main.go")
+	session := results.NewSession("test", "test")
+	result, err = newAgent(second, session).Resume(context.Background())
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+
+	if writes != 1 {
+		t.Errorf("expected 1 write, got %d", writes)
+	}
+	if result.Termination != TerminationCompleted || result.FinalText != "Created main.go" {
+		t.Errorf("unexpected result %s %q", result.Termination, result.FinalText)
+	}
+	if len(result.Turns) != 2 || result.Turns[1].Number != 2 || len(result.Messages) != 4 {
+		t.Errorf("expected the resumed run to extend the first, got %d turns and %d messages", len(result.Turns), len(result.Messages))
+	}
+	if req, _ := second.LastRequest(); len(req.Messages) != 3 {
+		t.Errorf("expected the saved history to be sent, got %d messages", len(req.Messages))
+	}
+	if session.InitialPrompt != "create main.go" || len(session.Messages) != 2 {
+		t.Errorf("expected the session to be restored, got %q with %d messages", session.InitialPrompt, len(session.Messages))
+	}
+
+	// A completed run is returned as saved
+	third := fake.New()
+	result, err = newAgent(third, nil).Resume(context.Background())
+	if err != nil || result.FinalText != "Created main.go" || len(third.Requests()) != 0 {
+		t.Errorf("expected the completed run without new requests, got %v, %q", err, result.FinalText)
+	}
+}
+
+func TestAgent_Resume_InterruptedToolCall(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checkpoint.json")
+	crashed := filepath.Join(dir, "crashed.json")
+
+	deploys := 0
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("build", "Build the stack", func(ctx context.Context, args map[string]any) (string, error) {
+		return "built", nil
+	})
+	server.RegisterTool("deploy", "Deploy the stack", func(ctx context.Context, args map[string]any) (string, error) {
+		// Keep the checkpoint as it was when the process "died" here
+		deploys++
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return "deployed", os.WriteFile(crashed, data, 0644)
+	})
+
+	provider := fake.New().
+		CallTools(fake.Call("build", nil), fake.Call("deploy", nil)).
+		ReplyText("Done")
+	agent, err := NewAgent(AgentConfig{
+		Provider:       provider,
+		MCPServer:      NewMCPServerAdapter(server),
+		SystemPrompt:   "You are a test agent",
+		CheckpointPath: path,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	if err := agent.Run(context.Background(), "deploy the stack"); err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	resumed := fake.New().ReplyText("Checked the deployment")
+	agent, err = NewAgent(AgentConfig{
+		Provider:       resumed,
+		MCPServer:      NewMCPServerAdapter(server),
+		SystemPrompt:   "You are a test agent",
+		CheckpointPath: crashed,
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	result, err := agent.Resume(context.Background())
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+
+	if deploys != 1 {
+		t.Errorf("expected the interrupted deploy not to be repeated, ran %d times", deploys)
+	}
+	toolResults := resumed.ToolResults()
+	if len(toolResults) != 2 {
+		t.Fatalf("expected 2 tool results, got %d", len(toolResults))
+	}
+	for _, block := range toolResults {
+		if !block.IsError || block.Content != interruptedMessage {
+			t.Errorf("expected interrupted tool result, got %+v", block)
+		}
+	}
+	if len(result.ToolCalls) != 2 || !result.ToolCalls[1].IsError {
+		t.Errorf("expected the interrupted calls to be recorded, got %+v", result.ToolCalls)
+	}
+}
+
+func TestAgent_Resume_Budget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("write_file", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+		return "wrote", nil
+	})
+
+	newAgent := func(provider providers.Provider) *Agent {
+		agent, err := NewAgent(AgentConfig{
+			Provider:       provider,
+			MCPServer:      NewMCPServerAdapter(server),
+			SystemPrompt:   "You are a test agent",
+			CheckpointPath: path,
+			Budget:         Budget{MaxTurns: 2, MaxToolCalls: 1},
+		})
+		if err != nil {
+			t.Fatalf("failed to create agent: %v", err)
+		}
+		return agent
+	}
+
+	first := fake.New().
+		CallTool("write_file", map[string]any{"path": "main.go"}).
+		Fail(errors.New("service unavailable"))
+	if _, err := newAgent(first).RunWithResult(context.Background(), "create main.go"); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	// The write before the checkpoint counts against the tool call budget
+	second := fake.New().CallTool("write_file", map[string]any{"path": "main_test.go"})
+	result, err := newAgent(second).Resume(context.Background())
+
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Limit != BudgetToolCalls || budgetErr.Used != 2 {
+		t.Fatalf("expected the tool call budget to be exceeded, got %v", err)
+	}
+	if len(result.ToolCalls) != 1 {
+		t.Errorf("expected only the first write to run, got %d calls", len(result.ToolCalls))
+	}
+
+	// So do the turns of both attempts
+	third := fake.New().ReplyText("Done")
+	if _, err := newAgent(third).Resume(context.Background()); !errors.As(err, &budgetErr) || budgetErr.Limit != BudgetTurns {
+		t.Errorf("expected the turn budget to be exceeded, got %v", err)
+	}
+	if len(third.Requests()) != 0 {
+		t.Errorf("expected no requests after the turn budget was used, got %d", len(third.Requests()))
+	}
+}
+
+func TestAgent_Resume_SessionSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("write_file", "Write a file", func(ctx context.Context, args map[string]any) (string, error) {
+		return "wrote", nil
+	})
+
+	newAgent := func(provider providers.Provider, session *results.Session) *Agent {
+		agent, err := NewAgent(AgentConfig{
+			Provider:       provider,
+			MCPServer:      NewMCPServerAdapter(server),
+			Model:          "test-model",
+			SystemPrompt:   "You are a test agent",
+			Session:        session,
+			CheckpointPath: path,
+		})
+		if err != nil {
+			t.Fatalf("failed to create agent: %v", err)
+		}
+		return agent
+	}
+
+	first := fake.New().
+		CallTool("write_file", map[string]any{"path": "main.go"}).
+		Fail(errors.New("service unavailable"))
+	if _, err := newAgent(first, results.NewSession("test", "test")).RunWithResult(context.Background(), "create main.go"); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	// Settings that are not saved in the checkpoint survive the resume
+	session := results.NewSession("test", "test")
+	session.RedactThinking = true
+	session.Prices = providers.PriceTable{"test-model": {Input: 2}}
+	second := fake.New().Respond(&providers.MessageResponse{
+		Content: []providers.ContentBlock{
+			{Type: "thinking", Thinking: "secret reasoning"},
+			{Type: "text", Text: "Created main.go"},
+		},
+		StopReason: providers.StopReasonEndTurn,
+		Usage:      providers.Usage{InputTokens: 1_000_000},
+	})
+	if _, err := newAgent(second, session).Resume(context.Background()); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+
+	if !session.RedactThinking || len(session.Thinking) != 1 || !session.Thinking[0].Redacted || session.Thinking[0].Content != "" {
+		t.Errorf("expected the resumed thinking to be redacted, got %+v", session.Thinking)
+	}
+	if got := session.TotalCost(); got != 2 {
+		t.Errorf("expected the resumed turn to use the custom prices, got cost %v", got)
+	}
+}
+
+func TestAgent_Resume_NoCheckpoint(t *testing.T) {
+	agent, err := NewAgent(AgentConfig{
+		Provider:       fake.New(),
+		MCPServer:      NewMCPServerAdapter(mcp.NewServer(mcp.Config{Name: "test"})),
+		SystemPrompt:   "You are a test agent",
+		CheckpointPath: filepath.Join(t.TempDir(), "missing.json"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	if _, err := agent.Resume(context.Background()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestAgent_Run_Observer(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("build", "Build the stack", func(ctx context.Context, args map[string]any) (string, error) {
+		return "built", nil
+	})
+
+	provider := fake.New().
+		CallToolsWithText("Building", fake.Call("build", nil), fake.Call("ask_developer", map[string]any{"question": "Which region?"})).
+		ReplyText("Done").
+		ReplyText("Done, really")
+
+	vetoed := false
+	var got []events.Event
+	session := results.NewSession("expert", "test-scenario")
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		Session:      session,
+		Developer:    &testAgentDeveloper{answers: []string{"us-east-1"}},
+		SystemPrompt: "You are a test agent",
+		Guardrails: []Guardrail{{
+			BeforeFinish: func(ctx context.Context, result *RunResult) string {
+				if vetoed {
+					return ""
+				}
+				vetoed = true
+				return "Check your work."
+			},
+		}},
+		Observer: events.Func(func(e events.Event) { got = append(got, e) }),
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	if err := agent.Run(context.Background(), "deploy the stack"); err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	var types []events.Type
+	for _, e := range got {
+		types = append(types, e.Type)
+		if e.SessionID != session.ID || e.Persona != "expert" || e.Time.IsZero() {
+			t.Errorf("event %s not stamped with the session: %+v", e.Type, e)
+		}
+	}
+	want := []events.Type{
+		events.RunStarted,
+		events.TurnStarted, events.RequestSent, events.TextDelta, events.TurnFinished,
+		events.ToolStarted, events.ToolFinished,
+		events.ToolStarted, events.QuestionAsked, events.QuestionAnswered, events.ToolFinished,
+		events.TurnStarted, events.RequestSent, events.TextDelta, events.TurnFinished,
+		events.GuardrailTriggered,
+		events.TurnStarted, events.RequestSent, events.TextDelta, events.TurnFinished,
+		events.RunFinished,
+	}
+	if !slices.Equal(types, want) {
+		t.Fatalf("expected events\n%v\ngot\n%v", want, types)
+	}
+
+	if e := got[6]; e.Tool != "build" || e.Output != "built" || e.Turn != 1 {
+		t.Errorf("unexpected tool event %+v", e)
+	}
+	if e := got[9]; e.Text != "us-east-1" {
+		t.Errorf("unexpected answer event %+v", e)
+	}
+	if e := got[len(got)-1]; e.Termination != string(TerminationCompleted) || e.Error != "" || e.Duration == 0 {
+		t.Errorf("unexpected run finished event %+v", e)
+	}
+}
+
+func TestAgent_Run_ObserverBudget(t *testing.T) {
+	provider := fake.New().Respond(&providers.MessageResponse{
+		Content:    []providers.ContentBlock{{Type: "text", Text: "Done"}},
+		StopReason: providers.StopReasonEndTurn,
+		Usage:      providers.Usage{InputTokens: 100, OutputTokens: 50},
+	})
+
+	var got []events.Event
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(mcp.NewServer(mcp.Config{Name: "test"})),
+		SystemPrompt: "You are a test agent",
+		Budget:       Budget{MaxTotalTokens: 100},
+		Observer:     events.Func(func(e events.Event) { got = append(got, e) }),
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	if err := agent.Run(context.Background(), "test prompt"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected budget error, got %v", err)
+	}
+
+	n := len(got)
+	if n < 2 || got[n-2].Type != events.BudgetExceeded || got[n-2].Text != string(BudgetTokens) {
+		t.Fatalf("expected a budget event before the end of the run, got %+v", got)
+	}
+	if e := got[n-1]; e.Type != events.RunFinished || e.Termination != string(TerminationBudgetExceeded) || e.Error == "" {
+		t.Errorf("unexpected run finished event %+v", e)
+	}
+}
+
+func TestAgent_Run_Verification(t *testing.T) {
+	builds := 0
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("wetwire_build", "Build the project", func(ctx context.Context, args map[string]any) (string, error) {
+		builds++
+		result := domain.NewResult("built")
+		if builds == 1 {
+			result = domain.NewErrorResult("build failed", domain.Error{Path: "main.go", Line: 3, Message: "undefined: Bucket"})
+		}
+		data, err := result.ToJSON()
+		return string(data), err
+	})
+	server.RegisterTool("wetwire_validate", "Validate the output", func(ctx context.Context, args map[string]any) (string, error) {
+		if args["path"] != "out" {
+			return "", fmt.Errorf("unexpected args %v", args)
+		}
+		return `{"success":true}`, nil
+	})
+
+	provider := fake.New().
+		ReplyText("Done").
+		ReplyText("Fixed the bucket reference")
+
+	session := results.NewSession("test", "test")
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		Session:      session,
+		SystemPrompt: "You are a test agent",
+		Verification: &Verification{Steps: []VerificationStep{
+			{Tool: "wetwire_build"},
+			{Tool: "wetwire_validate", Args: map[string]any{"path": "out"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	result, err := agent.RunWithResult(context.Background(), "create a bucket")
+	if err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+
+	if result.Verifications != 2 || result.FinalText != "Fixed the bucket reference" {
+		t.Errorf("expected a repaired run, got %d verifications and %q", result.Verifications, result.FinalText)
+	}
+	last, _ := provider.LastRequest()
+	repair := last.Messages[len(last.Messages)-1].Content[0].Text
+	if !strings.Contains(repair, "wetwire_build reported errors") || !strings.Contains(repair, "- main.go:3: undefined: Bucket") {
+		t.Errorf("unexpected repair message %q", repair)
+	}
+
+	if len(session.Verifications) != 2 || session.Verifications[0].Passed || !session.Verifications[1].Passed {
+		t.Fatalf("expected a failed then a passed round, got %+v", session.Verifications)
+	}
+	if session.VerifiedOnFirstTry() {
+		t.Error("expected the run not to be verified on the first try")
+	}
+}
+
+func TestAgent_Run_VerificationPermissions(t *testing.T) {
+	executed := map[string]int{}
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	for _, name := range []string{"wetwire_build", "wetwire_deploy"} {
+		server.RegisterTool(name, name, func(ctx context.Context, args map[string]any) (string, error) {
+			executed[name]++
+			return `{"success":false,"message":"failed"}`, nil
+		})
+	}
+
+	// A dry-run step is skipped and the run passes verification
+	agent, err := NewAgent(AgentConfig{
+		Provider:     fake.New().ReplyText("Done"),
+		MCPServer:    NewMCPServerAdapter(server),
+		SystemPrompt: "You are a test agent",
+		Permissions:  permissions.DryRun("wetwire_build"),
+		Verification: &Verification{Steps: []VerificationStep{{Tool: "wetwire_build"}}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	result, err := agent.RunWithResult(context.Background(), "create a bucket")
+	if err != nil {
+		t.Fatalf("agent run failed: %v", err)
+	}
+	if result.Verifications != 1 || executed["wetwire_build"] != 0 {
+		t.Errorf("expected a skipped dry-run step, got %d verifications and %v", result.Verifications, executed)
+	}
+
+	// A denied step fails the run without a repair round
+	provider := fake.New().ReplyText("Done")
+	agent, err = NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		SystemPrompt: "You are a test agent",
+		Permissions:  permissions.DenyTools("wetwire_deploy"),
+		Verification: &Verification{Steps: []VerificationStep{{Tool: "wetwire_deploy"}}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+	_, err = agent.RunWithResult(context.Background(), "create a bucket")
+	if !errors.Is(err, permissions.ErrDenied) {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if executed["wetwire_deploy"] != 0 || len(provider.Requests()) != 1 {
+		t.Errorf("expected no execution or repair, got %v and %d requests", executed, len(provider.Requests()))
+	}
+}
+
+func TestAgent_Run_VerificationFails(t *testing.T) {
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("wetwire_build", "Build the project", func(ctx context.Context, args map[string]any) (string, error) {
+		return "", errors.New("exit status 1")
+	})
+
+	provider := fake.New().
+		ReplyText("Done").
+		ReplyText("Still done")
+
+	agent, err := NewAgent(AgentConfig{
+		Provider:     provider,
+		MCPServer:    NewMCPServerAdapter(server),
+		SystemPrompt: "You are a test agent",
+		Verification: &Verification{
+			Steps:      []VerificationStep{{Tool: "wetwire_build"}},
+			MaxRepairs: 1,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	result, err := agent.RunWithResult(context.Background(), "create a bucket")
+
+	var verr *VerificationError
+	if !errors.As(err, &verr) || !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("expected a verification error, got %v", err)
+	}
+	if verr.Tool != "wetwire_build" || verr.Rounds != 2 || verr.Errors[0] != "exit status 1" {
+		t.Errorf("unexpected error %+v", verr)
+	}
+	if result.Termination != TerminationVerificationFailed {
+		t.Errorf("expected termination %s, got %s", TerminationVerificationFailed, result.Termination)
+	}
+	if n := len(provider.Requests()); n != 2 {
+		t.Errorf("expected 1 repair round, got %d requests", n)
+	}
+}
@@ -8,17 +8,24 @@ package agents
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/lex00/wetwire-core-go/agent/events"
+	"github.com/lex00/wetwire-core-go/agent/history"
 	"github.com/lex00/wetwire-core-go/agent/orchestrator"
+	"github.com/lex00/wetwire-core-go/agent/permissions"
 	"github.com/lex00/wetwire-core-go/agent/results"
 	"github.com/lex00/wetwire-core-go/providers"
 	anthropicprovider "github.com/lex00/wetwire-core-go/providers/anthropic"
 	claudeprovider "github.com/lex00/wetwire-core-go/providers/claude"
+	"github.com/lex00/wetwire-core-go/providers/structured"
 )
 
 // DomainConfig provides domain-specific configuration for the RunnerAgent.
@@ -43,6 +50,7 @@ type DomainConfig struct {
 // Deprecated: Use Agent with MCPServerAdapter instead. RunnerAgent hardcodes
 // its tools, while the new Agent architecture gets tools from an MCP server.
 // This provides better extensibility and consistency across wetwire domains.
+// LintGuardrail replaces RunnerAgent's built-in lint enforcement.
 //
 // Migration example:
 //
@@ -57,6 +65,7 @@ type DomainConfig struct {
 //		Provider:     provider,
 //		MCPServer:    NewMCPServerAdapter(mcpServer),
 //		SystemPrompt: "...",
+//		Guardrails:   []Guardrail{LintGuardrail(session)},
 //	})
 //	agent.Run(ctx, prompt)
 type RunnerAgent struct {
@@ -69,6 +78,7 @@ type RunnerAgent struct {
 	generatedFiles []string
 	templateJSON   string
 	maxLintCycles  int
+	maxTokens      int
 	streamHandler  providers.StreamHandler
 
 	// Lint enforcement state
@@ -104,6 +114,9 @@ type RunnerConfig struct {
 	// MaxLintCycles is the maximum number of lint/fix attempts
 	MaxLintCycles int
 
+	// MaxTokens limits each response (default: 4096, or the model's limit if lower)
+	MaxTokens int
+
 	// Session for tracking results
 	Session *results.Session
 
@@ -122,24 +135,10 @@ func NewRunnerAgent(config RunnerConfig) (*RunnerAgent, error) {
 	// Default to Claude CLI provider if available (no API key required).
 	// Falls back to Anthropic provider if Claude CLI is not installed.
 	if provider == nil {
-		if claudeprovider.Available() {
-			var err error
-			provider, err = claudeprovider.New(claudeprovider.Config{
-				WorkDir:        config.WorkDir,
-				PermissionMode: "acceptEdits",
-			})
-			if err != nil {
-				return nil, fmt.Errorf("failed to create Claude provider: %w", err)
-			}
-		} else {
-			// Fall back to Anthropic if Claude CLI not available
-			var err error
-			provider, err = anthropicprovider.New(anthropicprovider.Config{
-				APIKey: config.APIKey,
-			})
-			if err != nil {
-				return nil, fmt.Errorf("Claude CLI not found and %w\n\nInstall Claude Code: https://claude.ai/download", err)
-			}
+		var err error
+		provider, err = defaultProvider(config)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -161,7 +160,7 @@ func NewRunnerAgent(config RunnerConfig) (*RunnerAgent, error) {
 		model = anthropicprovider.DefaultModel
 	}
 
-	return &RunnerAgent{
+	r := &RunnerAgent{
 		provider:      provider,
 		model:         model,
 		domain:        domain,
@@ -170,7 +169,63 @@ func NewRunnerAgent(config RunnerConfig) (*RunnerAgent, error) {
 		workDir:       config.WorkDir,
 		maxLintCycles: config.MaxLintCycles,
 		streamHandler: config.StreamHandler,
-	}, nil
+	}
+
+	_, maxTokens, err := checkCapabilities(provider, model, config.MaxTokens, providers.MessageRequest{
+		System: domain.SystemPrompt,
+		Tools:  r.getTools(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.maxTokens = maxTokens
+
+	return r, nil
+}
+
+// checkCapabilities validates an agent configuration against what the
+// provider supports for model, before any request is sent. It returns the
+// capabilities and the response token limit to use.
+func checkCapabilities(provider providers.Provider, model string, maxTokens int, req providers.MessageRequest) (providers.Capabilities, int, error) {
+	caps := providers.CapabilitiesOf(provider, model)
+	if maxTokens == 0 {
+		maxTokens = providers.DefaultMaxTokens
+		if caps.MaxOutputTokens > 0 && caps.MaxOutputTokens < maxTokens {
+			maxTokens = caps.MaxOutputTokens
+		}
+	}
+
+	req.Model = model
+	req.MaxTokens = maxTokens
+	if err := caps.Check(req); err != nil {
+		return caps, 0, fmt.Errorf("%s cannot serve model %q: %w", provider.Name(), model, err)
+	}
+	return caps, maxTokens, nil
+}
+
+// defaultProvider builds the provider used when RunnerConfig.Provider is nil:
+// the Claude CLI if it is installed, otherwise the Anthropic API. The two are
+// not routed between, since a CLI transcript and tool set cannot be continued
+// on the API mid-conversation; use a providers.Router explicitly for that.
+func defaultProvider(config RunnerConfig) (providers.Provider, error) {
+	if claudeprovider.Available() {
+		provider, err := claudeprovider.New(claudeprovider.Config{
+			WorkDir:        config.WorkDir,
+			PermissionMode: "acceptEdits",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Claude provider: %w", err)
+		}
+		return provider, nil
+	}
+
+	provider, err := anthropicprovider.New(anthropicprovider.Config{
+		APIKey: config.APIKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Claude CLI not found and %w\n\nInstall Claude Code: https://claude.ai/download", err)
+	}
+	return provider, nil
 }
 
 // Run executes the runner workflow.
@@ -194,7 +249,7 @@ func (r *RunnerAgent) Run(ctx context.Context, prompt string) error {
 
 		req := providers.MessageRequest{
 			Model:     r.model,
-			MaxTokens: 4096,
+			MaxTokens: r.maxTokens,
 			System:    systemPrompt,
 			Messages:  messages,
 			Tools:     tools,
@@ -219,6 +274,11 @@ func (r *RunnerAgent) Run(ctx context.Context, prompt string) error {
 
 		// Capture assistant response to session
 		if r.session != nil {
+			if !resp.Usage.IsZero() {
+				r.session.AddUsage(providers.ServedBy(r.provider, resp), providers.ServedModel(r.model, resp), resp.Usage)
+			}
+			r.session.AddThinking(resp.Content)
+
 			textContent := extractTextContent(resp.Content)
 			if textContent != "" {
 				r.session.AddMessage("runner", textContent)
@@ -621,29 +681,27 @@ func CreateDeveloperResponderWithProvider(provider providers.Provider, apiKey st
 			}
 		}
 
-		req := providers.MessageRequest{
-			Model:     "claude-3-5-haiku-latest",
-			MaxTokens: 1024,
-			System:    systemPrompt,
-			Messages:  []providers.Message{providers.NewUserMessage(message)},
-		}
-
-		resp, err := p.CreateMessage(ctx, req)
+		reply, err := structured.Generate[developerReply](ctx, p, structured.Request{
+			Model:       "claude-3-5-haiku-latest",
+			MaxTokens:   1024,
+			System:      systemPrompt,
+			Messages:    []providers.Message{providers.NewUserMessage(message)},
+			Name:        "reply",
+			Description: "Reply to the coding agent.",
+		})
 		if err != nil {
 			return "", err
 		}
 
-		var response strings.Builder
-		for _, block := range resp.Content {
-			if block.Type == "text" {
-				response.WriteString(block.Text)
-			}
-		}
-
-		return response.String(), nil
+		return reply.Answer, nil
 	}
 }
 
+// developerReply is the structured response of an AI developer persona.
+type developerReply struct {
+	Answer string `json:"answer" description:"Your reply to the coding agent, in your own voice, without any preamble"`
+}
+
 // ============================================================================
 // Unified Agent Architecture (Issue #56)
 // ============================================================================
@@ -669,6 +727,16 @@ type MCPToolInfo struct {
 	Name        string
 	Description string
 	InputSchema map[string]any
+
+	// Parallel marks the tool as safe to run alongside other parallel
+	// calls when AgentConfig.MaxParallelTools is above 1. Other tools run
+	// exclusively: they wait for every earlier call in the response and
+	// hold back later ones, as lint must after a write.
+	Parallel bool
+
+	// ConflictKey names an input property, such as "path". Parallel calls
+	// with the same value for it run one after another, in order.
+	ConflictKey string
 }
 
 // Agent represents the unified agent that can operate in multiple modes:
@@ -686,13 +754,42 @@ type Agent struct {
 	developer     Developer
 	systemPrompt  string
 	streamHandler providers.StreamHandler
+	maxTokens     int
+
+	history          history.Policy
+	historyThreshold int
+
+	thinkingBudget  int
+	thinkingHandler providers.StreamHandler
+
+	budget Budget
+
+	maxParallelTools int
+
+	permissions permissions.Policy
+
+	guardrails []Guardrail
+
+	checkpointPath string
+
+	observer events.Observer
+
+	verification *Verification
 }
 
+// DefaultHistoryThreshold is the history size in tokens that triggers
+// compaction when the model's context window is unknown.
+const DefaultHistoryThreshold = 100000
+
 // AgentConfig configures the unified Agent.
 type AgentConfig struct {
 	// Provider is the AI provider (required)
 	Provider providers.Provider
 
+	// Middleware wraps Provider, for example with logging or redaction
+	// (optional). Use providers.Chain to combine several.
+	Middleware providers.Middleware
+
 	// Model to use (defaults to claude-sonnet-4-20250514)
 	Model string
 
@@ -710,6 +807,55 @@ type AgentConfig struct {
 
 	// StreamHandler for streaming responses (optional)
 	StreamHandler providers.StreamHandler
+
+	// MaxTokens limits each response (default: 4096, or the model's limit if lower)
+	MaxTokens int
+
+	// History compacts the conversation once it exceeds HistoryThreshold
+	// tokens (default: history.Default())
+	History history.Policy
+
+	// HistoryThreshold is the estimated history size in tokens that triggers
+	// compaction, which then shrinks the history to half of it
+	// (default: 75% of the model's context window, or DefaultHistoryThreshold if unknown)
+	HistoryThreshold int
+
+	// ThinkingBudget enables extended thinking with this many tokens (optional)
+	ThinkingBudget int
+
+	// ThinkingHandler receives streamed thinking chunks (optional)
+	ThinkingHandler providers.StreamHandler
+
+	// Budget limits each run (default: DefaultMaxTurns turns and
+	// DefaultMaxContinuations continuations, other limits unset)
+	Budget Budget
+
+	// MaxParallelTools is how many calls from one response may run at once.
+	// Only tools marked Parallel overlap, and results are returned in the
+	// original order (default: 1, sequential)
+	MaxParallelTools int
+
+	// Permissions decides whether each tool call may run; denied calls are
+	// sent back to the model as tool errors (optional, allows every call)
+	Permissions permissions.Policy
+
+	// Guardrails hook into tool calls and completion, for example
+	// LintGuardrail to require passing lint before finishing (optional)
+	Guardrails []Guardrail
+
+	// CheckpointPath is a file where the run is saved after every turn, so
+	// that Resume can continue it (optional)
+	CheckpointPath string
+
+	// Observer receives lifecycle events: turns, streamed text, tool calls,
+	// questions, guardrails, budgets and the end of the run (optional).
+	// With an observer the agent streams responses, as it does with a
+	// StreamHandler, so that text deltas can be reported.
+	Observer events.Observer
+
+	// Verification runs tools such as wetwire_build after the model
+	// finishes, and sends failures back to it for repair (optional)
+	Verification *Verification
 }
 
 // NewAgent creates a new unified Agent.
@@ -724,28 +870,163 @@ func NewAgent(config AgentConfig) (*Agent, error) {
 		return nil, fmt.Errorf("systemPrompt is required")
 	}
 
+	provider := config.Provider
+	if config.Middleware != nil {
+		provider = config.Middleware(provider)
+	}
+
 	model := config.Model
 	if model == "" {
 		model = anthropicprovider.DefaultModel
 	}
 
+	var tools []providers.Tool
+	for _, t := range config.MCPServer.GetTools() {
+		tools = append(tools, providers.Tool{Name: t.Name})
+	}
+	caps, maxTokens, err := checkCapabilities(provider, model, config.MaxTokens, providers.MessageRequest{
+		System: config.SystemPrompt,
+		Tools:  tools,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	historyPolicy := config.History
+	if historyPolicy == nil {
+		historyPolicy = history.Default()
+	}
+	historyThreshold := config.HistoryThreshold
+	if historyThreshold == 0 {
+		historyThreshold = DefaultHistoryThreshold
+		if caps.ContextWindow > 0 {
+			historyThreshold = caps.ContextWindow * 3 / 4
+		}
+	}
+
 	return &Agent{
-		provider:      config.Provider,
+		provider:      provider,
 		model:         model,
 		mcpServer:     config.MCPServer,
 		session:       config.Session,
 		developer:     config.Developer,
 		systemPrompt:  config.SystemPrompt,
 		streamHandler: config.StreamHandler,
+		maxTokens:     maxTokens,
+
+		history:          historyPolicy,
+		historyThreshold: historyThreshold,
+
+		thinkingBudget:  config.ThinkingBudget,
+		thinkingHandler: config.ThinkingHandler,
+
+		budget: config.Budget.withDefaults(),
+
+		maxParallelTools: max(config.MaxParallelTools, 1),
+
+		permissions: config.Permissions,
+
+		guardrails: config.Guardrails,
+
+		checkpointPath: config.CheckpointPath,
+
+		observer: events.Serialized(config.Observer),
+
+		verification: config.Verification,
 	}, nil
 }
 
 // Run executes the agent's workflow with the given prompt.
 func (a *Agent) Run(ctx context.Context, prompt string) error {
+	_, err := a.RunWithResult(ctx, prompt)
+	return err
+}
+
+// RunWithResult executes the agent's workflow with the given prompt and
+// returns the transcript, turns, tool calls and final text. The result is
+// returned even when the run fails, describing what happened up to then.
+func (a *Agent) RunWithResult(ctx context.Context, prompt string) (*RunResult, error) {
+	messages := []providers.Message{
+		providers.NewUserMessage(prompt),
+	}
+
+	if a.session != nil && a.session.InitialPrompt == "" {
+		a.session.InitialPrompt = prompt
+	}
+
+	return a.run(ctx, &runState{
+		prompt:   prompt,
+		messages: messages,
+		result:   &RunResult{Messages: slices.Clone(messages)},
+	})
+}
+
+// Resume continues the run saved at AgentConfig.CheckpointPath. Tool calls
+// that were in flight when the run stopped are not repeated; the model is
+// told their effect is unknown. The saved session replaces the contents of
+// the agent's session. A run that completed is returned as saved. Budgets
+// apply to the whole run: the turns, tokens, tool calls and time recorded in
+// the checkpoint's result count against them.
+//
+// The error wraps os.ErrNotExist if there is no checkpoint, so callers can
+// fall back to Run.
+func (a *Agent) Resume(ctx context.Context) (*RunResult, error) {
+	if a.checkpointPath == "" {
+		return nil, fmt.Errorf("no checkpoint path configured")
+	}
+	cp, err := LoadCheckpoint(a.checkpointPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.session != nil && cp.Session != nil {
+		// Settings that are not saved in the checkpoint stay as configured
+		redact, prices := a.session.RedactThinking, a.session.Prices
+		*a.session = *cp.Session
+		a.session.RedactThinking, a.session.Prices = redact, prices
+	}
+
+	result := cp.Result
+	if result.Termination == TerminationCompleted {
+		return result, nil
+	}
+
+	messages := cp.Messages
+	added, interrupted := resumeMessages(messages, result)
+	messages = append(messages, added...)
+	result.Messages = append(result.Messages, added...)
+	result.ToolCalls = append(result.ToolCalls, interrupted...)
+	result.Termination = ""
+
+	return a.run(ctx, &runState{
+		prompt:        cp.Prompt,
+		messages:      messages,
+		result:        result,
+		continued:     cp.Continued,
+		continuations: cp.Continuations,
+		repairs:       cp.Repairs,
+	})
+}
+
+// runState is the resumable state of a run.
+type runState struct {
+	prompt        string
+	messages      []providers.Message
+	result        *RunResult
+	continued     string
+	continuations int
+	repairs       int
+}
+
+// run executes the agentic loop from st until the model finishes or the
+// run fails, saving a checkpoint after every turn.
+func (a *Agent) run(ctx context.Context, st *runState) (*RunResult, error) {
 	// Get tools from MCP server
 	mcpTools := a.mcpServer.GetTools()
 	tools := make([]providers.Tool, len(mcpTools))
+	toolInfo := make(map[string]MCPToolInfo, len(mcpTools))
 	for i, t := range mcpTools {
+		toolInfo[t.Name] = t
 		tools[i] = providers.Tool{
 			Name:        t.Name,
 			Description: t.Description,
@@ -756,75 +1037,452 @@ func (a *Agent) Run(ctx context.Context, prompt string) error {
 		}
 	}
 
-	messages := []providers.Message{
-		providers.NewUserMessage(prompt),
+	result := st.result
+	start := time.Now()
+	elapsed := result.Duration
+	finish := func(err error) (*RunResult, error) {
+		result.Termination = terminationOf(err)
+		result.Duration = elapsed + time.Since(start)
+		if saveErr := a.saveCheckpoint(st); saveErr != nil && err == nil {
+			err = saveErr
+		}
+		a.emit(events.Event{
+			Type:        events.RunFinished,
+			Termination: string(result.Termination),
+			Usage:       result.Usage,
+			Duration:    result.Duration,
+			Error:       errorText(err),
+		})
+		return result, err
+	}
+	a.emit(events.Event{Type: events.RunStarted, Text: st.prompt})
+	a.beforeRun(ctx, result)
+
+	// The transcript keeps every message, even after history compaction
+	appendMessages := func(msgs ...providers.Message) {
+		st.messages = append(st.messages, msgs...)
+		result.Messages = append(result.Messages, msgs...)
+	}
+
+	if a.thinkingHandler != nil {
+		ctx = providers.WithThinkingHandler(ctx, a.thinkingHandler)
+	}
+
+	if a.budget.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, a.budget.MaxDuration-elapsed, errDurationExceeded)
+		defer cancel()
+	}
+
+	// Size of the history as reported by the last response, and how many
+	// messages it covered
+	var reported, reportedAt int
+
+	// Budgets count the whole run, including any part before a resume
+	turns := len(result.Turns)
+	toolCalls := make(map[string]int)
+	for _, call := range result.ToolCalls {
+		toolCalls[call.Name]++
 	}
 
 	// Agentic loop
 	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+		if err := ctx.Err(); err != nil {
+			return finish(a.stopped(ctx, err, elapsed+time.Since(start)))
+		}
+		if turns >= a.budget.MaxTurns {
+			return finish(a.exceeded(&BudgetExceededError{Limit: BudgetTurns, Used: int64(turns), Max: int64(a.budget.MaxTurns)}))
+		}
+
+		turns++
+		turn := len(result.Turns) + 1
+		a.emit(events.Event{Type: events.TurnStarted, Turn: turn})
+
+		tokens := max(history.EstimateTokens(st.messages), reported+history.EstimateTokens(st.messages[reportedAt:]))
+		if tokens > a.historyThreshold {
+			compacted, err := a.compact(ctx, st.messages, tokens)
+			if err != nil {
+				return finish(fmt.Errorf("history compaction failed: %w", err))
+			}
+			st.messages, reported, reportedAt = compacted, 0, 0
 		}
 
 		req := providers.MessageRequest{
-			Model:     a.model,
-			MaxTokens: 4096,
-			System:    a.systemPrompt,
-			Messages:  messages,
-			Tools:     tools,
+			Model:          a.model,
+			MaxTokens:      a.maxTokens,
+			System:         a.systemPrompt,
+			Messages:       st.messages,
+			Tools:          tools,
+			ThinkingBudget: a.thinkingBudget,
 		}
 
 		var resp *providers.MessageResponse
 		var err error
 
-		if a.streamHandler != nil {
-			resp, err = a.provider.StreamMessage(ctx, req, a.streamHandler)
+		a.emit(events.Event{Type: events.RequestSent, Turn: turn, Messages: len(st.messages)})
+		turnStart := time.Now()
+		if a.streamHandler != nil || a.observer != nil {
+			resp, err = a.provider.StreamMessage(ctx, req, a.textHandler(turn))
 		} else {
 			resp, err = a.provider.CreateMessage(ctx, req)
 		}
 		if err != nil {
-			return fmt.Errorf("API call failed: %w", err)
+			a.emit(events.Event{Type: events.TurnFinished, Turn: turn, Duration: time.Since(turnStart), Error: err.Error()})
+			if ctx.Err() != nil {
+				return finish(a.stopped(ctx, err, elapsed+time.Since(start)))
+			}
+			return finish(fmt.Errorf("API call failed: %w", err))
 		}
 
-		// Add assistant response to messages
-		messages = append(messages, providers.NewAssistantMessage(resp.Content))
+		result.Turns = append(result.Turns, Turn{
+			Number:     turn,
+			Provider:   providers.ServedBy(a.provider, resp),
+			StopReason: resp.StopReason,
+			Usage:      resp.Usage,
+			Start:      turnStart,
+			Duration:   time.Since(turnStart),
+		})
+		a.emit(events.Event{
+			Type:       events.TurnFinished,
+			Turn:       turn,
+			StopReason: resp.StopReason,
+			Usage:      resp.Usage,
+			Duration:   result.Turns[len(result.Turns)-1].Duration,
+		})
+		result.Usage = result.Usage.Add(resp.Usage)
+		result.StopReason = resp.StopReason
+		text := extractTextContent(resp.Content)
+
+		// Track token usage and reasoning
+		if a.session != nil {
+			if !resp.Usage.IsZero() {
+				a.session.AddUsage(providers.ServedBy(a.provider, resp), providers.ServedModel(a.model, resp), resp.Usage)
+			}
+			a.session.AddThinking(resp.Content)
+		}
 
-		// Check for stop reason
-		if resp.StopReason == providers.StopReasonEndTurn {
-			break
+		if total := result.Usage.TotalTokens(); a.budget.MaxTotalTokens > 0 && total > a.budget.MaxTotalTokens {
+			appendMessages(providers.NewAssistantMessage(resp.Content))
+			a.recordMessage(text, nil)
+			return finish(a.exceeded(&BudgetExceededError{Limit: BudgetTokens, Used: int64(total), Max: int64(a.budget.MaxTotalTokens)}))
 		}
 
-		// Process tool calls
-		if resp.StopReason == providers.StopReasonToolUse {
-			var toolResults []providers.ContentBlock
+		switch resp.StopReason {
+		case providers.StopReasonEndTurn, providers.StopReasonStopSequence, "":
+			// The agent sets no stop sequences, so either way the model is done
+			appendMessages(providers.NewAssistantMessage(resp.Content))
+			a.recordMessage(text, nil)
+			result.FinalText = st.continued + text
+			st.continued, st.continuations = "", 0
+
+			// Guardrails may send the model back to work
+			if veto := a.beforeFinish(ctx, result); veto != "" {
+				a.emit(events.Event{Type: events.GuardrailTriggered, Turn: turn, Text: veto})
+				appendMessages(providers.NewUserMessage(veto))
+				break
+			}
 
+			// So may a failed verification
+			repair, err := a.verify(ctx, st, turn)
+			if err != nil {
+				if ctx.Err() != nil {
+					return finish(a.stopped(ctx, err, elapsed+time.Since(start)))
+				}
+				return finish(err)
+			}
+			if repair != "" {
+				appendMessages(providers.NewUserMessage(repair))
+				break
+			}
+			return finish(nil)
+
+		case providers.StopReasonMaxTokens:
+			// Keep what was generated and ask the model to carry on
+			a.recordMessage(text, nil)
+			st.continued += text
+			st.continuations++
+			if st.continuations > a.budget.MaxContinuations {
+				appendMessages(providers.NewAssistantMessage(resp.Content))
+				result.FinalText = st.continued
+				return finish(a.exceeded(&BudgetExceededError{Limit: BudgetContinuations, Used: int64(st.continuations), Max: int64(a.budget.MaxContinuations)}))
+			}
+			appendMessages(
+				providers.NewAssistantMessage(truncatedContent(resp.Content)),
+				providers.NewUserMessage(continuePrompt))
+
+		case providers.StopReasonToolUse:
+			appendMessages(providers.NewAssistantMessage(resp.Content))
+			st.continued, st.continuations = "", 0
+
+			// Calls up to the first one over its budget are still run
+			var blocks []providers.ContentBlock
+			var over *BudgetExceededError
 			for _, block := range resp.Content {
-				if block.Type == "tool_use" {
-					result, err := a.executeTool(ctx, block.Name, block.Input)
-					toolResults = append(toolResults, providers.NewToolResult(
-						block.ID,
-						result,
-						err != nil,
-					))
+				if block.Type != "tool_use" {
+					continue
 				}
+
+				toolCalls[block.Name]++
+				if limit := a.budget.toolCallLimit(block.Name); limit > 0 && toolCalls[block.Name] > limit {
+					over = &BudgetExceededError{Limit: BudgetToolCalls, Tool: block.Name, Used: int64(toolCalls[block.Name]), Max: int64(limit)}
+					break
+				}
+				blocks = append(blocks, block)
 			}
 
-			messages = append(messages, providers.NewToolResultMessage(toolResults))
+			// Save before running the tools, so a crash while they run
+			// leaves a checkpoint that knows about them
+			if err := a.saveCheckpoint(st); err != nil {
+				return finish(err)
+			}
+
+			records := a.callTools(ctx, turn, blocks, toolInfo)
+			var toolResults []providers.ContentBlock
+			var calls []results.ToolCall
+			for _, call := range records {
+				result.ToolCalls = append(result.ToolCalls, call)
+				calls = append(calls, results.ToolCall{
+					Name:     call.Name,
+					Input:    string(call.Input),
+					Output:   call.Output,
+					IsError:  call.IsError,
+					DryRun:   call.DryRun,
+					Duration: call.Duration,
+				})
+				toolResults = append(toolResults, providers.NewToolResult(call.ID, call.Output, call.IsError))
+			}
+			a.recordMessage(text, calls)
+
+			if over != nil {
+				return finish(a.exceeded(over))
+			}
+			if len(toolResults) == 0 {
+				// Nothing to run; treat it as the end of the turn
+				result.FinalText = text
+				return finish(nil)
+			}
+
+			if msg := a.afterTools(ctx, records); msg != "" {
+				a.emit(events.Event{Type: events.GuardrailTriggered, Turn: turn, Text: msg})
+				toolResults = append(toolResults, providers.ContentBlock{Type: "text", Text: msg})
+			}
+			appendMessages(providers.NewToolResultMessage(toolResults))
+
+		default:
+			appendMessages(providers.NewAssistantMessage(resp.Content))
+			a.recordMessage(text, nil)
+			result.FinalText = text
+			return finish(fmt.Errorf("unexpected stop reason %q", resp.StopReason))
+		}
+
+		reported = resp.Usage.InputTokens + resp.Usage.CacheCreationInputTokens +
+			resp.Usage.CacheReadInputTokens + resp.Usage.OutputTokens
+		reportedAt = len(st.messages)
+
+		if err := a.saveCheckpoint(st); err != nil {
+			return finish(err)
 		}
 	}
+}
+
+// saveCheckpoint saves the state of a run to the checkpoint path, if set.
+func (a *Agent) saveCheckpoint(st *runState) error {
+	if a.checkpointPath == "" {
+		return nil
+	}
 
+	cp := &Checkpoint{
+		Version:       CheckpointVersion,
+		Prompt:        st.prompt,
+		Messages:      st.messages,
+		Continued:     st.continued,
+		Continuations: st.continuations,
+		Repairs:       st.repairs,
+		Result:        st.result,
+		Session:       a.session,
+		SavedAt:       time.Now(),
+	}
+	if err := cp.Save(a.checkpointPath); err != nil {
+		return fmt.Errorf("checkpoint failed: %w", err)
+	}
 	return nil
 }
 
-// executeTool executes a tool via the MCP server.
+// callTool executes a tool call and records its output and timing.
+// A failed or denied call's output is its error message, which is sent to
+// the model.
+func (a *Agent) callTool(ctx context.Context, turn int, block providers.ContentBlock) ToolCallRecord {
+	a.emit(events.Event{Type: events.ToolStarted, Turn: turn, ToolID: block.ID, Tool: block.Name, Input: block.Input})
+
+	start := time.Now()
+	output, err := a.executeTool(ctx, block.Name, block.Input)
+	dryRun := errors.Is(err, permissions.ErrDryRun)
+	switch {
+	case dryRun:
+		output, err = fmt.Sprintf("Dry run: %s was not executed. Continue as if it succeeded.", block.Name), nil
+	case err != nil:
+		output = err.Error()
+	}
+
+	record := ToolCallRecord{
+		Turn:     turn,
+		ID:       block.ID,
+		Name:     block.Name,
+		Input:    block.Input,
+		Output:   output,
+		IsError:  err != nil,
+		DryRun:   dryRun,
+		Duration: time.Since(start),
+	}
+	a.emit(toolEvent(events.ToolFinished, record))
+	return record
+}
+
+// textHandler returns the stream handler for a turn, which passes text to
+// the StreamHandler and reports it to the observer.
+func (a *Agent) textHandler(turn int) providers.StreamHandler {
+	return func(text string) {
+		if a.streamHandler != nil {
+			a.streamHandler(text)
+		}
+		a.emit(events.Event{Type: events.TextDelta, Turn: turn, Text: text})
+	}
+}
+
+// emit stamps an event with the time and the session, and delivers it to
+// the observer, if any.
+func (a *Agent) emit(e events.Event) {
+	if a.observer == nil {
+		return
+	}
+	e.Time = time.Now()
+	if a.session != nil {
+		e.SessionID, e.Persona = a.session.ID, a.session.Persona
+	}
+	a.observer.Observe(e)
+}
+
+// toolEvent returns an event describing a tool call.
+func toolEvent(typ events.Type, call ToolCallRecord) events.Event {
+	return events.Event{
+		Type:     typ,
+		Turn:     call.Turn,
+		ToolID:   call.ID,
+		Tool:     call.Name,
+		Input:    call.Input,
+		Output:   call.Output,
+		IsError:  call.IsError,
+		DryRun:   call.DryRun,
+		Duration: call.Duration,
+	}
+}
+
+// errorText returns the message of err, or "" if it is nil.
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// recordMessage adds a model response and its tool calls to the session.
+func (a *Agent) recordMessage(text string, calls []results.ToolCall) {
+	if a.session != nil && (text != "" || len(calls) > 0) {
+		a.session.AddMessageWithToolCalls("runner", text, calls)
+	}
+}
+
+// terminationOf returns the termination reason for a run's error.
+func terminationOf(err error) Termination {
+	switch {
+	case err == nil:
+		return TerminationCompleted
+	case errors.Is(err, ErrBudgetExceeded):
+		return TerminationBudgetExceeded
+	case errors.Is(err, ErrVerificationFailed):
+		return TerminationVerificationFailed
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return TerminationCancelled
+	default:
+		return TerminationError
+	}
+}
+
+// continuePrompt asks the model to continue a response cut off by the token limit.
+const continuePrompt = "Your last response was cut off by the output token limit. Continue exactly where you left off. If you were calling a tool, call it again with smaller input."
+
+// errDurationExceeded is the context cause when a run exceeds its MaxDuration.
+var errDurationExceeded = &BudgetExceededError{Limit: BudgetDuration}
+
+// truncatedContent returns the content of a response cut off by the token
+// limit without its incomplete tool calls, which would otherwise need results.
+func truncatedContent(content []providers.ContentBlock) []providers.ContentBlock {
+	var blocks []providers.ContentBlock
+	for _, block := range content {
+		if block.Type != "tool_use" {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) == 0 {
+		blocks = append(blocks, providers.ContentBlock{Type: "text", Text: "[response cut off by the token limit]"})
+	}
+	return blocks
+}
+
+// stopped returns the error for a run whose context ended, reporting the
+// duration budget if it caused the cancellation. Used is the run's duration.
+func (a *Agent) stopped(ctx context.Context, err error, used time.Duration) error {
+	if context.Cause(ctx) == errDurationExceeded {
+		return a.exceeded(&BudgetExceededError{
+			Limit: BudgetDuration,
+			Used:  int64(used),
+			Max:   int64(a.budget.MaxDuration),
+		})
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// exceeded records an exhausted budget in the session and returns it.
+func (a *Agent) exceeded(err *BudgetExceededError) error {
+	if a.session != nil {
+		a.session.SetBudgetExceeded(string(err.Limit), err.Tool, err.Error())
+	}
+	a.emit(events.Event{Type: events.BudgetExceeded, Text: string(err.Limit), Tool: err.Tool, Error: err.Error()})
+	return err
+}
+
+// compact shrinks the history with the agent's history policy and records
+// the compaction in the session.
+func (a *Agent) compact(ctx context.Context, messages []providers.Message, tokens int) ([]providers.Message, error) {
+	compacted, err := a.history.Compact(ctx, messages, a.historyThreshold/2)
+	if err != nil {
+		return nil, err
+	}
+
+	after := history.EstimateTokens(compacted)
+	if a.session != nil && (len(compacted) != len(messages) || after < history.EstimateTokens(messages)) {
+		a.session.AddCompaction(a.history.Name(), tokens, after, len(messages), len(compacted))
+	}
+	return compacted, nil
+}
+
+// executeTool checks a tool call against the permission policy and
+// executes it via the MCP server.
 func (a *Agent) executeTool(ctx context.Context, name string, input json.RawMessage) (string, error) {
 	var args map[string]any
 	if err := json.Unmarshal(input, &args); err != nil {
 		return "", fmt.Errorf("error parsing input: %w", err)
 	}
 
+	if a.permissions != nil {
+		if err := a.permissions.Check(ctx, permissions.Call{Tool: name, Args: args}); err != nil {
+			return "", err
+		}
+	}
+
 	// Special handling for ask_developer if available
 	if name == "ask_developer" && a.developer != nil {
 		question, ok := args["question"].(string)
@@ -832,10 +1490,13 @@ func (a *Agent) executeTool(ctx context.Context, name string, input json.RawMess
 			return "", fmt.Errorf("ask_developer requires a 'question' string parameter")
 		}
 
+		a.emit(events.Event{Type: events.QuestionAsked, Text: question})
 		answer, err := a.developer.Respond(ctx, question)
 		if err != nil {
+			a.emit(events.Event{Type: events.QuestionAnswered, Error: err.Error()})
 			return "", err
 		}
+		a.emit(events.Event{Type: events.QuestionAnswered, Text: answer})
 
 		if a.session != nil {
 			a.session.AddQuestion(question, answer)
@@ -5,7 +5,9 @@ import (
 	"testing"
 
 	"github.com/lex00/wetwire-core-go/providers"
+	"github.com/lex00/wetwire-core-go/providers/fake"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCheckLintEnforcement_WriteWithoutLint(t *testing.T) {
@@ -315,6 +317,32 @@ func TestDomainConfig_ToolDescriptions(t *testing.T) {
 	assert.Contains(t, buildTool.Description, "YAML")
 }
 
+func TestCreateDeveloperResponderWithProvider(t *testing.T) {
+	provider := fake.New().
+		ExpectSystem("You are a beginner").
+		CallTool("reply", map[string]any{"answer": "Use us-east-1."})
+
+	respond := CreateDeveloperResponderWithProvider(provider, "")
+	answer, err := respond(context.Background(), "You are a beginner", "Which region?")
+	require.NoError(t, err)
+	assert.Equal(t, "Use us-east-1.", answer)
+
+	req, ok := provider.LastRequest()
+	require.True(t, ok)
+	assert.Equal(t, "reply", req.ToolChoice)
+}
+
+func TestCreateDeveloperResponderWithProvider_AgenticProvider(t *testing.T) {
+	provider := fake.New().
+		WithCapabilities(providers.Capabilities{SystemPrompt: true, Agentic: true}).
+		ReplyText(`Sure! {"answer": "I don't know, pick one."}`)
+
+	respond := CreateDeveloperResponderWithProvider(provider, "")
+	answer, err := respond(context.Background(), "You are a beginner", "Which region?")
+	require.NoError(t, err)
+	assert.Equal(t, "I don't know, pick one.", answer)
+}
+
 // mockProvider implements providers.Provider for testing
 type mockProvider struct{}
 
@@ -0,0 +1,115 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Default budget settings.
+const (
+	DefaultMaxTurns         = 100
+	DefaultMaxContinuations = 3
+)
+
+// ErrBudgetExceeded is matched by every BudgetExceededError.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetLimit names a limit in a Budget.
+type BudgetLimit string
+
+const (
+	// BudgetTurns limits the number of model requests.
+	BudgetTurns BudgetLimit = "turns"
+
+	// BudgetTokens limits the total tokens reported by the provider.
+	BudgetTokens BudgetLimit = "tokens"
+
+	// BudgetToolCalls limits the number of calls to a single tool.
+	BudgetToolCalls BudgetLimit = "tool_calls"
+
+	// BudgetDuration limits the wall time of a run.
+	BudgetDuration BudgetLimit = "duration"
+
+	// BudgetContinuations limits how often a response cut off by the
+	// token limit is continued.
+	BudgetContinuations BudgetLimit = "continuations"
+)
+
+// Budget limits an agent run. The run stops with a *BudgetExceededError
+// as soon as any limit is reached. Zero values use the defaults; limits
+// without a default are unlimited. A resumed run also counts what it used
+// before the checkpoint.
+type Budget struct {
+	// MaxTurns is the maximum number of model requests, including
+	// continuations (default: 100)
+	MaxTurns int
+
+	// MaxTotalTokens is the maximum number of tokens across all requests,
+	// as reported by the provider (optional)
+	MaxTotalTokens int
+
+	// MaxToolCalls is the maximum number of calls to any one tool (optional)
+	MaxToolCalls int
+
+	// ToolCallLimits overrides MaxToolCalls for individual tools (optional)
+	ToolCallLimits map[string]int
+
+	// MaxDuration is the maximum wall time of a run (optional)
+	MaxDuration time.Duration
+
+	// MaxContinuations is the number of times a response cut off by the
+	// token limit is continued (default: 3)
+	MaxContinuations int
+}
+
+// withDefaults returns b with defaults applied.
+func (b Budget) withDefaults() Budget {
+	if b.MaxTurns == 0 {
+		b.MaxTurns = DefaultMaxTurns
+	}
+	if b.MaxContinuations == 0 {
+		b.MaxContinuations = DefaultMaxContinuations
+	}
+	return b
+}
+
+// toolCallLimit returns the call limit for a tool (0 if unlimited).
+func (b Budget) toolCallLimit(name string) int {
+	if limit, ok := b.ToolCallLimits[name]; ok {
+		return limit
+	}
+	return b.MaxToolCalls
+}
+
+// BudgetExceededError reports which budget limit stopped a run.
+type BudgetExceededError struct {
+	// Limit is the exhausted limit
+	Limit BudgetLimit
+
+	// Tool is the tool whose call limit was reached (for BudgetToolCalls)
+	Tool string
+
+	// Used and Max are the amount consumed and the configured limit.
+	// For BudgetDuration they are time.Duration values.
+	Used, Max int64
+}
+
+// Error describes the exhausted limit.
+func (e *BudgetExceededError) Error() string {
+	switch e.Limit {
+	case BudgetDuration:
+		return fmt.Sprintf("budget exceeded: run took longer than %s", time.Duration(e.Max))
+	case BudgetToolCalls:
+		return fmt.Sprintf("budget exceeded: %s called more than %d times", e.Tool, e.Max)
+	case BudgetContinuations:
+		return fmt.Sprintf("budget exceeded: response still cut off by the token limit after %d continuations", e.Max)
+	default:
+		return fmt.Sprintf("budget exceeded: %d %s used of %d", e.Used, e.Limit, e.Max)
+	}
+}
+
+// Is reports whether target is ErrBudgetExceeded.
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
@@ -0,0 +1,152 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lex00/wetwire-core-go/agent/results"
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// CheckpointVersion is the checkpoint file format written by this package.
+const CheckpointVersion = 1
+
+// Checkpoint is the state of an agent run. With AgentConfig.CheckpointPath
+// set, the agent saves one after every turn, so that Agent.Resume can
+// continue a run whose process died or whose provider failed.
+type Checkpoint struct {
+	// Version is the file format (CheckpointVersion)
+	Version int `json:"version"`
+
+	// Prompt is the task prompt
+	Prompt string `json:"prompt"`
+
+	// Messages is the history for the next request, after any compaction
+	Messages []providers.Message `json:"messages"`
+
+	// Continued is the text of responses cut off by the token limit that
+	// the model is still continuing
+	Continued string `json:"continued,omitempty"`
+
+	// Continuations is how often the current response has been continued
+	Continuations int `json:"continuations,omitempty"`
+
+	// Repairs is how many verification repair rounds the run has used
+	Repairs int `json:"repairs,omitempty"`
+
+	// Result is the run so far
+	Result *RunResult `json:"result"`
+
+	// Session is the agent's session (nil if it has none)
+	Session *results.Session `json:"session,omitempty"`
+
+	// SavedAt is when the checkpoint was written
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// LoadCheckpoint reads a checkpoint file. The error wraps os.ErrNotExist
+// if there is none.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	if cp.Version != CheckpointVersion {
+		return nil, fmt.Errorf("checkpoint %s has version %d, want %d", path, cp.Version, CheckpointVersion)
+	}
+	if cp.Result == nil || len(cp.Messages) == 0 {
+		return nil, fmt.Errorf("checkpoint %s has no history", path)
+	}
+	return &cp, nil
+}
+
+// Save writes the checkpoint to path. The file is replaced atomically, so
+// a crash while saving leaves the previous checkpoint intact.
+func (c *Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// interruptedMessage is the tool result for a call that was in flight
+// when the run stopped.
+const interruptedMessage = "The run was interrupted while this tool call was in progress, so it may or may not have taken effect. Check the current state before calling it again."
+
+// resumePrompt asks the model to carry on after a run stopped between turns.
+const resumePrompt = "The run was interrupted. Continue the task from where you left off."
+
+// resumeMessages completes a checkpoint's history so the next request is
+// valid. Tool calls from the last response are answered with their
+// recorded results; calls without one were in flight when the run stopped,
+// and get an error telling the model their effect is unknown. A history
+// ending in a plain assistant message gets a prompt to continue.
+func resumeMessages(messages []providers.Message, result *RunResult) ([]providers.Message, []ToolCallRecord) {
+	last := messages[len(messages)-1]
+	if last.Role != "assistant" {
+		return nil, nil
+	}
+
+	turn := len(result.Turns)
+	recorded := make(map[string]ToolCallRecord)
+	for _, call := range result.ToolCalls {
+		if call.Turn == turn {
+			recorded[call.ID] = call
+		}
+	}
+
+	var interrupted []ToolCallRecord
+	var toolResults []providers.ContentBlock
+	for _, block := range last.Content {
+		if block.Type != "tool_use" {
+			continue
+		}
+		call, ok := recorded[block.ID]
+		if !ok {
+			call = ToolCallRecord{
+				Turn:    turn,
+				ID:      block.ID,
+				Name:    block.Name,
+				Input:   block.Input,
+				Output:  interruptedMessage,
+				IsError: true,
+			}
+			interrupted = append(interrupted, call)
+		}
+		toolResults = append(toolResults, providers.NewToolResult(call.ID, call.Output, call.IsError))
+	}
+
+	if len(toolResults) == 0 {
+		return []providers.Message{providers.NewUserMessage(resumePrompt)}, nil
+	}
+	return []providers.Message{providers.NewToolResultMessage(toolResults)}, interrupted
+}
@@ -0,0 +1,197 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/lex00/wetwire-core-go/agent/results"
+)
+
+// Guardrail hooks into an agent run to enforce rules the model might skip.
+// Nil hooks are skipped. Hooks run one at a time, in tool call order, even
+// when tool calls run in parallel.
+type Guardrail struct {
+	// BeforeRun runs when a run starts or resumes, with the result so far
+	// (empty for a new run). Guardrails that keep state reset or rebuild
+	// it here.
+	BeforeRun func(ctx context.Context, result *RunResult)
+
+	// BeforeTool runs before a tool call, with Output unset. An error
+	// skips the call and is sent to the model as a tool error.
+	BeforeTool func(ctx context.Context, call ToolCallRecord) error
+
+	// AfterTool runs after every tool call, including failed and denied
+	// ones. A non-empty message is sent to the model with the tool results.
+	AfterTool func(ctx context.Context, call ToolCallRecord) string
+
+	// BeforeFinish runs when the model ends its turn. A non-empty message
+	// vetoes completion and is sent to the model, which keeps working.
+	BeforeFinish func(ctx context.Context, result *RunResult) string
+}
+
+// Tools watched by LintGuardrail.
+var (
+	lintWriteTools = []string{"wetwire_write", "write_file"}
+	lintTools      = []string{"wetwire_lint", "run_lint"}
+)
+
+// Messages sent by LintGuardrail when it vetoes completion.
+const (
+	lintPendingMessage = `You have written code since the last lint run.
+You MUST call wetwire_lint to validate your latest changes before finishing.`
+
+	lintFailedMessage = `The linter found issues that have not been resolved.
+You MUST fix the lint errors and run wetwire_lint again until it passes.`
+)
+
+// LintGuardrail returns a guardrail that keeps the agent from finishing
+// until lint has passed after its last write. It watches the wetwire_write
+// and wetwire_lint tools, and RunnerAgent's write_file and run_lint. Each
+// lint call is recorded as a lint cycle in session, which should be the
+// agent's session (optional).
+//
+// A lint call passes if it does not fail and its output is not a JSON
+// result with "success": false. Issues are read from the result's
+// "errors" or "issues" messages.
+func LintGuardrail(session *results.Session) Guardrail {
+	var state lintState
+
+	return Guardrail{
+		BeforeRun: func(ctx context.Context, result *RunResult) {
+			// A resumed run continues from the calls it already made
+			state = lintState{}
+			for _, call := range result.ToolCalls {
+				state.observe(call)
+			}
+		},
+		AfterTool: func(ctx context.Context, call ToolCallRecord) string {
+			if issues, fixed, linted := state.observe(call); linted && session != nil {
+				session.AddLintCycle(issues, fixed, state.passed)
+			}
+			return ""
+		},
+		BeforeFinish: func(ctx context.Context, result *RunResult) string {
+			switch {
+			case !state.wrote:
+				return ""
+			case state.pending:
+				return lintPendingMessage
+			case !state.passed:
+				return lintFailedMessage
+			}
+			return ""
+		},
+	}
+}
+
+// lintState tracks the writes and lint calls of a run.
+type lintState struct {
+	wrote, pending, passed bool
+	lastIssues             int
+}
+
+// observe updates the state with a tool call. For a lint call it returns
+// the issues found, how many fewer there are than in the previous lint
+// call, and true.
+func (s *lintState) observe(call ToolCallRecord) (issues []string, fixed int, linted bool) {
+	switch {
+	case slices.Contains(lintWriteTools, call.Name):
+		// An interrupted write may have taken effect
+		if !call.DryRun && (!call.IsError || call.Output == interruptedMessage) {
+			s.wrote, s.pending, s.passed = true, true, false
+		}
+	case slices.Contains(lintTools, call.Name):
+		if call.DryRun {
+			return nil, 0, false
+		}
+		s.passed, issues = lintResult(call)
+		s.pending = false
+		fixed = max(s.lastIssues-len(issues), 0)
+		s.lastIssues = len(issues)
+		return issues, fixed, true
+	}
+	return nil, 0, false
+}
+
+// lintResult reports whether a lint call passed and the issues it found.
+func lintResult(call ToolCallRecord) (bool, []string) {
+	if call.IsError {
+		line, _, _ := strings.Cut(strings.TrimSpace(call.Output), "\n")
+		return false, []string{line}
+	}
+
+	var result struct {
+		Success *bool `json:"success"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+		Issues []struct {
+			Message string `json:"message"`
+		} `json:"issues"`
+	}
+	if err := json.Unmarshal([]byte(call.Output), &result); err != nil || result.Success == nil {
+		return true, nil
+	}
+
+	var issues []string
+	for _, e := range append(result.Errors, result.Issues...) {
+		issues = append(issues, e.Message)
+	}
+	return *result.Success, issues
+}
+
+// beforeRun runs the BeforeRun hooks.
+func (a *Agent) beforeRun(ctx context.Context, result *RunResult) {
+	for _, g := range a.guardrails {
+		if g.BeforeRun != nil {
+			g.BeforeRun(ctx, result)
+		}
+	}
+}
+
+// beforeTool runs the BeforeTool hooks, stopping at the first error.
+func (a *Agent) beforeTool(ctx context.Context, call ToolCallRecord) error {
+	for _, g := range a.guardrails {
+		if g.BeforeTool == nil {
+			continue
+		}
+		if err := g.BeforeTool(ctx, call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterTools runs the AfterTool hooks for each call and returns their
+// messages joined into one.
+func (a *Agent) afterTools(ctx context.Context, calls []ToolCallRecord) string {
+	var messages []string
+	for _, call := range calls {
+		for _, g := range a.guardrails {
+			if g.AfterTool == nil {
+				continue
+			}
+			if msg := g.AfterTool(ctx, call); msg != "" && !slices.Contains(messages, msg) {
+				messages = append(messages, msg)
+			}
+		}
+	}
+	return strings.Join(messages, "\n\n")
+}
+
+// beforeFinish runs the BeforeFinish hooks and returns the veto messages
+// joined into one, or "" if the run may finish.
+func (a *Agent) beforeFinish(ctx context.Context, result *RunResult) string {
+	var messages []string
+	for _, g := range a.guardrails {
+		if g.BeforeFinish == nil {
+			continue
+		}
+		if msg := g.BeforeFinish(ctx, result); msg != "" {
+			messages = append(messages, msg)
+		}
+	}
+	return strings.Join(messages, "\n\n")
+}
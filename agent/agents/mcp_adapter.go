@@ -31,6 +31,8 @@ func (a *MCPServerAdapter) GetTools() []MCPToolInfo {
 			Name:        t.Name,
 			Description: t.Description,
 			InputSchema: t.InputSchema,
+			Parallel:    t.Parallel,
+			ConflictKey: t.ConflictKey,
 		}
 	}
 	return tools
@@ -0,0 +1,101 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/lex00/wetwire-core-go/agent/events"
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// callTools executes the tool calls from one response and returns their
+// records in the original order. Calls refused by a guardrail are not run.
+// Calls to parallel tools overlap, up to MaxParallelTools at a time;
+// exclusive calls wait for every earlier call and hold back later ones, and
+// parallel calls sharing a conflict key run in order.
+func (a *Agent) callTools(ctx context.Context, turn int, blocks []providers.ContentBlock, tools map[string]MCPToolInfo) []ToolCallRecord {
+	records := make([]ToolCallRecord, len(blocks))
+	var pending []int
+	for i, block := range blocks {
+		records[i] = ToolCallRecord{Turn: turn, ID: block.ID, Name: block.Name, Input: block.Input}
+		if err := a.beforeTool(ctx, records[i]); err != nil {
+			records[i].Output, records[i].IsError = err.Error(), true
+			e := toolEvent(events.GuardrailTriggered, records[i])
+			e.Text = err.Error()
+			a.emit(e)
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	if a.maxParallelTools <= 1 || len(pending) <= 1 {
+		for _, i := range pending {
+			records[i] = a.callTool(ctx, turn, blocks[i])
+		}
+		return records
+	}
+
+	done := make([]chan struct{}, len(blocks))
+	slots := make(chan struct{}, a.maxParallelTools)
+	exclusive := -1
+	lastByKey := make(map[string]int)
+
+	var wg sync.WaitGroup
+	for k, i := range pending {
+		block := blocks[i]
+		done[i] = make(chan struct{})
+
+		// Indexes of the calls that must finish first
+		var after []int
+		if tool, ok := tools[block.Name]; !ok || !tool.Parallel {
+			after = pending[:k]
+			exclusive = i
+		} else {
+			if exclusive >= 0 {
+				after = append(after, exclusive)
+			}
+			if key, ok := conflictKey(tool, block.Input); ok {
+				if j, ok := lastByKey[key]; ok {
+					after = append(after, j)
+				}
+				lastByKey[key] = i
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, j := range after {
+				<-done[j]
+			}
+			slots <- struct{}{}
+			records[i] = a.callTool(ctx, turn, block)
+			<-slots
+		}()
+	}
+	wg.Wait()
+
+	return records
+}
+
+// conflictKey returns the value of a call's conflict key property, prefixed
+// with the property name so that different properties never conflict.
+func conflictKey(tool MCPToolInfo, input json.RawMessage) (string, bool) {
+	if tool.ConflictKey == "" {
+		return "", false
+	}
+
+	var args map[string]any
+	if err := json.Unmarshal(input, &args); err != nil {
+		return "", false
+	}
+	value, ok := args[tool.ConflictKey]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%s=%v", tool.ConflictKey, value), true
+}
@@ -0,0 +1,110 @@
+package agents
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// Termination is the reason an agent run ended.
+type Termination string
+
+const (
+	// TerminationCompleted means the model ended its turn.
+	TerminationCompleted Termination = "completed"
+
+	// TerminationBudgetExceeded means a Budget limit was reached.
+	TerminationBudgetExceeded Termination = "budget_exceeded"
+
+	// TerminationVerificationFailed means the work still failed
+	// verification after the last repair round.
+	TerminationVerificationFailed Termination = "verification_failed"
+
+	// TerminationCancelled means the context was cancelled.
+	TerminationCancelled Termination = "cancelled"
+
+	// TerminationError means a provider, tool or history error ended the run.
+	TerminationError Termination = "error"
+)
+
+// RunResult describes a completed or failed agent run.
+type RunResult struct {
+	// Messages is the full transcript, starting with the prompt.
+	// History compaction does not affect it.
+	Messages []providers.Message `json:"messages"`
+
+	// Turns records each model request, in order
+	Turns []Turn `json:"turns"`
+
+	// ToolCalls records every tool call, in order
+	ToolCalls []ToolCallRecord `json:"tool_calls,omitempty"`
+
+	// FinalText is the text of the model's last response, including any
+	// responses it continued after hitting the token limit
+	FinalText string `json:"final_text,omitempty"`
+
+	// StopReason is the stop reason of the last response
+	StopReason providers.StopReason `json:"stop_reason,omitempty"`
+
+	// Termination is why the run ended
+	Termination Termination `json:"termination,omitempty"`
+
+	// Verifications is the number of verification rounds run
+	Verifications int `json:"verifications,omitempty"`
+
+	// Usage is the token usage summed over all turns
+	Usage providers.Usage `json:"usage"`
+
+	// Duration is the wall time of the run
+	Duration time.Duration `json:"duration"`
+}
+
+// Turn records a single model request.
+type Turn struct {
+	// Number is the 1-based turn number
+	Number int `json:"number"`
+
+	// Provider is the backend that served the turn
+	Provider string `json:"provider"`
+
+	// StopReason is why the model stopped generating
+	StopReason providers.StopReason `json:"stop_reason,omitempty"`
+
+	// Usage reports the tokens consumed by the turn
+	Usage providers.Usage `json:"usage"`
+
+	// Start is when the request was sent
+	Start time.Time `json:"start"`
+
+	// Duration is the request latency, excluding tool execution
+	Duration time.Duration `json:"duration"`
+}
+
+// ToolCallRecord records a single tool call.
+type ToolCallRecord struct {
+	// Turn is the number of the turn that requested the call
+	Turn int `json:"turn"`
+
+	// ID is the tool_use ID
+	ID string `json:"id"`
+
+	// Name of the tool
+	Name string `json:"name"`
+
+	// Input is the JSON input sent by the model
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// Output is the tool result, or the error message if the call failed
+	Output string `json:"output"`
+
+	// IsError is true if the call failed or was denied
+	IsError bool `json:"is_error,omitempty"`
+
+	// DryRun is true if the permission policy recorded the call without
+	// executing it
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Duration is how long the call took
+	Duration time.Duration `json:"duration"`
+}
@@ -0,0 +1,180 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lex00/wetwire-core-go/agent/events"
+	"github.com/lex00/wetwire-core-go/agent/permissions"
+	"github.com/lex00/wetwire-core-go/domain"
+)
+
+// DefaultMaxRepairs is how many repair rounds a failed verification gets
+// when Verification.MaxRepairs is unset.
+const DefaultMaxRepairs = 3
+
+// Verification checks the agent's work after the model ends its turn, by
+// calling tools such as wetwire_build and wetwire_validate. If a check
+// fails, its errors are sent to the model as a new user turn so it can
+// repair the project, and the checks run again when it finishes. Each
+// round is recorded in the session.
+type Verification struct {
+	// Steps are the tool calls to make, in order. Verification stops at
+	// the first failing step (required)
+	Steps []VerificationStep
+
+	// MaxRepairs is how many repair rounds the model gets before the run
+	// fails with a *VerificationError (default: DefaultMaxRepairs)
+	MaxRepairs int
+}
+
+// VerificationStep is a tool call made during verification.
+type VerificationStep struct {
+	// Tool is the MCP tool to call
+	Tool string
+
+	// Args are the tool arguments (optional)
+	Args map[string]any
+}
+
+// ErrVerificationFailed is matched by every VerificationError.
+var ErrVerificationFailed = errors.New("verification failed")
+
+// VerificationError reports work that still failed verification after the
+// last repair round.
+type VerificationError struct {
+	// Tool is the step that failed
+	Tool string
+
+	// Errors are the errors it reported
+	Errors []string
+
+	// Rounds is the number of verification rounds run
+	Rounds int
+}
+
+// Error describes the failure.
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("verification failed after %d rounds: %s: %s", e.Rounds, e.Tool, strings.Join(e.Errors, "; "))
+}
+
+// Is reports whether target is ErrVerificationFailed.
+func (e *VerificationError) Is(target error) bool {
+	return target == ErrVerificationFailed
+}
+
+// repairMessage asks the model to fix the errors of a failed verification.
+const repairMessage = `Verification failed: %s reported errors.
+
+%s
+
+Fix these errors. Your work is verified again when you finish.`
+
+// verify runs a verification round once the model has finished. It returns
+// a message for the model if the work failed and may be repaired, and a
+// *VerificationError if it failed with no repair rounds left.
+func (a *Agent) verify(ctx context.Context, st *runState, turn int) (string, error) {
+	if a.verification == nil || len(a.verification.Steps) == 0 {
+		return "", nil
+	}
+
+	tool, errs, err := a.runVerification(ctx)
+	if err != nil {
+		return "", err
+	}
+	passed := tool == ""
+	st.result.Verifications++
+
+	if a.session != nil {
+		a.session.AddVerification(tool, errs, passed)
+	}
+	a.emit(events.Event{
+		Type:    events.Verified,
+		Turn:    turn,
+		Tool:    tool,
+		IsError: !passed,
+		Text:    strings.Join(errs, "\n"),
+	})
+
+	if passed {
+		return "", nil
+	}
+
+	maxRepairs := a.verification.MaxRepairs
+	if maxRepairs == 0 {
+		maxRepairs = DefaultMaxRepairs
+	}
+	if st.repairs >= maxRepairs {
+		return "", &VerificationError{Tool: tool, Errors: errs, Rounds: st.result.Verifications}
+	}
+	st.repairs++
+
+	lines := make([]string, len(errs))
+	for i, e := range errs {
+		lines[i] = "- " + e
+	}
+	return fmt.Sprintf(repairMessage, tool, strings.Join(lines, "\n")), nil
+}
+
+// runVerification calls each step in order and returns the first failing
+// tool and its errors, or "" if every step passed. Steps go through the
+// permission policy like any other tool call: a dry-run step is skipped,
+// and a denied step or a cancelled context is returned as an error, since
+// no repair by the model can make it pass.
+func (a *Agent) runVerification(ctx context.Context) (string, []string, error) {
+	for _, step := range a.verification.Steps {
+		args := step.Args
+		if args == nil {
+			args = map[string]any{}
+		}
+		input, err := json.Marshal(args)
+		if err != nil {
+			return "", nil, fmt.Errorf("verification step %s: %w", step.Tool, err)
+		}
+
+		output, err := a.executeTool(ctx, step.Tool, input)
+		switch {
+		case errors.Is(err, permissions.ErrDryRun):
+			continue
+		case errors.Is(err, permissions.ErrDenied):
+			return "", nil, fmt.Errorf("verification step %s: %w", step.Tool, err)
+		case err != nil:
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return "", nil, ctxErr
+			}
+			return step.Tool, []string{err.Error()}, nil
+		}
+		if passed, errs := verificationResult(output); !passed {
+			return step.Tool, errs, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// verificationResult reports whether a step's output passed and the errors
+// it reported. Output that is not a domain.Result passes.
+func verificationResult(output string) (bool, []string) {
+	var result struct {
+		domain.Result
+		Success *bool `json:"success"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil || result.Success == nil || *result.Success {
+		return true, nil
+	}
+
+	var errs []string
+	for _, e := range result.Errors {
+		errs = append(errs, e.String())
+	}
+	if len(errs) == 0 {
+		msg := result.Message
+		if msg == "" {
+			msg = "failed without details"
+		}
+		errs = append(errs, msg)
+	}
+	return false, errs
+}
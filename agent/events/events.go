@@ -0,0 +1,208 @@
+// Package events defines the lifecycle events emitted by running agents.
+//
+// Agents report what they are doing to an Observer: turns and model
+// requests, streamed text, tool calls, developer questions, guardrails and
+// budgets, and the end of the run. Progress UIs and metrics can be built on
+// the events instead of scraping output. Every event carries the session
+// and persona it belongs to, so one observer can follow several runs.
+//
+// Example:
+//
+//	ch := make(chan events.Event, 64)
+//	agent, err := agents.NewAgent(agents.AgentConfig{
+//		...
+//		Observer: events.Channel(ch),
+//	})
+//
+//	go func() {
+//		for e := range ch {
+//			if e.Type == events.ToolFinished {
+//				fmt.Printf("%s took %s\n", e.Tool, e.Duration)
+//			}
+//		}
+//	}()
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// Type identifies an event.
+type Type string
+
+const (
+	// RunStarted is emitted when a run starts or resumes. Text is the prompt.
+	RunStarted Type = "run_started"
+
+	// TurnStarted is emitted at the start of each turn.
+	TurnStarted Type = "turn_started"
+
+	// RequestSent is emitted when a model request is sent. Messages is the
+	// size of the history sent.
+	RequestSent Type = "request_sent"
+
+	// TextDelta is emitted for each chunk of streamed model text.
+	TextDelta Type = "text_delta"
+
+	// TurnFinished is emitted when the model responds, with the turn's
+	// StopReason, Usage and request Duration, or Error if the request failed.
+	TurnFinished Type = "turn_finished"
+
+	// ToolStarted is emitted before a tool call runs.
+	ToolStarted Type = "tool_started"
+
+	// ToolFinished is emitted after a tool call, with its Output, Duration
+	// and whether it failed.
+	ToolFinished Type = "tool_finished"
+
+	// QuestionAsked is emitted when the agent asks the developer a
+	// question. Text is the question.
+	QuestionAsked Type = "question_asked"
+
+	// QuestionAnswered is emitted with the developer's answer as Text, or
+	// Error if there is none.
+	QuestionAnswered Type = "question_answered"
+
+	// GuardrailTriggered is emitted when a guardrail refuses a tool call,
+	// corrects the model or vetoes completion. Text is its message.
+	GuardrailTriggered Type = "guardrail_triggered"
+
+	// Verified is emitted after each verification round of the agent's
+	// work. If it failed, IsError is set, Tool is the failing tool and Text
+	// lists its errors.
+	Verified Type = "verified"
+
+	// BudgetExceeded is emitted when a run budget is exhausted. Text names
+	// the limit and Error describes it.
+	BudgetExceeded Type = "budget_exceeded"
+
+	// RunFinished is emitted when a run ends, with its Termination, total
+	// Usage and Duration, and Error if it failed.
+	RunFinished Type = "run_finished"
+)
+
+// Event is something that happened during a run. Fields that do not apply
+// to the event's type are left empty.
+type Event struct {
+	// Type identifies the event
+	Type Type `json:"type"`
+
+	// Time is when the event happened
+	Time time.Time `json:"time"`
+
+	// SessionID is the ID of the run's session (empty if it has none)
+	SessionID string `json:"session_id,omitempty"`
+
+	// Persona is the persona of the run's session (empty if it has none)
+	Persona string `json:"persona,omitempty"`
+
+	// Turn is the 1-based number of the turn the event belongs to
+	Turn int `json:"turn,omitempty"`
+
+	// Text is the prompt, text delta, question, answer or message
+	Text string `json:"text,omitempty"`
+
+	// Messages is the number of messages in a request
+	Messages int `json:"messages,omitempty"`
+
+	// ToolID is the tool_use ID of a tool call
+	ToolID string `json:"tool_id,omitempty"`
+
+	// Tool is the name of the tool called
+	Tool string `json:"tool,omitempty"`
+
+	// Input is the JSON input of a tool call
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// Output is the result of a tool call, or its error message
+	Output string `json:"output,omitempty"`
+
+	// IsError is true if a tool call failed or was denied
+	IsError bool `json:"is_error,omitempty"`
+
+	// DryRun is true if a tool call was recorded without being executed
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// StopReason is why the model stopped generating
+	StopReason providers.StopReason `json:"stop_reason,omitempty"`
+
+	// Usage is the token usage of a turn or run
+	Usage providers.Usage `json:"usage"`
+
+	// Duration is how long a turn's request, tool call or run took
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// Termination is why a run ended (see agents.Termination)
+	Termination string `json:"termination,omitempty"`
+
+	// Error describes a failure
+	Error string `json:"error,omitempty"`
+}
+
+// Observer receives the events of a run. Observe is called synchronously
+// while the agent runs, so it should return quickly.
+type Observer interface {
+	Observe(e Event)
+}
+
+// Func adapts a function to an Observer.
+type Func func(e Event)
+
+// Observe calls f.
+func (f Func) Observe(e Event) {
+	f(e)
+}
+
+// Multi returns an observer that delivers each event to every observer in
+// order. Nil observers are skipped.
+func Multi(observers ...Observer) Observer {
+	return multi(observers)
+}
+
+type multi []Observer
+
+// Observe delivers e to each observer.
+func (m multi) Observe(e Event) {
+	for _, o := range m {
+		if o != nil {
+			o.Observe(e)
+		}
+	}
+}
+
+// Channel returns an observer that sends each event to ch. Sends block, so
+// the receiver must keep reading until the run returns.
+func Channel(ch chan<- Event) Observer {
+	return Func(func(e Event) {
+		ch <- e
+	})
+}
+
+// Serialized returns an observer that delivers events to o one at a time,
+// for emitters that run concurrently, such as parallel tool calls or
+// personas. It returns nil if o is nil.
+func Serialized(o Observer) Observer {
+	if o == nil {
+		return nil
+	}
+	if s, ok := o.(*serialized); ok {
+		return s
+	}
+	return &serialized{observer: o}
+}
+
+type serialized struct {
+	mu       sync.Mutex
+	observer Observer
+}
+
+// Observe delivers e while holding the lock.
+func (s *serialized) Observe(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observer.Observe(e)
+}
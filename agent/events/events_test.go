@@ -0,0 +1,59 @@
+package events
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMulti(t *testing.T) {
+	var first, second []Type
+	o := Multi(
+		Func(func(e Event) { first = append(first, e.Type) }),
+		nil,
+		Func(func(e Event) { second = append(second, e.Type) }),
+	)
+
+	o.Observe(Event{Type: RunStarted})
+	o.Observe(Event{Type: RunFinished})
+
+	assert.Equal(t, []Type{RunStarted, RunFinished}, first)
+	assert.Equal(t, first, second)
+}
+
+func TestChannel(t *testing.T) {
+	ch := make(chan Event, 2)
+	o := Channel(ch)
+
+	o.Observe(Event{Type: ToolStarted, Tool: "wetwire_lint"})
+	o.Observe(Event{Type: ToolFinished, Tool: "wetwire_lint"})
+	close(ch)
+
+	var got []Type
+	for e := range ch {
+		assert.Equal(t, "wetwire_lint", e.Tool)
+		got = append(got, e.Type)
+	}
+	assert.Equal(t, []Type{ToolStarted, ToolFinished}, got)
+}
+
+func TestSerialized(t *testing.T) {
+	assert.Nil(t, Serialized(nil))
+
+	// The unsynchronized counter is safe only if events arrive one at a time
+	count := 0
+	o := Serialized(Func(func(e Event) { count++ }))
+	assert.Same(t, o, Serialized(o))
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o.Observe(Event{Type: TextDelta})
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 50, count)
+}
@@ -0,0 +1,411 @@
+// Package history provides policies for keeping an agent's conversation
+// history within the model's context window.
+//
+// An agent appends every assistant message and tool result to its history.
+// Long build/lint/fix loops with large tool results eventually exceed the
+// context window. Once the estimated size of the history passes a threshold,
+// the agent applies a Policy to shrink it before the next request.
+//
+// Policies operate on turns: a turn is an assistant message followed by the
+// user messages (usually tool results) that answer it. Policies never split a
+// turn, so every tool_use keeps its tool_result, and never touch the first
+// message (the task prompt) or the most recent turns.
+//
+// Example:
+//
+//	agent, err := agents.NewAgent(agents.AgentConfig{
+//		...
+//		History: history.Chain(
+//			history.TruncateToolResults{MaxChars: 4000},
+//			history.Summarize{Provider: provider, Model: "haiku"},
+//		),
+//	})
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// Default policy settings.
+const (
+	DefaultMaxChars = 2000
+	DefaultKeepLast = 2
+)
+
+// Policy compacts a conversation history.
+type Policy interface {
+	// Name identifies the policy in session records
+	Name() string
+
+	// Compact returns a shorter history. Budget is the token count the
+	// history should fit in; policies may stop once it does. The input
+	// slice must not be modified.
+	Compact(ctx context.Context, messages []providers.Message, budget int) ([]providers.Message, error)
+}
+
+// Default returns the policy used when an agent does not configure one:
+// large tool results in older turns are truncated, then the oldest turns
+// are dropped.
+func Default() Policy {
+	return Chain(TruncateToolResults{}, DropOldTurns{})
+}
+
+// imageTokens is the estimated cost of an image, which providers bill by
+// resolution rather than by encoded size. It is the cost of an image at the
+// largest size Anthropic accepts before downscaling.
+const imageTokens = 1600
+
+// EstimateTokens returns an approximate token count for messages,
+// at roughly four characters per token. Images count as imageTokens, and
+// documents and redacted thinking by their decoded size rather than their
+// base64 encoding.
+func EstimateTokens(messages []providers.Message) int {
+	chars, tokens := 0, 0
+	for _, msg := range messages {
+		for _, block := range msg.Content {
+			switch block.Type {
+			case "image":
+				tokens += imageTokens
+			case "document":
+				chars += mediaSize(block)
+			default:
+				chars += len(block.Text) + len(block.Input) + len(block.Content) +
+					len(block.Thinking) + base64.StdEncoding.DecodedLen(len(block.Data))
+			}
+		}
+	}
+	return tokens + chars/4
+}
+
+// mediaSize returns the decoded size of a media block, reading the size of
+// file-backed blocks from disk.
+func mediaSize(block providers.ContentBlock) int {
+	if block.Base64 == "" && block.Path != "" {
+		if info, err := os.Stat(block.Path); err == nil {
+			return int(info.Size())
+		}
+	}
+	return base64.StdEncoding.DecodedLen(len(block.Base64))
+}
+
+// Chain returns a policy that applies each policy in order until the
+// history fits the budget.
+func Chain(policies ...Policy) Policy {
+	return chain(policies)
+}
+
+type chain []Policy
+
+// Name returns the names of the chained policies.
+func (c chain) Name() string {
+	names := make([]string, len(c))
+	for i, p := range c {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// Compact applies each policy until the history fits the budget.
+func (c chain) Compact(ctx context.Context, messages []providers.Message, budget int) ([]providers.Message, error) {
+	for _, p := range c {
+		if EstimateTokens(messages) <= budget {
+			break
+		}
+		var err error
+		if messages, err = p.Compact(ctx, messages, budget); err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name(), err)
+		}
+	}
+	return messages, nil
+}
+
+// TruncateToolResults shortens large tool results outside the most recent turns.
+type TruncateToolResults struct {
+	// MaxChars is the number of characters kept from each tool result (default: 2000)
+	MaxChars int
+
+	// KeepLast is the number of recent turns left untouched (default: 2)
+	KeepLast int
+}
+
+// Name returns the policy name.
+func (t TruncateToolResults) Name() string {
+	return "truncate_tool_results"
+}
+
+// Compact truncates tool results in older turns.
+func (t TruncateToolResults) Compact(ctx context.Context, messages []providers.Message, budget int) ([]providers.Message, error) {
+	maxChars := orDefault(t.MaxChars, DefaultMaxChars)
+	first, old, recent := split(messages, orDefault(t.KeepLast, DefaultKeepLast))
+
+	result := append([]providers.Message{}, first...)
+	for _, msg := range old {
+		content := make([]providers.ContentBlock, len(msg.Content))
+		for i, block := range msg.Content {
+			if block.Type == "tool_result" && len(block.Content) > maxChars {
+				kept := truncate(block.Content, maxChars)
+				block.Content = fmt.Sprintf("%s\n... [truncated %d characters]", kept, len(block.Content)-len(kept))
+			}
+			content[i] = block
+		}
+		result = append(result, providers.Message{Role: msg.Role, Content: content})
+	}
+	return append(result, recent...), nil
+}
+
+// DropOldTurns removes the oldest turns until the history fits the budget.
+// A note in the first message tells the model how many turns were omitted.
+type DropOldTurns struct {
+	// KeepLast is the minimum number of recent turns kept (default: 2)
+	KeepLast int
+}
+
+// Name returns the policy name.
+func (d DropOldTurns) Name() string {
+	return "drop_old_turns"
+}
+
+// Compact drops the oldest turns.
+func (d DropOldTurns) Compact(ctx context.Context, messages []providers.Message, budget int) ([]providers.Message, error) {
+	keepLast := orDefault(d.KeepLast, DefaultKeepLast)
+	all := turns(messages)
+	if len(messages) == 0 || len(all) <= keepLast {
+		return messages, nil
+	}
+
+	// Drop one turn at a time until the history fits or only keepLast remain
+	dropped := 1
+	for dropped < len(all)-keepLast && EstimateTokens(dropTurns(messages[0], all, dropped)) > budget {
+		dropped++
+	}
+	return dropTurns(messages[0], all, dropped), nil
+}
+
+// dropTurns returns the history without its first n turns.
+func dropTurns(first providers.Message, all [][]providers.Message, n int) []providers.Message {
+	prompt, note := splitNote(first)
+	note.omitted += n
+	return append([]providers.Message{withNote(prompt, note)}, flatten(all[n:])...)
+}
+
+// Summarize replaces older turns with a summary written by a model,
+// typically a cheap one.
+type Summarize struct {
+	// Provider writes the summary (required)
+	Provider providers.Provider
+
+	// Model used for the summary (default: "haiku")
+	Model string
+
+	// KeepLast is the number of recent turns kept verbatim (default: 2)
+	KeepLast int
+
+	// MaxTokens limits the length of the summary (default: 1024)
+	MaxTokens int
+}
+
+// summaryPrompt instructs the model that writes the summary.
+const summaryPrompt = `You summarize the earlier part of an agent's work so it can continue with less context.
+Write a concise summary of what was done: files written, commands and tools run, errors found and how they were fixed, and what remains.
+Keep exact file paths, resource names and error messages. Do not invent anything.`
+
+// Name returns the policy name.
+func (s Summarize) Name() string {
+	return "summarize"
+}
+
+// Compact summarizes all but the most recent turns.
+func (s Summarize) Compact(ctx context.Context, messages []providers.Message, budget int) ([]providers.Message, error) {
+	if s.Provider == nil {
+		return nil, fmt.Errorf("summarize requires a provider")
+	}
+
+	first, old, recent := split(messages, orDefault(s.KeepLast, DefaultKeepLast))
+	if len(old) == 0 {
+		return messages, nil
+	}
+
+	// A previous compaction note is summarized along with the older turns
+	prompt, previous := splitNote(first[0])
+	input := transcript(old)
+	if text := previous.String(); text != "" {
+		input = "[earlier] " + text + "\n" + input
+	}
+
+	model := s.Model
+	if model == "" {
+		model = "haiku"
+	}
+	resp, err := s.Provider.CreateMessage(ctx, providers.MessageRequest{
+		Model:     model,
+		MaxTokens: orDefault(s.MaxTokens, 1024),
+		System:    summaryPrompt,
+		Messages:  []providers.Message{providers.NewUserMessage(input)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var summary strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			summary.WriteString(block.Text)
+		}
+	}
+
+	compacted := withNote(prompt, compactionNote{summary: strings.TrimSpace(summary.String())})
+	return append([]providers.Message{compacted}, recent...), nil
+}
+
+// turns splits the messages after the first into turns, each starting with
+// an assistant message.
+func turns(messages []providers.Message) [][]providers.Message {
+	var result [][]providers.Message
+	for i := 1; i < len(messages); i++ {
+		if messages[i].Role == "assistant" || len(result) == 0 {
+			result = append(result, nil)
+		}
+		result[len(result)-1] = append(result[len(result)-1], messages[i])
+	}
+	return result
+}
+
+// split returns the first message, the messages of older turns, and the
+// messages of the most recent keepLast turns.
+func split(messages []providers.Message, keepLast int) (first, old, recent []providers.Message) {
+	if len(messages) == 0 {
+		return nil, nil, nil
+	}
+
+	all := turns(messages)
+	cut := max(len(all)-keepLast, 0)
+	return messages[:1], flatten(all[:cut]), flatten(all[cut:])
+}
+
+// flatten joins turns back into a message list.
+func flatten(turns [][]providers.Message) []providers.Message {
+	var messages []providers.Message
+	for _, turn := range turns {
+		messages = append(messages, turn...)
+	}
+	return messages
+}
+
+// Compaction note formats.
+const (
+	summaryHeader = "Summary of earlier work:\n\n"
+	omittedFormat = "[%d earlier turns were omitted to save context]"
+)
+
+// compactionNote is the text block appended to the first message after
+// compaction. Each compaction replaces the previous note rather than adding
+// another one.
+type compactionNote struct {
+	summary string
+	omitted int
+}
+
+// String renders the note, or "" if it is empty.
+func (n compactionNote) String() string {
+	var parts []string
+	if n.summary != "" {
+		parts = append(parts, summaryHeader+n.summary)
+	}
+	if n.omitted > 0 {
+		parts = append(parts, fmt.Sprintf(omittedFormat, n.omitted))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// splitNote returns msg without its compaction note, and the note.
+func splitNote(msg providers.Message) (providers.Message, compactionNote) {
+	last := len(msg.Content) - 1
+	if last < 1 || msg.Content[last].Type != "text" {
+		return msg, compactionNote{}
+	}
+
+	var note compactionNote
+	text := msg.Content[last].Text
+	if i := strings.LastIndex(text, "["); i >= 0 {
+		if _, err := fmt.Sscanf(text[i:], omittedFormat, &note.omitted); err == nil {
+			text = strings.TrimSuffix(text[:i], "\n\n")
+		}
+	}
+	if strings.HasPrefix(text, summaryHeader) {
+		note.summary = strings.TrimPrefix(text, summaryHeader)
+	} else if text != "" || note.omitted == 0 {
+		return msg, compactionNote{}
+	}
+
+	return providers.Message{Role: msg.Role, Content: msg.Content[:last]}, note
+}
+
+// withNote returns a copy of msg with the note appended as a text block.
+func withNote(msg providers.Message, note compactionNote) providers.Message {
+	content := append([]providers.ContentBlock{}, msg.Content...)
+	content = append(content, providers.ContentBlock{Type: "text", Text: note.String()})
+	return providers.Message{Role: msg.Role, Content: content}
+}
+
+// truncate returns at most n bytes of s without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// transcript renders messages as plain text for summarization.
+func transcript(messages []providers.Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		for _, block := range msg.Content {
+			switch block.Type {
+			case "text":
+				fmt.Fprintf(&b, "[%s] %s\n", msg.Role, block.Text)
+			case "tool_use":
+				fmt.Fprintf(&b, "[tool call] %s %s\n", block.Name, compactJSON(block.Input))
+			case "tool_result":
+				content := block.Content
+				if len(content) > DefaultMaxChars {
+					content = truncate(content, DefaultMaxChars) + " ..."
+				}
+				status := "result"
+				if block.IsError {
+					status = "error"
+				}
+				fmt.Fprintf(&b, "[tool %s] %s\n", status, content)
+			}
+		}
+	}
+	return b.String()
+}
+
+// compactJSON returns raw JSON without insignificant whitespace.
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// orDefault returns v, or def if v is not positive.
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
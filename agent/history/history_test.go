@@ -0,0 +1,220 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/lex00/wetwire-core-go/providers"
+	"github.com/lex00/wetwire-core-go/providers/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conversation builds a prompt followed by n tool-use turns whose results
+// are size characters long.
+func conversation(n, size int) []providers.Message {
+	messages := []providers.Message{providers.NewUserMessage("Create a Lambda function")}
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("tool_%d", i)
+		messages = append(messages,
+			providers.NewAssistantMessage([]providers.ContentBlock{
+				{Type: "text", Text: fmt.Sprintf("Step %d", i)},
+				{Type: "tool_use", ID: id, Name: "run_build", Input: json.RawMessage(`{"path": "."}`)},
+			}),
+			providers.NewToolResultMessage([]providers.ContentBlock{
+				providers.NewToolResult(id, strings.Repeat("x", size), false),
+			}),
+		)
+	}
+	return messages
+}
+
+// assertPaired checks that every tool_use has a matching tool_result.
+func assertPaired(t *testing.T, messages []providers.Message) {
+	t.Helper()
+	uses := map[string]bool{}
+	for _, msg := range messages {
+		for _, block := range msg.Content {
+			switch block.Type {
+			case "tool_use":
+				uses[block.ID] = true
+			case "tool_result":
+				assert.True(t, uses[block.ToolUseID], "tool_result %s without tool_use", block.ToolUseID)
+				delete(uses, block.ToolUseID)
+			}
+		}
+	}
+	assert.Empty(t, uses, "tool_use without tool_result")
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(nil))
+	assert.Equal(t, 2, EstimateTokens([]providers.Message{providers.NewUserMessage("12345678")}))
+	assert.Greater(t, EstimateTokens(conversation(2, 4000)), 2000)
+}
+
+func TestEstimateTokensMedia(t *testing.T) {
+	image := providers.NewImage("image/png", make([]byte, 1<<20))
+	assert.Equal(t, imageTokens, EstimateTokens([]providers.Message{{Role: "user", Content: []providers.ContentBlock{image}}}))
+
+	doc := providers.NewDocument("application/pdf", make([]byte, 4000))
+	assert.Equal(t, 1000, EstimateTokens([]providers.Message{{Role: "user", Content: []providers.ContentBlock{doc}}}))
+
+	path := filepath.Join(t.TempDir(), "spec.pdf")
+	require.NoError(t, os.WriteFile(path, make([]byte, 8000), 0o600))
+	file, err := providers.NewMediaFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2000, EstimateTokens([]providers.Message{{Role: "user", Content: []providers.ContentBlock{file}}}))
+}
+
+func TestTruncateToolResults(t *testing.T) {
+	messages := conversation(4, 5000)
+
+	compacted, err := TruncateToolResults{MaxChars: 100}.Compact(context.Background(), messages, 0)
+	require.NoError(t, err)
+
+	require.Len(t, compacted, len(messages))
+	assert.Equal(t, messages[0], compacted[0])
+
+	// Older turns are truncated, the last two are untouched
+	assert.Contains(t, compacted[2].Content[0].Content, "[truncated 4900 characters]")
+	assert.Contains(t, compacted[4].Content[0].Content, "[truncated 4900 characters]")
+	assert.Len(t, compacted[6].Content[0].Content, 5000)
+	assert.Len(t, compacted[8].Content[0].Content, 5000)
+
+	// The input is not modified
+	assert.Len(t, messages[2].Content[0].Content, 5000)
+	assertPaired(t, compacted)
+}
+
+func TestTruncateToolResultsRuneBoundary(t *testing.T) {
+	messages := conversation(3, 0)
+	messages[2].Content[0].Content = strings.Repeat("é", 100)
+
+	compacted, err := TruncateToolResults{MaxChars: 11, KeepLast: 1}.Compact(context.Background(), messages, 0)
+	require.NoError(t, err)
+
+	content := compacted[2].Content[0].Content
+	assert.True(t, utf8.ValidString(content), "truncated content is not valid UTF-8: %q", content)
+	assert.True(t, strings.HasPrefix(content, strings.Repeat("é", 5)+"\n"), content)
+	assert.Contains(t, content, "[truncated 190 characters]")
+}
+
+func TestDropOldTurns(t *testing.T) {
+	messages := conversation(6, 4000)
+
+	t.Run("drops until under budget", func(t *testing.T) {
+		compacted, err := DropOldTurns{}.Compact(context.Background(), messages, 3500)
+		require.NoError(t, err)
+
+		// Three turns of ~1000 tokens fit
+		assert.Len(t, compacted, 1+3*2)
+		assert.Equal(t, "assistant", compacted[1].Role)
+		assert.Contains(t, compacted[0].Content[1].Text, "3 earlier turns were omitted")
+		assert.LessOrEqual(t, EstimateTokens(compacted), 3500)
+		assertPaired(t, compacted)
+	})
+
+	t.Run("keeps the last turns", func(t *testing.T) {
+		compacted, err := DropOldTurns{KeepLast: 2}.Compact(context.Background(), messages, 0)
+		require.NoError(t, err)
+		assert.Len(t, compacted, 1+2*2)
+		assert.Equal(t, messages[len(messages)-1], compacted[len(compacted)-1])
+	})
+
+	t.Run("nothing to drop", func(t *testing.T) {
+		short := conversation(2, 10)
+		compacted, err := DropOldTurns{}.Compact(context.Background(), short, 0)
+		require.NoError(t, err)
+		assert.Equal(t, short, compacted)
+	})
+}
+
+func TestRepeatedCompactionReplacesNote(t *testing.T) {
+	ctx := context.Background()
+	messages := conversation(6, 10)
+
+	compacted, err := DropOldTurns{KeepLast: 4}.Compact(ctx, messages, 0)
+	require.NoError(t, err)
+	compacted = append(compacted, conversation(2, 10)[1:]...)
+
+	compacted, err = DropOldTurns{KeepLast: 4}.Compact(ctx, compacted, 0)
+	require.NoError(t, err)
+	require.Len(t, compacted[0].Content, 2)
+	assert.Equal(t, "[4 earlier turns were omitted to save context]", compacted[0].Content[1].Text)
+
+	summarizer := fake.New().
+		Expect(func(req providers.MessageRequest) error {
+			if !strings.HasPrefix(req.Messages[0].Content[0].Text, "[earlier] [4 earlier turns were omitted") {
+				return fmt.Errorf("transcript missing the previous note")
+			}
+			return nil
+		}).
+		ReplyText("Built the function.")
+
+	compacted, err = Summarize{Provider: summarizer, KeepLast: 2}.Compact(ctx, compacted, 0)
+	require.NoError(t, err)
+	require.NoError(t, summarizer.Err())
+	require.Len(t, compacted[0].Content, 2)
+	assert.Equal(t, "Summary of earlier work:\n\nBuilt the function.", compacted[0].Content[1].Text)
+
+	compacted, err = DropOldTurns{KeepLast: 1}.Compact(ctx, compacted, 0)
+	require.NoError(t, err)
+	require.Len(t, compacted[0].Content, 2)
+	assert.Equal(t, "Summary of earlier work:\n\nBuilt the function.\n\n[1 earlier turns were omitted to save context]", compacted[0].Content[1].Text)
+	assertPaired(t, compacted)
+}
+
+func TestSummarize(t *testing.T) {
+	messages := conversation(5, 3000)
+
+	summarizer := fake.New().
+		Expect(func(req providers.MessageRequest) error {
+			if req.Model != "haiku" {
+				return fmt.Errorf("expected haiku, got %s", req.Model)
+			}
+			if !strings.Contains(req.Messages[0].Content[0].Text, "[tool call] run_build {\"path\":\".\"}") {
+				return fmt.Errorf("transcript missing tool call")
+			}
+			return nil
+		}).
+		ReplyText("Built the function twice.")
+
+	compacted, err := Summarize{Provider: summarizer}.Compact(context.Background(), messages, 0)
+	require.NoError(t, err)
+	require.NoError(t, summarizer.Err())
+
+	assert.Len(t, compacted, 1+2*2)
+	assert.Equal(t, "Summary of earlier work:\n\nBuilt the function twice.", compacted[0].Content[1].Text)
+	assertPaired(t, compacted)
+}
+
+func TestSummarizeRequiresProvider(t *testing.T) {
+	_, err := Summarize{}.Compact(context.Background(), conversation(3, 10), 0)
+	assert.Error(t, err)
+}
+
+func TestChain(t *testing.T) {
+	messages := conversation(6, 4000)
+	policy := Chain(TruncateToolResults{MaxChars: 100}, DropOldTurns{})
+
+	assert.Equal(t, "truncate_tool_results+drop_old_turns", policy.Name())
+
+	// Truncation alone brings the history under budget, so no turns are dropped
+	compacted, err := policy.Compact(context.Background(), messages, 2500)
+	require.NoError(t, err)
+	assert.Len(t, compacted, len(messages))
+	assert.LessOrEqual(t, EstimateTokens(compacted), 2500)
+
+	// A smaller budget needs both
+	compacted, err = policy.Compact(context.Background(), messages, 1500)
+	require.NoError(t, err)
+	assert.Less(t, len(compacted), len(messages))
+	assertPaired(t, compacted)
+}
@@ -134,8 +134,16 @@ func (o *Orchestrator) CalculateScore(
 	score.LintQuality.Notes = notes
 	score.LintCycles = lintCycles
 
-	// Output validity
+	// Output validity, capped by the agent's own verification rounds
 	rating, notes = scoring.ScoreOutputValidity(validationErrors, validationWarnings)
+	if rounds := o.session.Verifications; len(rounds) > 0 {
+		verified, verifyNotes := scoring.ScoreVerification(len(rounds), rounds[len(rounds)-1].Passed)
+		if verified < rating {
+			rating = verified
+		}
+		notes += "; " + verifyNotes
+		score.Verifications = len(rounds)
+	}
 	score.OutputValidity.Rating = rating
 	score.OutputValidity.Notes = notes
 
@@ -146,6 +154,10 @@ func (o *Orchestrator) CalculateScore(
 	score.QuestionEfficiency.Notes = notes
 	score.QuestionCount = questionCount
 
+	// Usage
+	score.TotalTokens = o.session.TotalUsage().TotalTokens()
+	score.CostUSD = o.session.TotalCost()
+
 	o.session.Score = score
 	return score
 }
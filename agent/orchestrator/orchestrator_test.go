@@ -223,6 +223,19 @@ func TestOrchestrator_CalculateScore(t *testing.T) {
 	assert.Equal(t, score, orch.session.Score)
 }
 
+func TestOrchestrator_CalculateScore_Verification(t *testing.T) {
+	orch := New(Config{Persona: personas.Beginner, Scenario: "test"}, &MockDeveloper{}, &MockRunner{})
+
+	// The build failed once before the repair
+	orch.session.AddVerification("wetwire_build", []string{"undefined: Bucket"}, false)
+	orch.session.AddVerification("", nil, true)
+
+	score := orch.CalculateScore(3, 3, true, 0, 0)
+	assert.Equal(t, scoring.RatingGood, score.OutputValidity.Rating)
+	assert.Contains(t, score.OutputValidity.Notes, "Verified after 1 repair round")
+	assert.Equal(t, 2, score.Verifications)
+}
+
 func TestOrchestrator_CalculateScore_Partial(t *testing.T) {
 	config := Config{
 		Persona:  personas.Expert,
@@ -0,0 +1,307 @@
+// Package permissions provides policies that decide whether an agent may
+// run a tool call.
+//
+// The agent checks its Policy before every tool call. A denied call is not
+// executed; the denial is sent to the model as a tool error so it can try
+// something else. Policies can also approve calls through a human, or
+// record them as a dry run without executing them.
+//
+// Example:
+//
+//	agent, err := agents.NewAgent(agents.AgentConfig{
+//		...
+//		Permissions: permissions.All(
+//			permissions.DenyTools("wetwire_import"),
+//			permissions.PathsWithin{Dir: workDir},
+//			permissions.NoExistingProject{},
+//			&permissions.Approval{Approver: permissions.Ask(developer), Tools: []string{"wetwire_write"}},
+//		),
+//	})
+package permissions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrDenied is matched by every DeniedError.
+var ErrDenied = errors.New("permission denied")
+
+// ErrDryRun is returned by DryRun. The agent records the call as intended
+// but does not execute it.
+var ErrDryRun = errors.New("dry run")
+
+// Call is a tool call awaiting a decision.
+type Call struct {
+	// Tool is the tool name
+	Tool string
+
+	// Args are the decoded tool arguments
+	Args map[string]any
+}
+
+// Policy decides whether a tool call may run.
+type Policy interface {
+	// Check returns nil to allow the call, ErrDryRun to record it without
+	// executing it, or an error (usually a *DeniedError) to refuse it.
+	Check(ctx context.Context, call Call) error
+}
+
+// Func adapts a function to a Policy.
+type Func func(ctx context.Context, call Call) error
+
+// Check calls f.
+func (f Func) Check(ctx context.Context, call Call) error {
+	return f(ctx, call)
+}
+
+// DeniedError reports why a tool call was refused.
+type DeniedError struct {
+	// Tool is the refused tool
+	Tool string
+
+	// Reason explains the refusal to the model
+	Reason string
+}
+
+// Deny returns a *DeniedError for a call.
+func Deny(call Call, format string, args ...any) error {
+	return &DeniedError{Tool: call.Tool, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Error describes the refusal.
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("permission denied: %s: %s", e.Tool, e.Reason)
+}
+
+// Is reports whether target is ErrDenied.
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrDenied
+}
+
+// All returns a policy that allows a call only if every policy does. A
+// denial from any policy wins over a dry run. Nil policies are skipped.
+func All(policies ...Policy) Policy {
+	return all(policies)
+}
+
+type all []Policy
+
+// Check applies each policy in order.
+func (a all) Check(ctx context.Context, call Call) error {
+	var dryRun error
+	for _, p := range a {
+		if p == nil {
+			continue
+		}
+		err := p.Check(ctx, call)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrDryRun):
+			dryRun = err
+		default:
+			return err
+		}
+	}
+	return dryRun
+}
+
+// AllowTools returns a policy that denies every tool not listed.
+func AllowTools(names ...string) Policy {
+	return Func(func(ctx context.Context, call Call) error {
+		if !slices.Contains(names, call.Tool) {
+			return Deny(call, "tool is not allowed")
+		}
+		return nil
+	})
+}
+
+// DenyTools returns a policy that denies the listed tools.
+func DenyTools(names ...string) Policy {
+	return Func(func(ctx context.Context, call Call) error {
+		if slices.Contains(names, call.Tool) {
+			return Deny(call, "tool is not allowed")
+		}
+		return nil
+	})
+}
+
+// DryRun returns a policy that records calls to the listed tools, or to
+// every tool if none are listed, without executing them.
+func DryRun(tools ...string) Policy {
+	return Func(func(ctx context.Context, call Call) error {
+		if len(tools) == 0 || slices.Contains(tools, call.Tool) {
+			return ErrDryRun
+		}
+		return nil
+	})
+}
+
+// PathsWithin denies calls whose path arguments point outside Dir.
+// Relative paths are resolved against the current directory, as the
+// standard wetwire file handlers do.
+type PathsWithin struct {
+	// Dir is the directory calls may touch (required)
+	Dir string
+
+	// Tools are the tools to check (default: every tool)
+	Tools []string
+
+	// Args are the arguments holding paths (default: "path" and "output")
+	Args []string
+}
+
+// Check denies the call if any path argument leaves Dir.
+func (p PathsWithin) Check(ctx context.Context, call Call) error {
+	if len(p.Tools) > 0 && !slices.Contains(p.Tools, call.Tool) {
+		return nil
+	}
+
+	dir, err := filepath.Abs(p.Dir)
+	if err != nil {
+		return Deny(call, "cannot resolve %s: %v", p.Dir, err)
+	}
+
+	args := p.Args
+	if len(args) == 0 {
+		args = []string{"path", "output"}
+	}
+	for _, arg := range args {
+		path, ok := call.Args[arg].(string)
+		if !ok || path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return Deny(call, "cannot resolve %s: %v", path, err)
+		}
+		if rel, err := filepath.Rel(dir, abs); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return Deny(call, "%s is outside %s", path, p.Dir)
+		}
+	}
+	return nil
+}
+
+// NoExistingProject denies project initialization in a directory that
+// already holds a project, so an agent cannot overwrite existing work.
+type NoExistingProject struct {
+	// Tools are the initialization tools (default: "wetwire_init")
+	Tools []string
+
+	// Marker is the file that marks a project (default: "go.mod")
+	Marker string
+}
+
+// Check denies the call if its target directory, or the named project
+// directory inside it, contains Marker.
+func (p NoExistingProject) Check(ctx context.Context, call Call) error {
+	tools := p.Tools
+	if len(tools) == 0 {
+		tools = []string{"wetwire_init"}
+	}
+	if !slices.Contains(tools, call.Tool) {
+		return nil
+	}
+
+	marker := p.Marker
+	if marker == "" {
+		marker = "go.mod"
+	}
+
+	dir, _ := call.Args["path"].(string)
+	if dir == "" {
+		dir = "."
+	}
+	candidates := []string{dir}
+	if name, _ := call.Args["name"].(string); name != "" {
+		candidates = append(candidates, filepath.Join(dir, name))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(filepath.Join(candidate, marker)); err == nil {
+			return Deny(call, "%s already contains a project", candidate)
+		}
+	}
+	return nil
+}
+
+// Approver asks a human whether a tool call may run.
+type Approver interface {
+	// Approve returns true if the call may run.
+	Approve(ctx context.Context, call Call) (bool, error)
+}
+
+// ApproverFunc adapts a function to an Approver.
+type ApproverFunc func(ctx context.Context, call Call) (bool, error)
+
+// Approve calls f.
+func (f ApproverFunc) Approve(ctx context.Context, call Call) (bool, error) {
+	return f(ctx, call)
+}
+
+// Approval denies calls that its Approver rejects. An Approval asks about
+// one call at a time, even when the agent runs tool calls in parallel, since
+// a human answers one at a time. Agents that ask the same human should share
+// one *Approval; separate Approvals do not wait for each other.
+type Approval struct {
+	// Approver decides each call (required)
+	Approver Approver
+
+	// Tools are the tools that need approval (default: every tool)
+	Tools []string
+
+	mu sync.Mutex
+}
+
+// Check asks the Approver about the call.
+func (p *Approval) Check(ctx context.Context, call Call) error {
+	if len(p.Tools) > 0 && !slices.Contains(p.Tools, call.Tool) {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	approved, err := p.Approver.Approve(ctx, call)
+	if err != nil {
+		return Deny(call, "approval failed: %v", err)
+	}
+	if !approved {
+		return Deny(call, "rejected by the user")
+	}
+	return nil
+}
+
+// Responder answers a question in plain text. The agents.Developer
+// interface satisfies it.
+type Responder interface {
+	Respond(ctx context.Context, message string) (string, error)
+}
+
+// Ask returns an Approver that asks a Responder, such as the agent's
+// Developer, a yes/no question about each call.
+func Ask(r Responder) Approver {
+	return ApproverFunc(func(ctx context.Context, call Call) (bool, error) {
+		args, err := json.Marshal(call.Args)
+		if err != nil {
+			return false, err
+		}
+		answer, err := r.Respond(ctx, fmt.Sprintf("Allow the agent to call %s with %s? Answer yes or no.", call.Tool, args))
+		if err != nil {
+			return false, err
+		}
+		return isYes(answer), nil
+	})
+}
+
+// isYes reports whether an answer starts with yes.
+func isYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || strings.HasPrefix(answer, "yes") || strings.HasPrefix(answer, "y ") || strings.HasPrefix(answer, "y,")
+}
@@ -0,0 +1,166 @@
+package permissions
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func call(tool string, args map[string]any) Call {
+	return Call{Tool: tool, Args: args}
+}
+
+func TestToolLists(t *testing.T) {
+	ctx := context.Background()
+
+	allow := AllowTools("wetwire_read", "wetwire_lint")
+	assert.NoError(t, allow.Check(ctx, call("wetwire_read", nil)))
+	assert.ErrorIs(t, allow.Check(ctx, call("wetwire_write", nil)), ErrDenied)
+
+	deny := DenyTools("wetwire_import")
+	assert.NoError(t, deny.Check(ctx, call("wetwire_read", nil)))
+
+	err := deny.Check(ctx, call("wetwire_import", nil))
+	var denied *DeniedError
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, "wetwire_import", denied.Tool)
+	assert.EqualError(t, err, "permission denied: wetwire_import: tool is not allowed")
+}
+
+func TestPathsWithin(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	policy := PathsWithin{Dir: dir}
+	assert.NoError(t, policy.Check(ctx, call("wetwire_write", map[string]any{"path": filepath.Join(dir, "main.go")})))
+	assert.NoError(t, policy.Check(ctx, call("wetwire_lint", map[string]any{"package": "./..."})))
+	assert.ErrorIs(t, policy.Check(ctx, call("wetwire_write", map[string]any{"path": "/etc/passwd"})), ErrDenied)
+	assert.ErrorIs(t, policy.Check(ctx, call("wetwire_write", map[string]any{"path": filepath.Join(dir, "..", "escape.go")})), ErrDenied)
+	assert.ErrorIs(t, policy.Check(ctx, call("wetwire_build", map[string]any{"output": filepath.Join(dir+"-sibling", "out")})), ErrDenied)
+
+	// Only the listed tools are checked
+	reads := PathsWithin{Dir: dir, Tools: []string{"wetwire_write"}}
+	assert.NoError(t, reads.Check(ctx, call("wetwire_read", map[string]any{"path": "/etc/passwd"})))
+}
+
+func TestNoExistingProject(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "existing"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "existing", "go.mod"), []byte("module existing\n"), 0644))
+
+	policy := NoExistingProject{}
+	assert.NoError(t, policy.Check(ctx, call("wetwire_init", map[string]any{"name": "fresh", "path": dir})))
+	assert.ErrorIs(t, policy.Check(ctx, call("wetwire_init", map[string]any{"name": "existing", "path": dir})), ErrDenied)
+	assert.ErrorIs(t, policy.Check(ctx, call("wetwire_init", map[string]any{"path": filepath.Join(dir, "existing")})), ErrDenied)
+	assert.NoError(t, policy.Check(ctx, call("wetwire_write", map[string]any{"path": filepath.Join(dir, "existing")})))
+}
+
+func TestAll(t *testing.T) {
+	ctx := context.Background()
+
+	policy := All(DryRun("wetwire_write"), nil, DenyTools("wetwire_import"))
+	assert.NoError(t, policy.Check(ctx, call("wetwire_read", nil)))
+	assert.ErrorIs(t, policy.Check(ctx, call("wetwire_write", nil)), ErrDryRun)
+	assert.ErrorIs(t, policy.Check(ctx, call("wetwire_import", nil)), ErrDenied)
+
+	// A denial wins over a dry run
+	policy = All(DryRun(), DenyTools("wetwire_import"))
+	assert.ErrorIs(t, policy.Check(ctx, call("wetwire_import", nil)), ErrDenied)
+	assert.ErrorIs(t, policy.Check(ctx, call("wetwire_read", nil)), ErrDryRun)
+}
+
+// responder answers every question with a fixed reply.
+type responder struct {
+	reply    string
+	err      error
+	question string
+}
+
+func (r *responder) Respond(ctx context.Context, message string) (string, error) {
+	r.question = message
+	return r.reply, r.err
+}
+
+func TestApprovalAsk(t *testing.T) {
+	ctx := context.Background()
+	write := call("wetwire_write", map[string]any{"path": "main.go"})
+
+	tests := []struct {
+		reply   string
+		err     error
+		allowed bool
+	}{
+		{reply: "yes", allowed: true},
+		{reply: " Y ", allowed: true},
+		{reply: "Yes, go ahead", allowed: true},
+		{reply: "no"},
+		{reply: "you should not"},
+		{reply: "", err: errors.New("stdin closed")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			r := &responder{reply: tt.reply, err: tt.err}
+			err := (&Approval{Approver: Ask(r)}).Check(ctx, write)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrDenied)
+			}
+			assert.Contains(t, r.question, `wetwire_write with {"path":"main.go"}`)
+		})
+	}
+}
+
+func TestApprovalTools(t *testing.T) {
+	ctx := context.Background()
+	asked := 0
+	policy := &Approval{
+		Approver: ApproverFunc(func(ctx context.Context, call Call) (bool, error) {
+			asked++
+			return false, nil
+		}),
+		Tools: []string{"wetwire_write"},
+	}
+
+	assert.NoError(t, policy.Check(ctx, call("wetwire_read", nil)))
+	err := policy.Check(ctx, call("wetwire_write", nil))
+	assert.True(t, strings.HasSuffix(err.Error(), "rejected by the user"))
+	assert.Equal(t, 1, asked)
+}
+
+func TestApprovalsAreIndependent(t *testing.T) {
+	ctx := context.Background()
+	started, answered := make(chan struct{}), make(chan struct{})
+
+	// The first approval waits for the second, which would deadlock if
+	// approvals shared a lock
+	waiting := &Approval{Approver: ApproverFunc(func(ctx context.Context, call Call) (bool, error) {
+		close(started)
+		select {
+		case <-answered:
+			return true, nil
+		case <-time.After(5 * time.Second):
+			return false, errors.New("timed out waiting for the other approval")
+		}
+	})}
+	other := &Approval{Approver: ApproverFunc(func(ctx context.Context, call Call) (bool, error) {
+		close(answered)
+		return true, nil
+	})}
+
+	done := make(chan error, 1)
+	go func() { done <- waiting.Check(ctx, call("wetwire_write", nil)) }()
+	<-started
+
+	require.NoError(t, other.Check(ctx, call("wetwire_write", nil)))
+	assert.NoError(t, <-done)
+}
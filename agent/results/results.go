@@ -4,6 +4,7 @@
 // - Session metadata (persona, scenario, timestamp)
 // - Complete conversation log
 // - Lint cycles and fixes
+// - Token usage and cost
 // - Final score breakdown
 // - Improvement suggestions
 package results
@@ -15,8 +16,10 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/lex00/wetwire-core-go/agent/scoring"
+	"github.com/lex00/wetwire-core-go/providers"
 )
 
 // Message represents a single message in the conversation.
@@ -29,9 +32,12 @@ type Message struct {
 
 // ToolCall represents a tool invocation by the agent.
 type ToolCall struct {
-	Name   string `json:"name"`
-	Input  string `json:"input"`
-	Output string `json:"output"`
+	Name     string        `json:"name"`
+	Input    string        `json:"input"`
+	Output   string        `json:"output"`
+	IsError  bool          `json:"is_error,omitempty"`
+	DryRun   bool          `json:"dry_run,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
 }
 
 // LintCycle represents one cycle of lint/fix.
@@ -43,12 +49,57 @@ type LintCycle struct {
 	Passed     bool     `json:"passed"`
 }
 
+// VerificationRound records one check of the agent's work after it
+// claimed completion. A failed round is followed by a repair round.
+type VerificationRound struct {
+	Round     int       `json:"round"`
+	Passed    bool      `json:"passed"`
+	Tool      string    `json:"tool,omitempty"` // the tool that failed
+	Errors    []string  `json:"errors,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // Question represents a clarifying question from the Runner.
 type Question struct {
 	Question string `json:"question"`
 	Answer   string `json:"answer"`
 }
 
+// Thinking represents a reasoning block produced by the model.
+type Thinking struct {
+	Content   string    `json:"content,omitempty"`
+	Redacted  bool      `json:"redacted,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Compaction records a reduction of the conversation history to fit the
+// context window.
+type Compaction struct {
+	Policy         string    `json:"policy"`
+	TokensBefore   int       `json:"tokens_before"`
+	TokensAfter    int       `json:"tokens_after"`
+	MessagesBefore int       `json:"messages_before"`
+	MessagesAfter  int       `json:"messages_after"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// BudgetExceeded records the budget limit that stopped a run.
+type BudgetExceeded struct {
+	Limit     string    `json:"limit"`
+	Tool      string    `json:"tool,omitempty"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// TurnUsage records the tokens and cost of a single model turn.
+type TurnUsage struct {
+	Turn     int             `json:"turn"`
+	Provider string          `json:"provider,omitempty"`
+	Model    string          `json:"model"`
+	Usage    providers.Usage `json:"usage"`
+	CostUSD  float64         `json:"cost_usd"`
+}
+
 // Session contains all data for a single agent session.
 type Session struct {
 	// Metadata
@@ -68,6 +119,27 @@ type Session struct {
 	// Lint cycles
 	LintCycles []LintCycle `json:"lint_cycles"`
 
+	// Verification rounds after the agent claimed completion, in order
+	Verifications []VerificationRound `json:"verifications,omitempty"`
+
+	// Model reasoning, in order
+	Thinking []Thinking `json:"thinking,omitempty"`
+
+	// RedactThinking records that thinking occurred without storing its content
+	RedactThinking bool `json:"-"`
+
+	// Token usage per model turn
+	Usage []TurnUsage `json:"usage,omitempty"`
+
+	// History compactions, in order
+	Compactions []Compaction `json:"compactions,omitempty"`
+
+	// Budget limit that stopped the run, if any
+	BudgetExceeded *BudgetExceeded `json:"budget_exceeded,omitempty"`
+
+	// Prices used to cost usage (defaults to providers.DefaultPrices)
+	Prices providers.PriceTable `json:"-"`
+
 	// Output
 	GeneratedFiles []string `json:"generated_files"`
 	TemplateJSON   string   `json:"template_json,omitempty"`
@@ -101,6 +173,17 @@ func (s *Session) AddMessage(role, content string) {
 	})
 }
 
+// AddMessageWithToolCalls adds a message and the tool calls it made to the
+// conversation log.
+func (s *Session) AddMessageWithToolCalls(role, content string, toolCalls []ToolCall) {
+	s.Messages = append(s.Messages, Message{
+		Role:      role,
+		Content:   content,
+		Timestamp: time.Now(),
+		ToolCalls: toolCalls,
+	})
+}
+
 // AddQuestion adds a clarifying question and answer.
 func (s *Session) AddQuestion(question, answer string) {
 	s.Questions = append(s.Questions, Question{
@@ -120,6 +203,127 @@ func (s *Session) AddLintCycle(issues []string, fixed int, passed bool) {
 	})
 }
 
+// AddVerification records a verification round. Tool and errors describe
+// the failing check and are empty if the round passed.
+func (s *Session) AddVerification(tool string, errors []string, passed bool) {
+	s.Verifications = append(s.Verifications, VerificationRound{
+		Round:     len(s.Verifications) + 1,
+		Passed:    passed,
+		Tool:      tool,
+		Errors:    errors,
+		Timestamp: time.Now(),
+	})
+}
+
+// VerifiedOnFirstTry reports whether the first verification round passed.
+func (s *Session) VerifiedOnFirstTry() bool {
+	return len(s.Verifications) > 0 && s.Verifications[0].Passed
+}
+
+// AddThinking records the thinking and redacted_thinking blocks of a response.
+// Other blocks are ignored.
+func (s *Session) AddThinking(blocks []providers.ContentBlock) {
+	for _, block := range blocks {
+		if !block.IsThinking() {
+			continue
+		}
+
+		entry := Thinking{Timestamp: time.Now()}
+		if block.Type == "redacted_thinking" || s.RedactThinking {
+			entry.Redacted = true
+		} else {
+			entry.Content = block.Thinking
+		}
+		s.Thinking = append(s.Thinking, entry)
+	}
+}
+
+// AddUsage records the usage of a model turn and prices it.
+// Provider names the backend that served the turn.
+func (s *Session) AddUsage(provider, model string, usage providers.Usage) {
+	prices := s.Prices
+	if prices == nil {
+		prices = providers.DefaultPrices
+	}
+
+	s.Usage = append(s.Usage, TurnUsage{
+		Turn:     len(s.Usage) + 1,
+		Provider: provider,
+		Model:    model,
+		Usage:    usage,
+		CostUSD:  prices.Cost(model, usage),
+	})
+}
+
+// AddCompaction records a history compaction with estimated token counts.
+func (s *Session) AddCompaction(policy string, tokensBefore, tokensAfter, messagesBefore, messagesAfter int) {
+	s.Compactions = append(s.Compactions, Compaction{
+		Policy:         policy,
+		TokensBefore:   tokensBefore,
+		TokensAfter:    tokensAfter,
+		MessagesBefore: messagesBefore,
+		MessagesAfter:  messagesAfter,
+		Timestamp:      time.Now(),
+	})
+}
+
+// SetBudgetExceeded records that the run stopped because a budget limit
+// was reached.
+func (s *Session) SetBudgetExceeded(limit, tool, message string) {
+	s.BudgetExceeded = &BudgetExceeded{
+		Limit:     limit,
+		Tool:      tool,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+}
+
+// TotalUsage returns the usage summed over all turns.
+func (s *Session) TotalUsage() providers.Usage {
+	var total providers.Usage
+	for _, u := range s.Usage {
+		total = total.Add(u.Usage)
+	}
+	return total
+}
+
+// TotalCost returns the cost in USD summed over all turns.
+func (s *Session) TotalCost() float64 {
+	var total float64
+	for _, u := range s.Usage {
+		total += u.CostUSD
+	}
+	return total
+}
+
+// Merge appends the conversation and records of another session, such as
+// one of several agents working on the same task. Lint cycles,
+// verification rounds and usage turns are renumbered to follow this
+// session's own. The other session's metadata and score are ignored.
+func (s *Session) Merge(other *Session) {
+	s.Messages = append(s.Messages, other.Messages...)
+	s.Questions = append(s.Questions, other.Questions...)
+	for _, c := range other.LintCycles {
+		c.Cycle = len(s.LintCycles) + 1
+		s.LintCycles = append(s.LintCycles, c)
+	}
+	for _, v := range other.Verifications {
+		v.Round = len(s.Verifications) + 1
+		s.Verifications = append(s.Verifications, v)
+	}
+	s.Thinking = append(s.Thinking, other.Thinking...)
+	for _, u := range other.Usage {
+		u.Turn = len(s.Usage) + 1
+		s.Usage = append(s.Usage, u)
+	}
+	s.Compactions = append(s.Compactions, other.Compactions...)
+	if other.BudgetExceeded != nil {
+		s.BudgetExceeded = other.BudgetExceeded
+	}
+	s.GeneratedFiles = append(s.GeneratedFiles, other.GeneratedFiles...)
+	s.Suggestions = append(s.Suggestions, other.Suggestions...)
+}
+
 // Complete marks the session as complete and calculates the final score.
 func (s *Session) Complete() {
 	s.EndTime = time.Now()
@@ -249,6 +453,55 @@ func (w *Writer) formatMarkdown(s *Session) string {
 		}
 	}
 
+	// Verification
+	if len(s.Verifications) > 0 {
+		b.WriteString("## Verification\n\n")
+		for _, v := range s.Verifications {
+			if v.Passed {
+				b.WriteString(fmt.Sprintf("### Round %d (Passed)\n\n", v.Round))
+				continue
+			}
+			b.WriteString(fmt.Sprintf("### Round %d (Failed: %s)\n\n", v.Round, v.Tool))
+			for _, e := range v.Errors {
+				b.WriteString(fmt.Sprintf("- %s\n", e))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	// Token usage
+	if len(s.Usage) > 0 {
+		total := s.TotalUsage()
+		b.WriteString("## Usage\n\n")
+		b.WriteString(fmt.Sprintf("**Total:** %d tokens, $%.4f\n\n", total.TotalTokens(), s.TotalCost()))
+		b.WriteString("| Turn | Provider | Model | Input | Output | Cache Write | Cache Read | Cost |\n")
+		b.WriteString("|------|----------|-------|-------|--------|-------------|------------|------|\n")
+		for _, u := range s.Usage {
+			b.WriteString(fmt.Sprintf("| %d | %s | %s | %d | %d | %d | %d | $%.4f |\n",
+				u.Turn, u.Provider, u.Model, u.Usage.InputTokens, u.Usage.OutputTokens,
+				u.Usage.CacheCreationInputTokens, u.Usage.CacheReadInputTokens, u.CostUSD))
+		}
+		b.WriteString("\n")
+	}
+
+	// History compactions
+	if len(s.Compactions) > 0 {
+		b.WriteString("## Context Compaction\n\n")
+		b.WriteString("| Time | Policy | Tokens | Messages |\n")
+		b.WriteString("|------|--------|--------|----------|\n")
+		for _, c := range s.Compactions {
+			b.WriteString(fmt.Sprintf("| %s | %s | %d → %d | %d → %d |\n",
+				c.Timestamp.Format("15:04:05"), c.Policy, c.TokensBefore, c.TokensAfter, c.MessagesBefore, c.MessagesAfter))
+		}
+		b.WriteString("\n")
+	}
+
+	// Budget
+	if s.BudgetExceeded != nil {
+		b.WriteString("## Budget Exceeded\n\n")
+		b.WriteString(fmt.Sprintf("Run stopped at %s: %s\n\n", s.BudgetExceeded.Timestamp.Format("15:04:05"), s.BudgetExceeded.Message))
+	}
+
 	// Generated files
 	if len(s.GeneratedFiles) > 0 {
 		b.WriteString("## Generated Files\n\n")
@@ -267,12 +520,26 @@ func (w *Writer) formatMarkdown(s *Session) string {
 		if len(msg.ToolCalls) > 0 {
 			b.WriteString("**Tool Calls:**\n\n")
 			for _, tc := range msg.ToolCalls {
-				b.WriteString(fmt.Sprintf("- `%s`\n", tc.Name))
+				b.WriteString(formatToolCall(tc))
 			}
 			b.WriteString("\n")
 		}
 	}
 
+	// Thinking
+	if len(s.Thinking) > 0 {
+		b.WriteString("## Thinking\n\n")
+		for _, th := range s.Thinking {
+			b.WriteString(fmt.Sprintf("### %s\n\n", th.Timestamp.Format("15:04:05")))
+			if th.Redacted {
+				b.WriteString("_(redacted)_\n\n")
+			} else {
+				b.WriteString(th.Content)
+				b.WriteString("\n\n")
+			}
+		}
+	}
+
 	// Suggestions
 	if len(s.Suggestions) > 0 {
 		b.WriteString("## Improvement Suggestions\n\n")
@@ -285,6 +552,49 @@ func (w *Writer) formatMarkdown(s *Session) string {
 	return b.String()
 }
 
+// maxToolCallText is the length of tool inputs and outputs shown in RESULTS.md.
+const maxToolCallText = 200
+
+// formatToolCall renders a tool call as a list item with its input and the
+// first line of its output.
+func formatToolCall(tc ToolCall) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("- `%s`", tc.Name))
+	if tc.IsError {
+		b.WriteString(" (error)")
+	}
+	if tc.DryRun {
+		b.WriteString(" (dry run)")
+	}
+	if tc.Duration > 0 {
+		b.WriteString(fmt.Sprintf(" in %s", tc.Duration.Round(time.Millisecond)))
+	}
+	b.WriteString("\n")
+	if input := summarize(tc.Input); input != "" && input != "{}" {
+		b.WriteString(fmt.Sprintf("  - Input: `%s`\n", input))
+	}
+	if output := summarize(tc.Output); output != "" {
+		b.WriteString(fmt.Sprintf("  - Output: %s\n", output))
+	}
+	return b.String()
+}
+
+// summarize returns the first line of s, shortened for display.
+func summarize(s string) string {
+	line, _, more := strings.Cut(strings.TrimSpace(s), "\n")
+	if len(line) > maxToolCallText {
+		n := maxToolCallText
+		for n > 0 && !utf8.RuneStart(line[n]) {
+			n--
+		}
+		return line[:n] + "..."
+	}
+	if more {
+		return line + " ..."
+	}
+	return line
+}
+
 // generateID creates a unique session ID.
 func generateID() string {
 	return fmt.Sprintf("session_%s", time.Now().Format("20060102_150405"))
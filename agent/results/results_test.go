@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/lex00/wetwire-core-go/agent/scoring"
+	"github.com/lex00/wetwire-core-go/providers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -69,6 +72,136 @@ func TestSession_AddLintCycle(t *testing.T) {
 	assert.True(t, session.LintCycles[1].Passed)
 }
 
+func TestSession_AddUsage(t *testing.T) {
+	session := NewSession("beginner", "s3_bucket")
+	session.Prices = providers.PriceTable{
+		"test-model": {Input: 1, Output: 10},
+	}
+
+	session.AddUsage("anthropic", "test-model", providers.Usage{InputTokens: 1_000_000, OutputTokens: 100_000})
+	session.AddUsage("anthropic", "test-model", providers.Usage{InputTokens: 500_000, CacheReadInputTokens: 200})
+	session.AddUsage("anthropic", "cli-model", providers.Usage{OutputTokens: 10, CostUSD: 0.25})
+
+	require.Len(t, session.Usage, 3)
+	assert.Equal(t, 1, session.Usage[0].Turn)
+	assert.Equal(t, 3, session.Usage[2].Turn)
+	assert.Equal(t, "anthropic", session.Usage[0].Provider)
+	assert.InDelta(t, 2.0, session.Usage[0].CostUSD, 1e-9)
+	assert.InDelta(t, 0.5, session.Usage[1].CostUSD, 1e-9)
+	assert.InDelta(t, 0.25, session.Usage[2].CostUSD, 1e-9)
+
+	total := session.TotalUsage()
+	assert.Equal(t, 1_500_000, total.InputTokens)
+	assert.Equal(t, 100_010, total.OutputTokens)
+	assert.Equal(t, 200, total.CacheReadInputTokens)
+	assert.InDelta(t, 2.75, session.TotalCost(), 1e-9)
+}
+
+func TestSession_AddUsageDefaultPrices(t *testing.T) {
+	session := NewSession("beginner", "s3_bucket")
+	session.AddUsage("anthropic", "claude-sonnet-4-20250514", providers.Usage{InputTokens: 1_000_000})
+
+	assert.InDelta(t, 3.0, session.TotalCost(), 1e-9)
+}
+
+func TestSession_AddThinking(t *testing.T) {
+	session := NewSession("beginner", "s3_bucket")
+
+	session.AddThinking([]providers.ContentBlock{
+		{Type: "thinking", Thinking: "Use versioning", Signature: "sig"},
+		{Type: "text", Text: "ignored"},
+		{Type: "redacted_thinking", Data: "encrypted"},
+	})
+
+	require.Len(t, session.Thinking, 2)
+	assert.Equal(t, "Use versioning", session.Thinking[0].Content)
+	assert.False(t, session.Thinking[0].Redacted)
+	assert.Empty(t, session.Thinking[1].Content)
+	assert.True(t, session.Thinking[1].Redacted)
+
+	redacted := NewSession("beginner", "s3_bucket")
+	redacted.RedactThinking = true
+	redacted.AddThinking([]providers.ContentBlock{{Type: "thinking", Thinking: "secret"}})
+
+	require.Len(t, redacted.Thinking, 1)
+	assert.Empty(t, redacted.Thinking[0].Content)
+	assert.True(t, redacted.Thinking[0].Redacted)
+}
+
+func TestSession_AddCompaction(t *testing.T) {
+	session := NewSession("beginner", "s3_bucket")
+
+	session.AddCompaction("truncate_tool_results", 120000, 60000, 21, 21)
+
+	require.Len(t, session.Compactions, 1)
+	c := session.Compactions[0]
+	assert.Equal(t, "truncate_tool_results", c.Policy)
+	assert.Equal(t, 120000, c.TokensBefore)
+	assert.Equal(t, 60000, c.TokensAfter)
+	assert.Equal(t, 21, c.MessagesBefore)
+	assert.Equal(t, 21, c.MessagesAfter)
+	assert.False(t, c.Timestamp.IsZero())
+}
+
+func TestSession_SetBudgetExceeded(t *testing.T) {
+	session := NewSession("beginner", "s3_bucket")
+	assert.Nil(t, session.BudgetExceeded)
+
+	session.SetBudgetExceeded("tool_calls", "run_lint", "budget exceeded: run_lint called more than 5 times")
+
+	require.NotNil(t, session.BudgetExceeded)
+	assert.Equal(t, "tool_calls", session.BudgetExceeded.Limit)
+	assert.Equal(t, "run_lint", session.BudgetExceeded.Tool)
+	assert.False(t, session.BudgetExceeded.Timestamp.IsZero())
+}
+
+func TestSession_AddVerification(t *testing.T) {
+	session := NewSession("beginner", "s3_bucket")
+	assert.False(t, session.VerifiedOnFirstTry())
+
+	session.AddVerification("wetwire_build", []string{"main.go:3: undefined: Bucket"}, false)
+	session.AddVerification("", nil, true)
+
+	require.Len(t, session.Verifications, 2)
+	assert.Equal(t, 1, session.Verifications[0].Round)
+	assert.Equal(t, "wetwire_build", session.Verifications[0].Tool)
+	assert.True(t, session.Verifications[1].Passed)
+	assert.False(t, session.VerifiedOnFirstTry())
+
+	md := NewWriter("").formatMarkdown(session)
+	assert.Contains(t, md, "### Round 1 (Failed: wetwire_build)\n\n- main.go:3: undefined: Bucket")
+	assert.Contains(t, md, "### Round 2 (Passed)")
+}
+
+func TestSession_Merge(t *testing.T) {
+	session := NewSession("beginner", "aws_gitlab")
+	session.AddMessage("runner", "planned")
+	session.AddUsage("fake", "claude-sonnet-4", providers.Usage{InputTokens: 10})
+	session.AddLintCycle([]string{"unused import"}, 0, false)
+
+	worker := NewSession("beginner", "aws_gitlab")
+	worker.AddMessage("runner", "built the bucket")
+	worker.AddQuestion("Which region?", "us-east-1")
+	worker.AddLintCycle(nil, 1, true)
+	worker.AddVerification("", nil, true)
+	worker.AddUsage("fake", "claude-sonnet-4", providers.Usage{InputTokens: 20})
+	worker.GeneratedFiles = []string{"infra/bucket.go"}
+
+	session.Merge(worker)
+
+	require.Len(t, session.Messages, 2)
+	assert.Equal(t, "built the bucket", session.Messages[1].Content)
+	assert.Len(t, session.Questions, 1)
+	require.Len(t, session.LintCycles, 2)
+	assert.Equal(t, 2, session.LintCycles[1].Cycle)
+	require.Len(t, session.Verifications, 1)
+	assert.Equal(t, 1, session.Verifications[0].Round)
+	require.Len(t, session.Usage, 2)
+	assert.Equal(t, 2, session.Usage[1].Turn)
+	assert.Equal(t, 30, session.TotalUsage().InputTokens)
+	assert.Equal(t, []string{"infra/bucket.go"}, session.GeneratedFiles)
+}
+
 func TestSession_Complete(t *testing.T) {
 	session := NewSession("test", "test")
 
@@ -178,11 +311,20 @@ func TestWriter_FormatMarkdown(t *testing.T) {
 	session.InitialPrompt = "Lambda with API Gateway"
 	session.AddMessage("developer", "Lambda with API Gateway")
 	session.AddMessage("runner", "I'll create the Lambda function")
+	session.AddMessageWithToolCalls("runner", "Checking the code", []ToolCall{
+		{Name: "write_file", Input: `{"path":"compute.go"}`, Output: "wrote compute.go", Duration: 12 * time.Millisecond},
+		{Name: "run_lint", Input: "{}", Output: "compute.go:3: missing tag\ncompute.go:9: unused", IsError: true},
+		{Name: "wetwire_init", Input: "{}", Output: "Dry run: wetwire_init was not executed.", DryRun: true},
+	})
 	session.AddQuestion("Runtime?", "Python 3.12")
 	session.AddLintCycle([]string{"error1"}, 1, false)
 	session.AddLintCycle([]string{}, 0, true)
 	session.GeneratedFiles = []string{"compute.go", "api.go"}
 	session.Suggestions = []string{"Add better error handling"}
+	session.AddUsage("anthropic", "claude-sonnet-4-20250514", providers.Usage{InputTokens: 1200, OutputTokens: 300})
+	session.AddThinking([]providers.ContentBlock{{Type: "thinking", Thinking: "Lambda needs an IAM role"}})
+	session.AddCompaction("drop_old_turns", 150000, 40000, 31, 9)
+	session.SetBudgetExceeded("turns", "", "budget exceeded: 50 turns used of 50")
 	session.Complete()
 
 	score := scoring.NewScore("expert", "lambda_api")
@@ -219,6 +361,23 @@ func TestWriter_FormatMarkdown(t *testing.T) {
 	assert.Contains(t, md, "Cycle 2")
 	assert.Contains(t, md, "Passed")
 
+	// Check usage
+	assert.Contains(t, md, "## Usage")
+	assert.Contains(t, md, "**Total:** 1500 tokens")
+	assert.Contains(t, md, "| 1 | anthropic | claude-sonnet-4-20250514 | 1200 | 300 |")
+
+	// Check compactions
+	assert.Contains(t, md, "## Context Compaction")
+	assert.Contains(t, md, "| drop_old_turns | 150000 → 40000 | 31 → 9 |")
+
+	// Check budget
+	assert.Contains(t, md, "## Budget Exceeded")
+	assert.Contains(t, md, "budget exceeded: 50 turns used of 50")
+
+	// Check thinking
+	assert.Contains(t, md, "## Thinking")
+	assert.Contains(t, md, "Lambda needs an IAM role")
+
 	// Check generated files
 	assert.Contains(t, md, "## Generated Files")
 	assert.Contains(t, md, "compute.go")
@@ -226,6 +385,10 @@ func TestWriter_FormatMarkdown(t *testing.T) {
 
 	// Check conversation log
 	assert.Contains(t, md, "## Conversation Log")
+	assert.Contains(t, md, "- `write_file` in 12ms\n  - Input: `{\"path\":\"compute.go\"}`\n  - Output: wrote compute.go")
+	assert.Contains(t, md, "- `run_lint` (error)\n  - Output: compute.go:3: missing tag")
+	assert.NotContains(t, md, "unused")
+	assert.Contains(t, md, "- `wetwire_init` (dry run)\n")
 
 	// Check suggestions
 	assert.Contains(t, md, "## Improvement Suggestions")
@@ -265,6 +428,15 @@ func TestMessage_ToolCalls(t *testing.T) {
 	assert.Equal(t, "storage.go", call1["input"])
 }
 
+func TestSummarize(t *testing.T) {
+	assert.Equal(t, "first line ...", summarize("first line\nsecond line"))
+
+	long := strings.Repeat("a", maxToolCallText-1) + "é" + "tail"
+	got := summarize(long)
+	assert.True(t, utf8.ValidString(got), "summary split a rune: %q", got)
+	assert.Equal(t, strings.Repeat("a", maxToolCallText-1)+"...", got)
+}
+
 func TestGenerateID(t *testing.T) {
 	id1 := generateID()
 	time.Sleep(2 * time.Second) // Ensure different timestamp
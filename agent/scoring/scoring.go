@@ -59,7 +59,10 @@ type Score struct {
 	Persona       string
 	Scenario      string
 	LintCycles    int
+	Verifications int
 	QuestionCount int
+	TotalTokens   int
+	CostUSD       float64
 }
 
 // Total returns the sum of all dimension scores (0-12).
@@ -165,6 +168,23 @@ func ScoreOutputValidity(errors, warnings int) (Rating, string) {
 	return RatingPartial, fmt.Sprintf("%d warnings in output", warnings)
 }
 
+// ScoreVerification scores based on the verification rounds needed before
+// the agent's work built and validated. Passing on the first try is best.
+func ScoreVerification(rounds int, passed bool) (Rating, string) {
+	if !passed {
+		return RatingNone, fmt.Sprintf("Verification failed after %d rounds", rounds)
+	}
+
+	switch rounds {
+	case 0, 1:
+		return RatingExcellent, "Verified on the first try"
+	case 2:
+		return RatingGood, "Verified after 1 repair round"
+	default:
+		return RatingPartial, fmt.Sprintf("Verified after %d repair rounds", rounds-1)
+	}
+}
+
 // ScoreQuestionEfficiency scores based on number of clarifying questions.
 func ScoreQuestionEfficiency(questions int) (Rating, string) {
 	switch {
@@ -183,7 +203,11 @@ func ScoreQuestionEfficiency(questions int) (Rating, string) {
 func (s Score) String() string {
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("Score: %d/12 (%s)\n", s.Total(), s.Threshold()))
-	b.WriteString(fmt.Sprintf("Persona: %s, Scenario: %s\n\n", s.Persona, s.Scenario))
+	b.WriteString(fmt.Sprintf("Persona: %s, Scenario: %s\n", s.Persona, s.Scenario))
+	if s.TotalTokens > 0 {
+		b.WriteString(fmt.Sprintf("Usage: %d tokens ($%.4f)\n", s.TotalTokens, s.CostUSD))
+	}
+	b.WriteString("\n")
 
 	dims := []Dimension{
 		s.Completeness,
@@ -122,6 +122,25 @@ func TestScoreLintQuality(t *testing.T) {
 	}
 }
 
+func TestScoreVerification(t *testing.T) {
+	tests := []struct {
+		rounds int
+		passed bool
+		rating Rating
+	}{
+		{1, true, RatingExcellent},
+		{2, true, RatingGood},
+		{3, true, RatingPartial},
+		{5, true, RatingPartial},
+		{4, false, RatingNone},
+	}
+
+	for _, tt := range tests {
+		rating, _ := ScoreVerification(tt.rounds, tt.passed)
+		assert.Equal(t, tt.rating, rating, "rounds=%d, passed=%v", tt.rounds, tt.passed)
+	}
+}
+
 func TestScoreOutputValidity(t *testing.T) {
 	tests := []struct {
 		errors   int
@@ -178,3 +197,12 @@ func TestScore_String(t *testing.T) {
 	assert.Contains(t, str, "s3_bucket")
 	assert.Contains(t, str, "Completeness")
 }
+
+func TestScore_StringUsage(t *testing.T) {
+	s := NewScore("beginner", "s3_bucket")
+	assert.NotContains(t, s.String(), "Usage:")
+
+	s.TotalTokens = 1500
+	s.CostUSD = 0.0123
+	assert.Contains(t, s.String(), "Usage: 1500 tokens ($0.0123)")
+}
@@ -9,6 +9,7 @@
 //	--all      Run all personas
 //	--verbose  Show streaming output from Claude
 //	--record   Generate SVG recordings (requires termsvg)
+//	--resume   Continue interrupted personas from their checkpoints
 //
 // Examples:
 //
@@ -17,6 +18,7 @@
 //	go run ./cmd/run_scenario ./examples/aws_gitlab expert ./results
 //	go run ./cmd/run_scenario ./examples/aws_gitlab --all ./results
 //	go run ./cmd/run_scenario ./examples/aws_gitlab --all --record ./results
+//	go run ./cmd/run_scenario ./examples/aws_gitlab --all --resume ./results
 package main
 
 import (
@@ -40,6 +42,7 @@ func main() {
 	generateRecordings := false
 	verbose := false
 	validate := false
+	resume := false
 
 	args := os.Args[1:]
 	for i := 0; i < len(args); i++ {
@@ -52,6 +55,8 @@ func main() {
 			verbose = true
 		} else if arg == "--validate" {
 			validate = true
+		} else if arg == "--resume" {
+			resume = true
 		} else if arg == "--help" || arg == "-h" {
 			printUsage()
 			return
@@ -82,6 +87,7 @@ func main() {
 		GenerateRecordings: generateRecordings,
 		Verbose:            verbose,
 		Validate:           validate,
+		Resume:             resume,
 	}
 
 	if runAll {
@@ -116,6 +122,7 @@ func main() {
 		if r.Score != nil {
 			fmt.Printf("Score:    %d/12 (%s)\n", r.Score.Total(), r.Score.Threshold())
 		}
+		fmt.Printf("Usage:    %d tokens ($%.4f)\n", r.Usage.TotalTokens(), r.CostUSD)
 		if !r.Success {
 			os.Exit(1)
 		}
@@ -130,6 +137,7 @@ Flags:
   --verbose   Show streaming output from Claude (recommended)
   --record    Generate SVG recordings (requires termsvg)
   --validate  Run validation after generation
+  --resume    Continue interrupted personas from their checkpoints
   --help      Show this help
 
 Examples:
@@ -137,7 +145,8 @@ Examples:
   run_scenario ./examples/aws_gitlab beginner --verbose
   run_scenario ./examples/aws_gitlab expert ./results
   run_scenario ./examples/aws_gitlab --all --verbose ./results
-  run_scenario ./examples/aws_gitlab --all --validate ./results`)
+  run_scenario ./examples/aws_gitlab --all --validate ./results
+  run_scenario ./examples/aws_gitlab --all --resume ./results`)
 }
 
 func printSummary(results []runner.Result) {
@@ -147,7 +156,11 @@ func printSummary(results []runner.Result) {
 	fmt.Println()
 
 	successCount := 0
+	totalTokens := 0
+	totalCost := 0.0
 	for _, r := range results {
+		totalTokens += r.Usage.TotalTokens()
+		totalCost += r.CostUSD
 		status := "✗ FAILED"
 		if r.Success {
 			status = "✓ SUCCESS"
@@ -162,4 +175,5 @@ func printSummary(results []runner.Result) {
 
 	fmt.Println()
 	fmt.Printf("Results: %d/%d passed\n", successCount, len(results))
+	fmt.Printf("Usage:   %d tokens ($%.4f)\n", totalTokens, totalCost)
 }
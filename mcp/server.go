@@ -24,6 +24,31 @@ type Tool struct {
 	Description string
 	Handler     ToolHandler
 	InputSchema map[string]any // JSON Schema for input parameters
+
+	// Parallel marks calls as safe to run alongside other parallel calls.
+	// Agents run other tools exclusively.
+	Parallel bool
+
+	// ConflictKey names an input property, such as "path". Parallel calls
+	// with the same value for it run one after another.
+	ConflictKey string
+}
+
+// ToolOption sets optional tool metadata.
+type ToolOption func(*Tool)
+
+// Parallel marks a tool as safe to run alongside other parallel calls.
+var Parallel ToolOption = func(t *Tool) {
+	t.Parallel = true
+}
+
+// ConflictKey marks a tool as parallel and serializes calls that share a
+// value for the given input property, such as writes to the same path.
+func ConflictKey(property string) ToolOption {
+	return func(t *Tool) {
+		t.Parallel = true
+		t.ConflictKey = property
+	}
 }
 
 // Config configures the MCP server.
@@ -60,21 +85,25 @@ func NewServer(config Config) *Server {
 }
 
 // RegisterTool adds a tool that MCP clients can invoke.
-func (s *Server) RegisterTool(name, description string, handler ToolHandler) {
-	s.RegisterToolWithSchema(name, description, handler, nil)
+func (s *Server) RegisterTool(name, description string, handler ToolHandler, opts ...ToolOption) {
+	s.RegisterToolWithSchema(name, description, handler, nil, opts...)
 }
 
 // RegisterToolWithSchema adds a tool with a JSON Schema for input validation.
-func (s *Server) RegisterToolWithSchema(name, description string, handler ToolHandler, inputSchema map[string]any) {
+func (s *Server) RegisterToolWithSchema(name, description string, handler ToolHandler, inputSchema map[string]any, opts ...ToolOption) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.tools[name] = &Tool{
+	tool := &Tool{
 		Name:        name,
 		Description: description,
 		Handler:     handler,
 		InputSchema: inputSchema,
 	}
+	for _, opt := range opts {
+		opt(tool)
+	}
+	s.tools[name] = tool
 
 	s.debugf("Registered tool: %s", name)
 }
@@ -326,6 +355,8 @@ func (s *Server) GetTools() []ToolInfo {
 		info := ToolInfo{
 			Name:        tool.Name,
 			Description: tool.Description,
+			Parallel:    tool.Parallel,
+			ConflictKey: tool.ConflictKey,
 		}
 		if tool.InputSchema != nil {
 			info.InputSchema = tool.InputSchema
@@ -110,6 +110,38 @@ func TestHandleInitialize(t *testing.T) {
 	}
 }
 
+func TestRegisterToolOptions(t *testing.T) {
+	server := NewServer(Config{Name: "test"})
+
+	server.RegisterTool("read", "Read a file", nil, Parallel)
+	server.RegisterTool("write", "Write a file", nil, ConflictKey("path"))
+	server.RegisterTool("lint", "Run the linter", nil)
+
+	tools := make(map[string]ToolInfo)
+	for _, info := range server.GetTools() {
+		tools[info.Name] = info
+	}
+
+	if !tools["read"].Parallel || tools["read"].ConflictKey != "" {
+		t.Errorf("expected read to be parallel without a conflict key, got %+v", tools["read"])
+	}
+	if !tools["write"].Parallel || tools["write"].ConflictKey != "path" {
+		t.Errorf("expected write to be parallel with conflict key 'path', got %+v", tools["write"])
+	}
+	if tools["lint"].Parallel {
+		t.Error("expected lint to be exclusive")
+	}
+
+	// The hints are not sent to MCP clients
+	data, err := json.Marshal(tools["write"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "path") || strings.Contains(string(data), "arallel") {
+		t.Errorf("expected no execution hints in %s", data)
+	}
+}
+
 func TestHandleToolsList(t *testing.T) {
 	server := NewServer(Config{Name: "test"})
 
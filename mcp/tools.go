@@ -202,11 +202,15 @@ func StandardToolDefinitions(domain string) []ToolInfo {
 			Name:        "wetwire_write",
 			Description: "Write content to a file",
 			InputSchema: WriteSchema,
+			Parallel:    true,
+			ConflictKey: "path",
 		},
 		{
 			Name:        "wetwire_read",
 			Description: "Read content from a file",
 			InputSchema: ReadSchema,
+			Parallel:    true,
+			ConflictKey: "path",
 		},
 		{
 			Name:        "wetwire_build",
@@ -285,11 +289,19 @@ func RegisterStandardTools(server *Server, domain string, handlers StandardToolH
 			continue
 		}
 
+		var opts []ToolOption
+		if def.ConflictKey != "" {
+			opts = append(opts, ConflictKey(def.ConflictKey))
+		} else if def.Parallel {
+			opts = append(opts, Parallel)
+		}
+
 		server.RegisterToolWithSchema(
 			def.Name,
 			def.Description,
 			handler,
 			def.InputSchema,
+			opts...,
 		)
 	}
 }
@@ -319,6 +319,16 @@ func TestRegisterStandardToolsWithDefaults(t *testing.T) {
 	if _, ok := server.tools["wetwire_read"]; !ok {
 		t.Error("wetwire_read not registered")
 	}
+
+	// File operations on different paths can run in parallel
+	for _, name := range []string{"wetwire_write", "wetwire_read"} {
+		if tool := server.tools[name]; !tool.Parallel || tool.ConflictKey != "path" {
+			t.Errorf("expected %s to be parallel with conflict key 'path'", name)
+		}
+	}
+	if server.tools["wetwire_init"].Parallel {
+		t.Error("expected wetwire_init to be exclusive")
+	}
 }
 
 func TestRegisterStandardToolsWithDefaultsCustomHandlers(t *testing.T) {
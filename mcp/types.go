@@ -63,6 +63,11 @@ type ToolInfo struct {
 	Name        string         `json:"name"`
 	Description string         `json:"description,omitempty"`
 	InputSchema map[string]any `json:"inputSchema"`
+
+	// Parallel and ConflictKey are local execution hints (see Tool);
+	// they are not part of the protocol.
+	Parallel    bool   `json:"-"`
+	ConflictKey string `json:"-"`
 }
 
 // ToolsListResult is returned from tools/list.
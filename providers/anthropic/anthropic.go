@@ -3,9 +3,11 @@ package anthropic
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -22,6 +24,9 @@ type Provider struct {
 	client    anthropic.Client
 	mcpClient *mcp.Client
 	mcpConfig *MCPConfig
+
+	// disableAutoCache turns off automatic cache breakpoints
+	disableAutoCache bool
 }
 
 // Config contains configuration for the Anthropic provider.
@@ -32,6 +37,10 @@ type Config struct {
 	// MCP contains optional MCP server configuration for tool integration.
 	// If set, tools will be discovered from and executed via the MCP server.
 	MCP *MCPConfig
+
+	// DisableAutoCache disables automatic prompt cache breakpoints.
+	// Breakpoints set explicitly on the request are always honored.
+	DisableAutoCache bool
 }
 
 // MCPConfig contains configuration for MCP server integration.
@@ -62,8 +71,9 @@ func New(config Config) (*Provider, error) {
 	client := anthropic.NewClient(option.WithAPIKey(apiKey))
 
 	return &Provider{
-		client:    client,
-		mcpConfig: config.MCP,
+		client:           client,
+		mcpConfig:        config.MCP,
+		disableAutoCache: config.DisableAutoCache,
 	}, nil
 }
 
@@ -172,8 +182,27 @@ func (p *Provider) Name() string {
 	return "anthropic"
 }
 
+// Capabilities returns what the Anthropic API supports for model.
+func (p *Provider) Capabilities(model string) providers.Capabilities {
+	if model == "" {
+		model = DefaultModel
+	}
+	caps := providers.Capabilities{
+		ToolCalling:  true,
+		Streaming:    true,
+		SystemPrompt: true,
+		Multimodal:   true,
+	}
+	return caps.WithModel(model)
+}
+
 // CreateMessage sends a message request and returns the complete response.
 func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageRequest) (*providers.MessageResponse, error) {
+	req, err := providers.InlineMedia(req)
+	if err != nil {
+		return nil, err
+	}
+
 	params := p.buildParams(req)
 
 	resp, err := p.client.Messages.New(ctx, params)
@@ -186,7 +215,13 @@ func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageReque
 
 // StreamMessage sends a message request and streams the response via the handler.
 func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
+	req, err := providers.InlineMedia(req)
+	if err != nil {
+		return nil, err
+	}
+
 	params := p.buildParams(req)
+	thinkingHandler := providers.ThinkingHandlerFrom(ctx)
 
 	stream := p.client.Messages.NewStreaming(ctx, params)
 
@@ -195,6 +230,7 @@ func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageReque
 	var contentBlocks []anthropic.ContentBlockUnion
 	currentTextContent := make(map[int64]*strings.Builder)
 	currentToolInput := make(map[int64]*strings.Builder)
+	currentThinking := make(map[int64]*strings.Builder)
 
 	for stream.Next() {
 		event := stream.Current()
@@ -205,6 +241,7 @@ func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageReque
 			message = &startEvent.Message
 			contentBlocks = nil
 			currentTextContent = make(map[int64]*strings.Builder)
+			currentThinking = make(map[int64]*strings.Builder)
 
 		case "content_block_start":
 			startEvent := event.AsContentBlockStart()
@@ -214,13 +251,17 @@ func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageReque
 				currentTextContent[startEvent.Index] = &strings.Builder{}
 			case "tool_use":
 				currentToolInput[startEvent.Index] = &strings.Builder{}
+			case "thinking":
+				currentThinking[startEvent.Index] = &strings.Builder{}
 			}
 
 			block := anthropic.ContentBlockUnion{
-				Type: startEvent.ContentBlock.Type,
-				ID:   startEvent.ContentBlock.ID,
-				Name: startEvent.ContentBlock.Name,
-				Text: startEvent.ContentBlock.Text,
+				Type:      startEvent.ContentBlock.Type,
+				ID:        startEvent.ContentBlock.ID,
+				Name:      startEvent.ContentBlock.Name,
+				Text:      startEvent.ContentBlock.Text,
+				Signature: startEvent.ContentBlock.Signature,
+				Data:      startEvent.ContentBlock.Data,
 			}
 			contentBlocks = append(contentBlocks, block)
 
@@ -241,6 +282,21 @@ func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageReque
 				}
 			}
 
+			if deltaEvent.Delta.Type == "thinking_delta" && deltaEvent.Delta.Thinking != "" {
+				if thinkingHandler != nil {
+					thinkingHandler(deltaEvent.Delta.Thinking)
+				}
+
+				if builder, ok := currentThinking[deltaEvent.Index]; ok {
+					builder.WriteString(deltaEvent.Delta.Thinking)
+				}
+			}
+
+			idx := int(deltaEvent.Index)
+			if deltaEvent.Delta.Type == "signature_delta" && idx < len(contentBlocks) {
+				contentBlocks[idx].Signature += deltaEvent.Delta.Signature
+			}
+
 		case "content_block_stop":
 			stopEvent := event.AsContentBlockStop()
 			idx := int(stopEvent.Index)
@@ -251,6 +307,9 @@ func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageReque
 				if builder, ok := currentToolInput[stopEvent.Index]; ok {
 					contentBlocks[idx].Input = json.RawMessage(builder.String())
 				}
+				if builder, ok := currentThinking[stopEvent.Index]; ok {
+					contentBlocks[idx].Thinking = builder.String()
+				}
 			}
 
 		case "message_delta":
@@ -258,6 +317,18 @@ func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageReque
 			if message != nil {
 				message.StopReason = deltaEvent.Delta.StopReason
 				message.StopSequence = deltaEvent.Delta.StopSequence
+
+				// Delta usage is cumulative; input counts may be zero if unchanged
+				message.Usage.OutputTokens = deltaEvent.Usage.OutputTokens
+				if deltaEvent.Usage.InputTokens > 0 {
+					message.Usage.InputTokens = deltaEvent.Usage.InputTokens
+				}
+				if deltaEvent.Usage.CacheCreationInputTokens > 0 {
+					message.Usage.CacheCreationInputTokens = deltaEvent.Usage.CacheCreationInputTokens
+				}
+				if deltaEvent.Usage.CacheReadInputTokens > 0 {
+					message.Usage.CacheReadInputTokens = deltaEvent.Usage.CacheReadInputTokens
+				}
 			}
 		}
 	}
@@ -279,10 +350,16 @@ func (p *Provider) buildParams(req providers.MessageRequest) anthropic.MessageNe
 	if model == "" {
 		model = DefaultModel
 	}
+	model = providers.DefaultModels.Resolve(model)
 
 	maxTokens := req.MaxTokens
 	if maxTokens == 0 {
-		maxTokens = 4096
+		maxTokens = providers.DefaultMaxTokens
+	}
+
+	// max_tokens includes the thinking budget, so leave room for the answer
+	if req.ThinkingBudget > 0 && maxTokens <= req.ThinkingBudget {
+		maxTokens += req.ThinkingBudget
 	}
 
 	params := anthropic.MessageNewParams{
@@ -290,16 +367,62 @@ func (p *Provider) buildParams(req providers.MessageRequest) anthropic.MessageNe
 		MaxTokens: int64(maxTokens),
 	}
 
+	if req.ThinkingBudget > 0 {
+		params.Thinking = anthropic.ThinkingConfigParamOfEnabled(int64(req.ThinkingBudget))
+	}
+
+	if !p.disableAutoCache && !req.HasCacheBreakpoints() {
+		req = autoCache(req)
+	}
+
 	if req.System != "" {
-		params.System = []anthropic.TextBlockParam{{Text: req.System}}
+		system := anthropic.TextBlockParam{Text: req.System}
+		if req.CacheSystem {
+			system.CacheControl = anthropic.NewCacheControlEphemeralParam()
+		}
+		params.System = []anthropic.TextBlockParam{system}
 	}
 
 	params.Messages = p.convertMessages(req.Messages)
 	params.Tools = p.convertTools(req.Tools)
+	if len(req.Tools) > 0 {
+		switch req.ToolChoice {
+		case "":
+		case "any":
+			params.ToolChoice = anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}}
+		default:
+			params.ToolChoice = anthropic.ToolChoiceParamOfTool(req.ToolChoice)
+		}
+	}
 
 	return params
 }
 
+// autoCache returns a copy of the request with cache breakpoints after the
+// static prefix (system prompt, or the tool list if there is no system prompt)
+// and after the last message, so each turn reads the previous turn's prefix
+// from the cache and writes its own.
+func autoCache(req providers.MessageRequest) providers.MessageRequest {
+	if req.System != "" {
+		req.CacheSystem = true
+	} else if n := len(req.Tools); n > 0 {
+		req.Tools = slices.Clone(req.Tools)
+		req.Tools[n-1].CacheBreakpoint = true
+	}
+
+	if n := len(req.Messages); n > 0 {
+		last := req.Messages[n-1]
+		if m := len(last.Content); m > 0 {
+			req.Messages = slices.Clone(req.Messages)
+			last.Content = slices.Clone(last.Content)
+			last.Content[m-1].CacheBreakpoint = true
+			req.Messages[n-1] = last
+		}
+	}
+
+	return req
+}
+
 // convertMessages converts provider messages to Anthropic message params.
 func (p *Provider) convertMessages(msgs []providers.Message) []anthropic.MessageParam {
 	result := make([]anthropic.MessageParam, 0, len(msgs))
@@ -308,6 +431,8 @@ func (p *Provider) convertMessages(msgs []providers.Message) []anthropic.Message
 		var blocks []anthropic.ContentBlockParamUnion
 
 		for _, block := range msg.Content {
+			start := len(blocks)
+
 			switch block.Type {
 			case "text":
 				blocks = append(blocks, anthropic.NewTextBlock(block.Text))
@@ -326,6 +451,23 @@ func (p *Provider) convertMessages(msgs []providers.Message) []anthropic.Message
 					block.Content,
 					block.IsError,
 				))
+			case "thinking":
+				// Thinking must be echoed back with its signature in tool-use continuations
+				blocks = append(blocks, anthropic.NewThinkingBlock(block.Signature, block.Thinking))
+			case "redacted_thinking":
+				blocks = append(blocks, anthropic.NewRedactedThinkingBlock(block.Data))
+			case "image":
+				if block.Base64 != "" {
+					blocks = append(blocks, anthropic.NewImageBlockBase64(block.MediaType, block.Base64))
+				}
+			case "document":
+				if doc, ok := convertDocument(block); ok {
+					blocks = append(blocks, doc)
+				}
+			}
+
+			if block.CacheBreakpoint && len(blocks) > start {
+				setCacheBreakpoint(blocks)
 			}
 		}
 
@@ -339,21 +481,53 @@ func (p *Provider) convertMessages(msgs []providers.Message) []anthropic.Message
 	return result
 }
 
+// setCacheBreakpoint marks the last block that accepts cache control.
+// Thinking blocks do not, so a breakpoint on one moves to the block before
+// it, and is dropped if the message has none.
+func setCacheBreakpoint(blocks []anthropic.ContentBlockParamUnion) {
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if cc := blocks[i].GetCacheControl(); cc != nil {
+			*cc = anthropic.NewCacheControlEphemeralParam()
+			return
+		}
+	}
+}
+
+// convertDocument converts a document block to an Anthropic document param.
+// Plain text is sent as text; everything else is sent as a base64 PDF.
+func convertDocument(block providers.ContentBlock) (anthropic.ContentBlockParamUnion, bool) {
+	if block.Base64 == "" {
+		return anthropic.ContentBlockParamUnion{}, false
+	}
+
+	if block.MediaType == "text/plain" {
+		text, err := base64.StdEncoding.DecodeString(block.Base64)
+		if err != nil {
+			return anthropic.ContentBlockParamUnion{}, false
+		}
+		return anthropic.NewDocumentBlock(anthropic.PlainTextSourceParam{Data: string(text)}), true
+	}
+
+	return anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{Data: block.Base64}), true
+}
+
 // convertTools converts provider tools to Anthropic tool params.
 func (p *Provider) convertTools(tools []providers.Tool) []anthropic.ToolUnionParam {
 	result := make([]anthropic.ToolUnionParam, 0, len(tools))
 
 	for _, tool := range tools {
-		result = append(result, anthropic.ToolUnionParam{
-			OfTool: &anthropic.ToolParam{
-				Name:        tool.Name,
-				Description: anthropic.String(tool.Description),
-				InputSchema: anthropic.ToolInputSchemaParam{
-					Properties: tool.InputSchema.Properties,
-					Required:   tool.InputSchema.Required,
-				},
+		param := &anthropic.ToolParam{
+			Name:        tool.Name,
+			Description: anthropic.String(tool.Description),
+			InputSchema: anthropic.ToolInputSchemaParam{
+				Properties: tool.InputSchema.Properties,
+				Required:   tool.InputSchema.Required,
 			},
-		})
+		}
+		if tool.CacheBreakpoint {
+			param.CacheControl = anthropic.NewCacheControlEphemeralParam()
+		}
+		result = append(result, anthropic.ToolUnionParam{OfTool: param})
 	}
 
 	return result
@@ -367,6 +541,7 @@ func (p *Provider) convertResponse(resp *anthropic.Message) *providers.MessageRe
 
 	result := &providers.MessageResponse{
 		StopReason: convertStopReason(resp.StopReason),
+		Usage:      convertUsage(resp.Usage),
 	}
 
 	for _, block := range resp.Content {
@@ -381,6 +556,11 @@ func (p *Provider) convertResponse(resp *anthropic.Message) *providers.MessageRe
 			cb.ID = block.ID
 			cb.Name = block.Name
 			cb.Input = block.Input
+		case "thinking":
+			cb.Thinking = block.Thinking
+			cb.Signature = block.Signature
+		case "redacted_thinking":
+			cb.Data = block.Data
 		}
 
 		result.Content = append(result.Content, cb)
@@ -389,6 +569,16 @@ func (p *Provider) convertResponse(resp *anthropic.Message) *providers.MessageRe
 	return result
 }
 
+// convertUsage converts Anthropic usage to provider usage.
+func convertUsage(usage anthropic.Usage) providers.Usage {
+	return providers.Usage{
+		InputTokens:              int(usage.InputTokens),
+		OutputTokens:             int(usage.OutputTokens),
+		CacheCreationInputTokens: int(usage.CacheCreationInputTokens),
+		CacheReadInputTokens:     int(usage.CacheReadInputTokens),
+	}
+}
+
 // convertStopReason converts Anthropic stop reason to provider stop reason.
 func convertStopReason(reason anthropic.StopReason) providers.StopReason {
 	switch reason {
@@ -3,9 +3,14 @@ package anthropic
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/lex00/wetwire-core-go/providers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -57,6 +62,44 @@ func TestBuildParamsDefaults(t *testing.T) {
 	assert.Equal(t, int64(4096), params.MaxTokens)
 }
 
+func TestBuildParamsResolvesAlias(t *testing.T) {
+	p := &Provider{}
+
+	params := p.buildParams(providers.MessageRequest{
+		Model:    "haiku",
+		Messages: []providers.Message{providers.NewUserMessage("hello")},
+	})
+
+	assert.Equal(t, anthropic.Model("claude-haiku-4-5-20251001"), params.Model)
+}
+
+func TestBuildParamsToolChoice(t *testing.T) {
+	p := &Provider{}
+	tools := []providers.Tool{{Name: "respond"}}
+
+	params := p.buildParams(providers.MessageRequest{Tools: tools})
+	assert.Nil(t, params.ToolChoice.OfAny)
+	assert.Nil(t, params.ToolChoice.OfTool)
+
+	params = p.buildParams(providers.MessageRequest{Tools: tools, ToolChoice: "any"})
+	assert.NotNil(t, params.ToolChoice.OfAny)
+
+	params = p.buildParams(providers.MessageRequest{Tools: tools, ToolChoice: "respond"})
+	require.NotNil(t, params.ToolChoice.OfTool)
+	assert.Equal(t, "respond", params.ToolChoice.OfTool.Name)
+}
+
+func TestCapabilities(t *testing.T) {
+	p := &Provider{}
+
+	caps := p.Capabilities("")
+	assert.True(t, caps.ToolCalling)
+	assert.True(t, caps.Multimodal)
+	assert.False(t, caps.Agentic)
+	assert.Equal(t, 200000, caps.ContextWindow)
+	assert.Equal(t, 64000, caps.MaxOutputTokens)
+}
+
 func TestBuildParamsWithValues(t *testing.T) {
 	p := &Provider{}
 
@@ -75,6 +118,112 @@ func TestBuildParamsWithValues(t *testing.T) {
 	assert.Equal(t, "You are helpful", params.System[0].Text)
 }
 
+func TestBuildParamsAutoCache(t *testing.T) {
+	p := &Provider{}
+
+	req := providers.MessageRequest{
+		System: "You are helpful",
+		Tools:  []providers.Tool{{Name: "read_file"}, {Name: "write_file"}},
+		Messages: []providers.Message{
+			providers.NewUserMessage("hello"),
+			providers.NewAssistantMessage([]providers.ContentBlock{{Type: "text", Text: "hi"}}),
+			providers.NewUserMessage("again"),
+		},
+	}
+
+	params := p.buildParams(req)
+
+	assert.Equal(t, "ephemeral", string(params.System[0].CacheControl.Type))
+	assert.Empty(t, string(params.Tools[1].GetCacheControl().Type))
+	assert.Empty(t, string(params.Messages[0].Content[0].GetCacheControl().Type))
+	assert.Equal(t, "ephemeral", string(params.Messages[2].Content[0].GetCacheControl().Type))
+
+	// The request itself is not modified
+	assert.False(t, req.CacheSystem)
+	assert.False(t, req.Messages[2].Content[0].CacheBreakpoint)
+
+	data, err := json.Marshal(params)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), `"cache_control"`))
+}
+
+func TestBuildParamsAutoCacheToolsWithoutSystem(t *testing.T) {
+	p := &Provider{}
+
+	params := p.buildParams(providers.MessageRequest{
+		Tools:    []providers.Tool{{Name: "read_file"}, {Name: "write_file"}},
+		Messages: []providers.Message{providers.NewUserMessage("hello")},
+	})
+
+	assert.Empty(t, string(params.Tools[0].GetCacheControl().Type))
+	assert.Equal(t, "ephemeral", string(params.Tools[1].GetCacheControl().Type))
+}
+
+func TestBuildParamsExplicitCache(t *testing.T) {
+	p := &Provider{}
+
+	req := providers.MessageRequest{
+		System: "You are helpful",
+		Tools:  []providers.Tool{{Name: "read_file", CacheBreakpoint: true}, {Name: "write_file"}},
+		Messages: []providers.Message{
+			providers.NewUserMessage("hello"),
+			providers.NewToolResultMessage([]providers.ContentBlock{
+				providers.NewToolResult("tool_1", "ok", false),
+			}),
+		},
+	}
+
+	params := p.buildParams(req)
+
+	// Explicit breakpoints replace the automatic policy
+	assert.Empty(t, string(params.System[0].CacheControl.Type))
+	assert.Equal(t, "ephemeral", string(params.Tools[0].GetCacheControl().Type))
+	assert.Empty(t, string(params.Messages[1].Content[0].GetCacheControl().Type))
+
+	req.Messages[1].Content[0].CacheBreakpoint = true
+	params = p.buildParams(req)
+	assert.Equal(t, "ephemeral", string(params.Messages[1].Content[0].GetCacheControl().Type))
+}
+
+func TestBuildParamsCacheBreakpointOnThinking(t *testing.T) {
+	p := &Provider{}
+
+	params := p.buildParams(providers.MessageRequest{
+		Messages: []providers.Message{
+			providers.NewUserMessage("hello"),
+			providers.NewAssistantMessage([]providers.ContentBlock{
+				{Type: "text", Text: "Let me think"},
+				{Type: "thinking", Thinking: "A bucket needs versioning", Signature: "sig", CacheBreakpoint: true},
+			}),
+			providers.NewAssistantMessage([]providers.ContentBlock{
+				{Type: "redacted_thinking", Data: "opaque", CacheBreakpoint: true},
+			}),
+		},
+	})
+
+	// The breakpoint moves to the text block before the thinking block
+	assert.Equal(t, "ephemeral", string(params.Messages[1].Content[0].GetCacheControl().Type))
+	assert.Nil(t, params.Messages[1].Content[1].GetCacheControl())
+
+	// and is dropped when there is no cacheable block
+	data, err := json.Marshal(params.Messages[2])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "cache_control")
+}
+
+func TestBuildParamsDisableAutoCache(t *testing.T) {
+	p := &Provider{disableAutoCache: true}
+
+	params := p.buildParams(providers.MessageRequest{
+		System:   "You are helpful",
+		Messages: []providers.Message{providers.NewUserMessage("hello")},
+	})
+
+	data, err := json.Marshal(params)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "cache_control")
+}
+
 func TestConvertMessagesTextOnly(t *testing.T) {
 	p := &Provider{}
 
@@ -120,6 +269,34 @@ func TestConvertMessagesWithToolUse(t *testing.T) {
 	require.Len(t, result, 3)
 }
 
+func TestConvertMessagesWithMedia(t *testing.T) {
+	p := &Provider{}
+
+	msgs := []providers.Message{{
+		Role: "user",
+		Content: []providers.ContentBlock{
+			{Type: "text", Text: "Build what's in this diagram"},
+			providers.NewImage("image/png", []byte("png-bytes")),
+			providers.NewDocument("application/pdf", []byte("%PDF-1.4")),
+			providers.NewDocument("text/plain", []byte("Runbook")),
+			{Type: "image", MediaType: "image/png", Path: "unresolved.png"},
+		},
+	}}
+
+	result := p.convertMessages(msgs)
+
+	require.Len(t, result, 1)
+	require.Len(t, result[0].Content, 4)
+	require.NotNil(t, result[0].Content[1].OfImage)
+	require.NotNil(t, result[0].Content[1].OfImage.Source.OfBase64)
+	assert.Equal(t, anthropic.Base64ImageSourceMediaType("image/png"), result[0].Content[1].OfImage.Source.OfBase64.MediaType)
+	require.NotNil(t, result[0].Content[2].OfDocument)
+	require.NotNil(t, result[0].Content[2].OfDocument.Source.OfBase64)
+	require.NotNil(t, result[0].Content[3].OfDocument)
+	require.NotNil(t, result[0].Content[3].OfDocument.Source.OfText)
+	assert.Equal(t, "Runbook", result[0].Content[3].OfDocument.Source.OfText.Data)
+}
+
 func TestConvertTools(t *testing.T) {
 	p := &Provider{}
 
@@ -181,6 +358,136 @@ func TestConvertResponse(t *testing.T) {
 	assert.Equal(t, "read_file", result.Content[1].Name)
 }
 
+func TestConvertResponseThinking(t *testing.T) {
+	p := &Provider{}
+
+	resp := &anthropic.Message{
+		Content: []anthropic.ContentBlockUnion{
+			{Type: "thinking", Thinking: "The user wants a bucket", Signature: "sig-1"},
+			{Type: "redacted_thinking", Data: "encrypted"},
+			{Type: "tool_use", ID: "tool-1", Name: "write_file", Input: json.RawMessage(`{}`)},
+		},
+		StopReason: anthropic.StopReasonToolUse,
+	}
+
+	result := p.convertResponse(resp)
+
+	require.Len(t, result.Content, 3)
+	assert.True(t, result.Content[0].IsThinking())
+	assert.Equal(t, "The user wants a bucket", result.Content[0].Thinking)
+	assert.Equal(t, "sig-1", result.Content[0].Signature)
+	assert.Equal(t, "redacted_thinking", result.Content[1].Type)
+	assert.Equal(t, "encrypted", result.Content[1].Data)
+
+	// Thinking round-trips unchanged in the tool-use continuation
+	msgs := p.convertMessages([]providers.Message{providers.NewAssistantMessage(result.Content)})
+	require.Len(t, msgs[0].Content, 3)
+	require.NotNil(t, msgs[0].Content[0].OfThinking)
+	assert.Equal(t, "The user wants a bucket", msgs[0].Content[0].OfThinking.Thinking)
+	assert.Equal(t, "sig-1", msgs[0].Content[0].OfThinking.Signature)
+	require.NotNil(t, msgs[0].Content[1].OfRedactedThinking)
+	assert.Equal(t, "encrypted", msgs[0].Content[1].OfRedactedThinking.Data)
+}
+
+func TestBuildParamsThinking(t *testing.T) {
+	p := &Provider{}
+
+	params := p.buildParams(providers.MessageRequest{
+		ThinkingBudget: 8000,
+		Messages:       []providers.Message{providers.NewUserMessage("hello")},
+	})
+
+	require.NotNil(t, params.Thinking.OfEnabled)
+	assert.Equal(t, int64(8000), params.Thinking.OfEnabled.BudgetTokens)
+	assert.Greater(t, params.MaxTokens, int64(8000))
+
+	params = p.buildParams(providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("hello")},
+	})
+	assert.Nil(t, params.Thinking.OfEnabled)
+}
+
+func TestStreamMessageThinking(t *testing.T) {
+	events := []string{
+		`event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}`,
+		`event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need a "}}`,
+		`event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"bucket."}}`,
+		`event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-1"}}`,
+		`event: content_block_stop
+data: {"type":"content_block_stop","index":0}`,
+		`event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Done"}}`,
+		`event: content_block_stop
+data: {"type":"content_block_stop","index":1}`,
+		`event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":20}}`,
+		`event: message_stop
+data: {"type":"message_stop"}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			_, _ = io.WriteString(w, e+"\n\n")
+		}
+	}))
+	defer server.Close()
+
+	p := &Provider{client: anthropic.NewClient(option.WithAPIKey("test"), option.WithBaseURL(server.URL))}
+
+	var text, thinking strings.Builder
+	ctx := providers.WithThinkingHandler(context.Background(), func(chunk string) {
+		thinking.WriteString(chunk)
+	})
+
+	resp, err := p.StreamMessage(ctx, providers.MessageRequest{
+		ThinkingBudget: 2048,
+		Messages:       []providers.Message{providers.NewUserMessage("hello")},
+	}, func(chunk string) {
+		text.WriteString(chunk)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Done", text.String())
+	assert.Equal(t, "Need a bucket.", thinking.String())
+	require.Len(t, resp.Content, 2)
+	assert.Equal(t, "thinking", resp.Content[0].Type)
+	assert.Equal(t, "Need a bucket.", resp.Content[0].Thinking)
+	assert.Equal(t, "sig-1", resp.Content[0].Signature)
+	assert.Equal(t, "Done", resp.Content[1].Text)
+}
+
+func TestConvertResponseUsage(t *testing.T) {
+	p := &Provider{}
+
+	resp := &anthropic.Message{
+		StopReason: anthropic.StopReasonEndTurn,
+		Usage: anthropic.Usage{
+			InputTokens:              100,
+			OutputTokens:             20,
+			CacheCreationInputTokens: 1000,
+			CacheReadInputTokens:     5000,
+		},
+	}
+
+	result := p.convertResponse(resp)
+
+	assert.Equal(t, providers.Usage{
+		InputTokens:              100,
+		OutputTokens:             20,
+		CacheCreationInputTokens: 1000,
+		CacheReadInputTokens:     5000,
+	}, result.Usage)
+}
+
 func TestConvertResponseNil(t *testing.T) {
 	p := &Provider{}
 
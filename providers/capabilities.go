@@ -0,0 +1,125 @@
+package providers
+
+import "fmt"
+
+// Capabilities describes what a provider supports for a model.
+type Capabilities struct {
+	// ToolCalling reports that MessageRequest.Tools are offered to the model
+	// and tool_use blocks are returned for the caller to execute
+	ToolCalling bool
+
+	// Streaming reports that StreamMessage delivers text as it is generated,
+	// rather than replaying the complete response
+	Streaming bool
+
+	// SystemPrompt reports that MessageRequest.System is honored. Agentic
+	// providers without it ignore the system prompt instead of failing.
+	SystemPrompt bool
+
+	// Multimodal reports that image and document blocks are accepted
+	Multimodal bool
+
+	// ContextWindow is the maximum number of tokens per request (0 if unknown)
+	ContextWindow int
+
+	// MaxOutputTokens is the maximum value of MessageRequest.MaxTokens (0 if unknown)
+	MaxOutputTokens int
+
+	// Agentic reports that the provider runs its own agentic loop with its
+	// own tools, so a single call completes the task. Request tools are not
+	// offered to the model.
+	Agentic bool
+}
+
+// CapabilityReporter is implemented by providers that describe their capabilities.
+type CapabilityReporter interface {
+	// Capabilities returns what the provider supports for model
+	// (or for its default model if model is empty).
+	Capabilities(model string) Capabilities
+}
+
+// CapabilitiesOf returns the capabilities of p for model.
+// Wrapping providers are unwrapped until one reports its capabilities.
+// Other providers are assumed to be raw model APIs with tool calling,
+// streaming and system prompts, and the limits of the model in DefaultModels.
+func CapabilitiesOf(p Provider, model string) Capabilities {
+	for p != nil {
+		if r, ok := p.(CapabilityReporter); ok {
+			return r.Capabilities(model)
+		}
+		w, ok := p.(interface{ Unwrap() Provider })
+		if !ok {
+			break
+		}
+		p = w.Unwrap()
+	}
+
+	caps := Capabilities{
+		ToolCalling:  true,
+		Streaming:    true,
+		SystemPrompt: true,
+	}
+	return caps.WithModel(model)
+}
+
+// WithModel returns c with the limits of model, if it is in DefaultModels.
+// Multimodal support requires both the provider and the model.
+func (c Capabilities) WithModel(model string) Capabilities {
+	m, ok := DefaultModels.Lookup(model)
+	if !ok {
+		return c
+	}
+	c.Multimodal = c.Multimodal && m.Multimodal
+	c.ContextWindow = m.ContextWindow
+	c.MaxOutputTokens = m.MaxOutputTokens
+	return c
+}
+
+// Check returns an error describing the first part of req the provider
+// cannot honor. Agents call it up front so misconfiguration fails before
+// any tokens are spent.
+func (c Capabilities) Check(req MessageRequest) error {
+	if len(req.Tools) > 0 && !c.ToolCalling && !c.Agentic {
+		return fmt.Errorf("provider does not support tool calling")
+	}
+	// Agentic providers may be configured with their own prompt, such as a
+	// CLI agent file, so an unused system prompt is not an error.
+	if req.System != "" && !c.SystemPrompt && !c.Agentic {
+		return fmt.Errorf("provider does not support system prompts; configure the prompt on the provider instead")
+	}
+	if c.MaxOutputTokens > 0 && req.MaxTokens > c.MaxOutputTokens {
+		return fmt.Errorf("max tokens %d exceeds the model limit of %d", req.MaxTokens, c.MaxOutputTokens)
+	}
+	if !c.Multimodal {
+		for _, msg := range req.Messages {
+			for _, block := range msg.Content {
+				if block.IsMedia() {
+					return fmt.Errorf("provider does not support %s content", block.Type)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Merge returns the capabilities shared by c and other, for a provider
+// that may serve a request with either. Limits take the smaller known value.
+func (c Capabilities) Merge(other Capabilities) Capabilities {
+	return Capabilities{
+		ToolCalling:     c.ToolCalling && other.ToolCalling,
+		Streaming:       c.Streaming && other.Streaming,
+		SystemPrompt:    c.SystemPrompt && other.SystemPrompt,
+		Multimodal:      c.Multimodal && other.Multimodal,
+		ContextWindow:   minLimit(c.ContextWindow, other.ContextWindow),
+		MaxOutputTokens: minLimit(c.MaxOutputTokens, other.MaxOutputTokens),
+		Agentic:         c.Agentic || other.Agentic,
+	}
+}
+
+// minLimit returns the smaller of two limits, where 0 means unknown.
+func minLimit(a, b int) int {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,94 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capableProvider is a stubProvider that reports its capabilities.
+type capableProvider struct {
+	stubProvider
+	caps Capabilities
+}
+
+func (c *capableProvider) Capabilities(model string) Capabilities { return c.caps }
+
+// wrapper is a provider that wraps another, like retry.Provider.
+type wrapper struct {
+	stubProvider
+	inner Provider
+}
+
+func (w *wrapper) Unwrap() Provider { return w.inner }
+
+func TestCapabilitiesOf(t *testing.T) {
+	t.Run("raw provider defaults with model limits", func(t *testing.T) {
+		caps := CapabilitiesOf(&stubProvider{name: "stub"}, "haiku")
+		assert.True(t, caps.ToolCalling)
+		assert.True(t, caps.Streaming)
+		assert.True(t, caps.SystemPrompt)
+		assert.False(t, caps.Multimodal)
+		assert.False(t, caps.Agentic)
+		assert.Equal(t, 200000, caps.ContextWindow)
+		assert.Equal(t, 64000, caps.MaxOutputTokens)
+	})
+
+	t.Run("unknown model has unknown limits", func(t *testing.T) {
+		caps := CapabilitiesOf(&stubProvider{name: "stub"}, "qwen2.5-coder")
+		assert.Zero(t, caps.ContextWindow)
+		assert.Zero(t, caps.MaxOutputTokens)
+	})
+
+	t.Run("unwraps to a reporting provider", func(t *testing.T) {
+		inner := &capableProvider{caps: Capabilities{Agentic: true}}
+		caps := CapabilitiesOf(&wrapper{inner: inner}, "")
+		assert.Equal(t, Capabilities{Agentic: true}, caps)
+	})
+}
+
+func TestCapabilitiesCheck(t *testing.T) {
+	raw := Capabilities{ToolCalling: true, SystemPrompt: true, MaxOutputTokens: 8192}
+	agentic := Capabilities{Agentic: true, Multimodal: true}
+
+	tools := []Tool{{Name: "run_lint"}}
+	image := Message{Role: "user", Content: []ContentBlock{{Type: "image", MediaType: "image/png", Path: "a.png"}}}
+
+	assert.NoError(t, raw.Check(MessageRequest{System: "sys", Tools: tools, MaxTokens: 4096}))
+	assert.ErrorContains(t, raw.Check(MessageRequest{MaxTokens: 10000}), "exceeds the model limit of 8192")
+	assert.ErrorContains(t, raw.Check(MessageRequest{Messages: []Message{image}}), "image")
+
+	assert.NoError(t, agentic.Check(MessageRequest{Tools: tools, MaxTokens: 100000, Messages: []Message{image}}))
+	assert.NoError(t, agentic.Check(MessageRequest{System: "sys"}))
+	assert.ErrorContains(t, Capabilities{ToolCalling: true}.Check(MessageRequest{System: "sys"}), "system prompts")
+	assert.ErrorContains(t, Capabilities{}.Check(MessageRequest{Tools: tools}), "tool calling")
+}
+
+func TestRouterCapabilities(t *testing.T) {
+	cli := &capableProvider{
+		stubProvider: stubProvider{name: "claude"},
+		caps:         Capabilities{Streaming: true, SystemPrompt: true, Multimodal: true, Agentic: true},
+	}
+	api := &capableProvider{
+		stubProvider: stubProvider{name: "anthropic"},
+		caps:         Capabilities{ToolCalling: true, Streaming: true, SystemPrompt: true, Multimodal: true, ContextWindow: 200000, MaxOutputTokens: 8192},
+	}
+
+	r, err := NewRouter(RouterConfig{
+		Backends: []Backend{{Provider: cli}, {Provider: api}},
+		Routes:   map[string][]Route{"api": {{Backend: "anthropic"}}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, Capabilities{
+		Streaming:       true,
+		SystemPrompt:    true,
+		Multimodal:      true,
+		ContextWindow:   200000,
+		MaxOutputTokens: 8192,
+		Agentic:         true,
+	}, r.Capabilities("sonnet"))
+
+	assert.Equal(t, api.caps, r.Capabilities("api"))
+}
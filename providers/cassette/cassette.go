@@ -0,0 +1,439 @@
+// Package cassette provides a record/replay decorator for the Provider interface.
+//
+// In record mode every MessageRequest/MessageResponse pair sent through the
+// wrapped provider, including streamed text chunks, is appended to a cassette
+// file. In replay mode the cassette is loaded and responses are served back by
+// matching a hash of the normalized request, so agent sessions can be
+// committed as fixtures and replayed offline without an API key or CLI.
+//
+// Usage:
+//
+//	// Record a real session once
+//	rec, err := cassette.New(cassette.Config{
+//		Path:     "testdata/session.json",
+//		Mode:     cassette.ModeRecord,
+//		Provider: anthropicProvider,
+//	})
+//
+//	// Replay it in CI
+//	rep, err := cassette.New(cassette.Config{
+//		Path: "testdata/session.json",
+//		Mode: cassette.ModeReplay,
+//	})
+package cassette
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// Version is the cassette file format version.
+const Version = 1
+
+// Mode selects whether the cassette records or replays interactions.
+type Mode string
+
+const (
+	// ModeRecord forwards requests to the wrapped provider and records them.
+	ModeRecord Mode = "record"
+
+	// ModeReplay serves recorded responses without calling any provider.
+	ModeReplay Mode = "replay"
+
+	// ModeAuto replays if the cassette file exists, and records otherwise.
+	ModeAuto Mode = "auto"
+)
+
+// ErrNoMatch is returned in replay mode when no recorded interaction matches a request.
+var ErrNoMatch = errors.New("no recorded interaction matches request")
+
+// Cassette is the on-disk format of a recorded session.
+type Cassette struct {
+	// Version is the file format version
+	Version int `json:"version"`
+
+	// Provider is the name of the provider that was recorded
+	Provider string `json:"provider"`
+
+	// Interactions are the recorded request/response pairs, in call order
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request/response pair.
+type Interaction struct {
+	// Hash is the normalized request hash used for matching
+	Hash string `json:"hash"`
+
+	// Streamed is true if the interaction was recorded via StreamMessage
+	Streamed bool `json:"streamed,omitempty"`
+
+	// Request is the request that was sent
+	Request providers.MessageRequest `json:"request"`
+
+	// Response is the response that was returned (nil if Error is set)
+	Response *providers.MessageResponse `json:"response,omitempty"`
+
+	// Chunks are the text chunks delivered to the stream handler
+	Chunks []string `json:"chunks,omitempty"`
+
+	// Error is the error message returned by the provider, if any
+	Error string `json:"error,omitempty"`
+}
+
+// Config contains configuration for the cassette provider.
+type Config struct {
+	// Path is the cassette file path (required)
+	Path string
+
+	// Mode selects record, replay or auto (default: ModeReplay)
+	Mode Mode
+
+	// Provider is the provider to record (required for record mode)
+	Provider providers.Provider
+}
+
+// Provider implements the providers.Provider interface by recording or
+// replaying interactions with a cassette file.
+type Provider struct {
+	path     string
+	mode     Mode
+	provider providers.Provider
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New creates a new cassette provider.
+// In replay mode the cassette file must exist. In record mode any existing
+// cassette at the path is overwritten.
+func New(config Config) (*Provider, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	mode := config.Mode
+	if mode == "" {
+		mode = ModeReplay
+	}
+	if mode == ModeAuto {
+		if _, err := os.Stat(config.Path); err == nil {
+			mode = ModeReplay
+		} else {
+			mode = ModeRecord
+		}
+	}
+
+	p := &Provider{
+		path:     config.Path,
+		mode:     mode,
+		provider: config.Provider,
+	}
+
+	switch mode {
+	case ModeRecord:
+		if config.Provider == nil {
+			return nil, fmt.Errorf("provider is required in record mode")
+		}
+		p.cassette = Cassette{
+			Version:  Version,
+			Provider: config.Provider.Name(),
+		}
+	case ModeReplay:
+		c, err := Load(config.Path)
+		if err != nil {
+			return nil, err
+		}
+		p.cassette = *c
+		p.used = make([]bool, len(c.Interactions))
+	default:
+		return nil, fmt.Errorf("unknown cassette mode: %s", mode)
+	}
+
+	return p, nil
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette: %w", err)
+	}
+	if c.Version != Version {
+		return nil, fmt.Errorf("unsupported cassette version %d (expected %d)", c.Version, Version)
+	}
+
+	return &c, nil
+}
+
+// Save writes the cassette to a file.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	return nil
+}
+
+// Name returns the name of the recorded provider.
+func (p *Provider) Name() string {
+	if p.provider != nil {
+		return p.provider.Name()
+	}
+	if p.cassette.Provider != "" {
+		return p.cassette.Provider
+	}
+	return "cassette"
+}
+
+// Mode returns the effective mode (ModeAuto is resolved at construction).
+func (p *Provider) Mode() Mode {
+	return p.mode
+}
+
+// Unwrap returns the recorded provider, or nil when replaying.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// Interactions returns a copy of the recorded or loaded interactions.
+func (p *Provider) Interactions() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make([]Interaction, len(p.cassette.Interactions))
+	copy(result, p.cassette.Interactions)
+	return result
+}
+
+// Unused returns the replay interactions that were never served.
+// This is useful for asserting that a replayed session followed the recording.
+func (p *Provider) Unused() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var result []Interaction
+	for i, used := range p.used {
+		if !used {
+			result = append(result, p.cassette.Interactions[i])
+		}
+	}
+	return result
+}
+
+// CreateMessage records or replays a non-streaming request.
+func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageRequest) (*providers.MessageResponse, error) {
+	if p.mode == ModeReplay {
+		interaction, err := p.match(req)
+		if err != nil {
+			return nil, err
+		}
+		return replayResult(interaction)
+	}
+
+	resp, err := p.provider.CreateMessage(ctx, req)
+	if recErr := p.record(req, resp, nil, false, err); recErr != nil {
+		return nil, recErr
+	}
+	return resp, err
+}
+
+// StreamMessage records or replays a streaming request.
+// Recorded chunks are delivered to the handler on replay. Interactions
+// recorded via CreateMessage replay their text blocks as single chunks.
+func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
+	if p.mode == ModeReplay {
+		interaction, err := p.match(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if handler != nil {
+			if interaction.Streamed {
+				for _, chunk := range interaction.Chunks {
+					handler(chunk)
+				}
+			} else if interaction.Response != nil {
+				for _, block := range interaction.Response.Content {
+					if block.Type == "text" && block.Text != "" {
+						handler(block.Text)
+					}
+				}
+			}
+		}
+
+		return replayResult(interaction)
+	}
+
+	var chunks []string
+	resp, err := p.provider.StreamMessage(ctx, req, func(text string) {
+		chunks = append(chunks, text)
+		if handler != nil {
+			handler(text)
+		}
+	})
+	if recErr := p.record(req, resp, chunks, true, err); recErr != nil {
+		return nil, recErr
+	}
+	return resp, err
+}
+
+// record appends an interaction and saves the cassette so that partial
+// sessions survive a crash.
+func (p *Provider) record(req providers.MessageRequest, resp *providers.MessageResponse, chunks []string, streamed bool, callErr error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	interaction := Interaction{
+		Hash:     RequestHash(req),
+		Streamed: streamed,
+		Request:  req,
+		Response: resp,
+		Chunks:   chunks,
+	}
+	if callErr != nil {
+		interaction.Response = nil
+		interaction.Error = callErr.Error()
+	}
+
+	p.cassette.Interactions = append(p.cassette.Interactions, interaction)
+	return p.cassette.Save(p.path)
+}
+
+// match returns the first unused interaction with the same request hash.
+func (p *Provider) match(req providers.MessageRequest) (Interaction, error) {
+	hash := RequestHash(req)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, interaction := range p.cassette.Interactions {
+		if !p.used[i] && interaction.Hash == hash {
+			p.used[i] = true
+			return interaction, nil
+		}
+	}
+
+	return Interaction{}, fmt.Errorf("%w: hash %s in %s (last message: %q)",
+		ErrNoMatch, hash, p.path, lastMessageSummary(req))
+}
+
+// replayResult converts a recorded interaction into a provider result.
+func replayResult(interaction Interaction) (*providers.MessageResponse, error) {
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+	if interaction.Response == nil {
+		return &providers.MessageResponse{}, nil
+	}
+	return interaction.Response, nil
+}
+
+// RequestHash returns a stable hash of the normalized request.
+// Text is trimmed and tool inputs are re-encoded so that whitespace and key
+// order differences do not affect matching.
+func RequestHash(req providers.MessageRequest) string {
+	data, _ := json.Marshal(normalize(req))
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalize returns a copy of the request with insignificant differences removed.
+func normalize(req providers.MessageRequest) providers.MessageRequest {
+	n := providers.MessageRequest{
+		Model:     req.Model,
+		MaxTokens: req.MaxTokens,
+		System:    strings.TrimSpace(req.System),
+
+		ToolChoice:     req.ToolChoice,
+		ThinkingBudget: req.ThinkingBudget,
+	}
+
+	// Cache breakpoints do not affect the response
+	for _, tool := range req.Tools {
+		tool.CacheBreakpoint = false
+		n.Tools = append(n.Tools, tool)
+	}
+
+	for _, msg := range req.Messages {
+		nm := providers.Message{Role: msg.Role}
+		for _, block := range msg.Content {
+			block.Text = strings.TrimSpace(block.Text)
+			block.Content = strings.TrimSpace(block.Content)
+			block.Input = canonicalJSON(block.Input)
+			block.CacheBreakpoint = false
+			nm.Content = append(nm.Content, block)
+		}
+		n.Messages = append(n.Messages, nm)
+	}
+
+	return n
+}
+
+// canonicalJSON re-encodes raw JSON with sorted keys and no whitespace.
+func canonicalJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return raw
+	}
+	return data
+}
+
+// lastMessageSummary returns a short description of the last message for error output.
+func lastMessageSummary(req providers.MessageRequest) string {
+	if len(req.Messages) == 0 {
+		return ""
+	}
+
+	msg := req.Messages[len(req.Messages)-1]
+	var parts []string
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			parts = append(parts, block.Text)
+		case "tool_result":
+			parts = append(parts, "tool_result:"+block.ToolUseID)
+		case "tool_use":
+			parts = append(parts, "tool_use:"+block.Name)
+		}
+	}
+
+	summary := strings.Join(parts, " ")
+	if len(summary) > 80 {
+		summary = summary[:80] + "..."
+	}
+	return summary
+}
@@ -0,0 +1,228 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/lex00/wetwire-core-go/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider returns canned responses in order.
+type stubProvider struct {
+	responses []*providers.MessageResponse
+	err       error
+	calls     int
+}
+
+func (s *stubProvider) Name() string { return "stub" }
+
+func (s *stubProvider) CreateMessage(ctx context.Context, req providers.MessageRequest) (*providers.MessageResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	resp := s.responses[s.calls]
+	s.calls++
+	return resp, nil
+}
+
+func (s *stubProvider) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
+	resp, err := s.CreateMessage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			handler(block.Text[:len(block.Text)/2])
+			handler(block.Text[len(block.Text)/2:])
+		}
+	}
+	return resp, nil
+}
+
+func textResponse(text string) *providers.MessageResponse {
+	return &providers.MessageResponse{
+		StopReason: providers.StopReasonEndTurn,
+		Content:    []providers.ContentBlock{{Type: "text", Text: text}},
+	}
+}
+
+func request(text string) providers.MessageRequest {
+	return providers.MessageRequest{
+		System:   "system",
+		Messages: []providers.Message{providers.NewUserMessage(text)},
+	}
+}
+
+func TestProviderImplementsInterface(t *testing.T) {
+	var _ providers.Provider = (*Provider)(nil)
+}
+
+func TestNewValidation(t *testing.T) {
+	_, err := New(Config{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "path is required")
+
+	_, err = New(Config{Path: filepath.Join(t.TempDir(), "c.json"), Mode: ModeRecord})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "provider is required")
+
+	_, err = New(Config{Path: filepath.Join(t.TempDir(), "missing.json"), Mode: ModeReplay})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read cassette")
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures", "session.json")
+	stub := &stubProvider{responses: []*providers.MessageResponse{
+		textResponse("first"),
+		textResponse("second"),
+		textResponse("third"),
+	}}
+
+	rec, err := New(Config{Path: path, Mode: ModeRecord, Provider: stub})
+	require.NoError(t, err)
+	assert.Equal(t, "stub", rec.Name())
+
+	ctx := context.Background()
+	_, err = rec.CreateMessage(ctx, request("hello"))
+	require.NoError(t, err)
+	_, err = rec.CreateMessage(ctx, request("bye"))
+	require.NoError(t, err)
+	_, err = rec.CreateMessage(ctx, request("hello"))
+	require.NoError(t, err)
+
+	rep, err := New(Config{Path: path, Mode: ModeReplay})
+	require.NoError(t, err)
+	assert.Equal(t, "stub", rep.Name())
+	require.Len(t, rep.Interactions(), 3)
+
+	// Identical requests are served in recorded order
+	resp, err := rep.CreateMessage(ctx, request("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "first", resp.Content[0].Text)
+
+	resp, err = rep.CreateMessage(ctx, request("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "third", resp.Content[0].Text)
+
+	require.Len(t, rep.Unused(), 1)
+
+	resp, err = rep.CreateMessage(ctx, request("bye"))
+	require.NoError(t, err)
+	assert.Equal(t, "second", resp.Content[0].Text)
+	assert.Empty(t, rep.Unused())
+	assert.Equal(t, 3, stub.calls)
+}
+
+func TestReplayUnmatchedRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	stub := &stubProvider{responses: []*providers.MessageResponse{textResponse("ok")}}
+
+	rec, err := New(Config{Path: path, Mode: ModeRecord, Provider: stub})
+	require.NoError(t, err)
+	_, err = rec.CreateMessage(context.Background(), request("hello"))
+	require.NoError(t, err)
+
+	rep, err := New(Config{Path: path, Mode: ModeReplay})
+	require.NoError(t, err)
+
+	_, err = rep.CreateMessage(context.Background(), request("something else"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrNoMatch))
+	assert.Contains(t, err.Error(), "something else")
+}
+
+func TestStreamRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	stub := &stubProvider{responses: []*providers.MessageResponse{textResponse("streamed")}}
+
+	rec, err := New(Config{Path: path, Mode: ModeRecord, Provider: stub})
+	require.NoError(t, err)
+
+	var recorded []string
+	_, err = rec.StreamMessage(context.Background(), request("hi"), func(text string) {
+		recorded = append(recorded, text)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stre", "amed"}, recorded)
+
+	rep, err := New(Config{Path: path, Mode: ModeReplay})
+	require.NoError(t, err)
+
+	var replayed []string
+	resp, err := rep.StreamMessage(context.Background(), request("hi"), func(text string) {
+		replayed = append(replayed, text)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+	assert.Equal(t, "streamed", resp.Content[0].Text)
+}
+
+func TestRecordsProviderErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	stub := &stubProvider{err: errors.New("overloaded")}
+
+	rec, err := New(Config{Path: path, Mode: ModeRecord, Provider: stub})
+	require.NoError(t, err)
+	_, err = rec.CreateMessage(context.Background(), request("hi"))
+	require.EqualError(t, err, "overloaded")
+
+	rep, err := New(Config{Path: path, Mode: ModeReplay})
+	require.NoError(t, err)
+	_, err = rep.CreateMessage(context.Background(), request("hi"))
+	require.EqualError(t, err, "overloaded")
+}
+
+func TestAutoMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	stub := &stubProvider{responses: []*providers.MessageResponse{textResponse("ok")}}
+
+	p, err := New(Config{Path: path, Mode: ModeAuto, Provider: stub})
+	require.NoError(t, err)
+	assert.Equal(t, ModeRecord, p.Mode())
+	_, err = p.CreateMessage(context.Background(), request("hi"))
+	require.NoError(t, err)
+
+	p, err = New(Config{Path: path, Mode: ModeAuto, Provider: stub})
+	require.NoError(t, err)
+	assert.Equal(t, ModeReplay, p.Mode())
+	_, err = p.CreateMessage(context.Background(), request("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, stub.calls)
+}
+
+func TestRequestHashNormalization(t *testing.T) {
+	withInput := func(text, input string) providers.MessageRequest {
+		return providers.MessageRequest{
+			Messages: []providers.Message{
+				providers.NewUserMessage(text),
+				providers.NewAssistantMessage([]providers.ContentBlock{
+					{Type: "tool_use", ID: "1", Name: "write_file", Input: json.RawMessage(input)},
+				}),
+			},
+		}
+	}
+
+	a := withInput("hello", `{"path": "a.go", "content": "x"}`)
+	b := withInput("  hello\n", `{"content":"x","path":"a.go"}`)
+	c := withInput("hello", `{"path":"b.go","content":"x"}`)
+
+	assert.Equal(t, RequestHash(a), RequestHash(b))
+	assert.NotEqual(t, RequestHash(a), RequestHash(c))
+
+	// Cache breakpoints do not change the hash
+	cached := withInput("hello", `{"path": "a.go", "content": "x"}`)
+	cached.Messages[1].Content[0].CacheBreakpoint = true
+	cached.CacheSystem = true
+	assert.Equal(t, RequestHash(a), RequestHash(cached))
+
+	// Thinking changes the response, so it changes the hash
+	thinking := withInput("hello", `{"path": "a.go", "content": "x"}`)
+	thinking.ThinkingBudget = 2048
+	assert.NotEqual(t, RequestHash(a), RequestHash(thinking))
+}
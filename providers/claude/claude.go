@@ -3,6 +3,9 @@
 // This provider uses the `claude` CLI (Claude Code) as the AI backend, allowing
 // scenarios to run without an Anthropic API key. Claude Code handles its own
 // agentic loop internally, so the caller's loop typically runs once.
+// Follow-up calls that extend the same conversation, such as a Developer's
+// answer to a question, resume the previous CLI session rather than
+// re-sending the whole history.
 //
 // Usage:
 //
@@ -22,12 +25,20 @@ package claude
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/lex00/wetwire-core-go/providers"
 )
@@ -35,6 +46,21 @@ import (
 // Provider implements the providers.Provider interface using Claude Code CLI.
 type Provider struct {
 	config Config
+
+	mu      sync.Mutex
+	session session
+}
+
+// session tracks the CLI session that a conversation can be resumed from.
+type session struct {
+	// id is the CLI session ID (empty if there is none)
+	id string
+
+	// sent is the number of request messages the session has seen
+	sent int
+
+	// digest is a hash of those messages, so a diverged history is detected
+	digest string
 }
 
 // Config contains configuration for the Claude provider.
@@ -62,6 +88,19 @@ type Config struct {
 	// PermissionMode sets the permission mode (optional)
 	// Options: "default", "acceptEdits", "plan", etc.
 	PermissionMode string
+
+	// DisableResume starts a fresh CLI session on every call, sending the
+	// flattened history instead of resuming the previous session
+	DisableResume bool
+
+	// EventHandler receives each text, tool_use and tool_result block as
+	// Claude Code emits it in streaming mode (optional)
+	EventHandler func(block providers.ContentBlock)
+
+	// SessionHandler receives the CLI session ID as soon as Claude Code
+	// reports it in streaming mode, so a caller can save it and resume an
+	// interrupted session later with Provider.Resume (optional)
+	SessionHandler func(sessionID string)
 }
 
 // New creates a new Claude Code provider.
@@ -84,6 +123,23 @@ func (p *Provider) Name() string {
 	return "claude"
 }
 
+// Capabilities returns what the Claude Code CLI supports.
+// Claude Code runs its own agentic loop with its built-in and MCP tools,
+// so MessageRequest.Tools are not offered to the model. Config.Model, if set,
+// takes precedence over model.
+func (p *Provider) Capabilities(model string) providers.Capabilities {
+	if p.config.Model != "" {
+		model = p.config.Model
+	}
+	caps := providers.Capabilities{
+		Streaming:    true,
+		SystemPrompt: true,
+		Multimodal:   true,
+		Agentic:      true,
+	}
+	return caps.WithModel(model)
+}
+
 // Available checks if the claude CLI is installed and available.
 func Available() bool {
 	_, err := exec.LookPath("claude")
@@ -98,11 +154,33 @@ func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageReque
 		return nil, fmt.Errorf("claude CLI not found in PATH")
 	}
 
-	// Build the prompt from messages
-	prompt := p.buildPrompt(req)
+	// Write inline images and documents to files the CLI can read
+	spilled, cleanup, err := providers.SpillMedia(req, "")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	prompt, resumeID := p.conversation(req, spilled)
 
+	resp, sessionID, err := p.execute(ctx, spilled, prompt, resumeID)
+	if errors.Is(err, errSessionNotFound) {
+		// The session expired; start over with the full history
+		prompt, resumeID = p.buildPrompt(spilled), ""
+		resp, sessionID, err = p.execute(ctx, spilled, prompt, resumeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.remember(req, sessionID)
+	return resp, nil
+}
+
+// execute runs the claude CLI once and parses its JSON output.
+func (p *Provider) execute(ctx context.Context, req providers.MessageRequest, prompt, resumeID string) (*providers.MessageResponse, string, error) {
 	// Build command arguments
-	args := p.buildArgs(req, prompt)
+	args := withResume(p.buildArgs(req, prompt), resumeID)
 
 	// Execute claude CLI
 	cmd := exec.CommandContext(ctx, "claude", args...)
@@ -110,7 +188,15 @@ func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageReque
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("claude execution failed: %w\nOutput: %s", err, string(output))
+		if resumeID != "" && strings.Contains(string(output), sessionNotFoundMarker) {
+			return nil, "", fmt.Errorf("%w: %s", errSessionNotFound, resumeID)
+		}
+		// The CLI exits non-zero after printing an error result
+		var resultErr *ResultError
+		if _, _, parseErr := p.parseJSONOutput(output); errors.As(parseErr, &resultErr) {
+			return nil, "", resultErr
+		}
+		return nil, "", fmt.Errorf("claude execution failed: %w\nOutput: %s", err, string(output))
 	}
 
 	// Parse the JSON output
@@ -119,20 +205,53 @@ func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageReque
 
 // StreamMessage sends a message request and streams the response via the handler.
 // This uses --output-format stream-json to get realtime updates.
+// The tools Claude Code runs are reported as tool_use and tool_result blocks,
+// in order, ahead of the final text in the response.
 func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
 	if !Available() {
 		return nil, fmt.Errorf("claude CLI not found in PATH")
 	}
 
-	prompt := p.buildPrompt(req)
-	args := p.buildStreamArgs(req, prompt)
+	spilled, cleanup, err := providers.SpillMedia(req, "")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	prompt, resumeID := p.conversation(req, spilled)
+
+	delivered := false
+	stream := func(text string) {
+		delivered = true
+		if handler != nil {
+			handler(text)
+		}
+	}
+
+	resp, sessionID, err := p.executeStream(ctx, spilled, prompt, resumeID, stream)
+	if errors.Is(err, errSessionNotFound) && !delivered {
+		// The session expired; start over with the full history
+		prompt, resumeID = p.buildPrompt(spilled), ""
+		resp, sessionID, err = p.executeStream(ctx, spilled, prompt, resumeID, stream)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.remember(req, sessionID)
+	return resp, nil
+}
+
+// executeStream runs the claude CLI once with stream-json output.
+func (p *Provider) executeStream(ctx context.Context, req providers.MessageRequest, prompt, resumeID string, handler providers.StreamHandler) (*providers.MessageResponse, string, error) {
+	args := withResume(p.buildStreamArgs(req, prompt), resumeID)
 
 	cmd := exec.CommandContext(ctx, "claude", args...)
 	cmd.Dir = p.config.WorkDir
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return nil, "", fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
 	// Capture stderr for error messages
@@ -140,10 +259,13 @@ func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageReque
 	cmd.Stderr = &stderrBuf
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start claude: %w", err)
+		return nil, "", fmt.Errorf("failed to start claude: %w", err)
 	}
 
 	var finalResponse *providers.MessageResponse
+	var resultErr *ResultError
+	var blocks []providers.ContentBlock
+	var sessionID string
 	scanner := bufio.NewScanner(stdout)
 	// Increase buffer size for large outputs
 	buf := make([]byte, 0, 64*1024)
@@ -160,23 +282,36 @@ func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageReque
 			continue // Skip unparseable lines
 		}
 
+		if event.SessionID != "" && event.SessionID != sessionID {
+			sessionID = event.SessionID
+			if p.config.SessionHandler != nil {
+				p.config.SessionHandler(sessionID)
+			}
+		}
+
 		switch event.Type {
-		case "assistant":
-			// Stream text content to handler
-			if event.Message != nil {
-				for _, block := range event.Message.Content {
-					if block.Type == "text" && handler != nil {
-						handler(block.Text)
-					}
+		case "assistant", "user":
+			// Stream text to the handler and collect tool activity
+			for _, block := range event.contentBlocks() {
+				if block.Type == "text" {
+					handler(block.Text)
+				} else {
+					blocks = append(blocks, block)
+				}
+				if p.config.EventHandler != nil {
+					p.config.EventHandler(block)
 				}
 			}
 		case "result":
-			// Build final response from result
+			if event.IsError {
+				resultErr = newResultError(event.Subtype, event.Result)
+				continue
+			}
+			// Build final response from the tool activity and result
 			finalResponse = &providers.MessageResponse{
 				StopReason: providers.StopReasonEndTurn,
-				Content: []providers.ContentBlock{
-					{Type: "text", Text: event.Result},
-				},
+				Content:    append(blocks, providers.ContentBlock{Type: "text", Text: event.Result}),
+				Usage:      event.Usage.toUsage(event.TotalCostUSD),
 			}
 		}
 	}
@@ -184,23 +319,122 @@ func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageReque
 	if err := cmd.Wait(); err != nil {
 		// Check if context was cancelled
 		if ctx.Err() != nil {
-			return nil, ctx.Err()
+			return nil, "", ctx.Err()
 		}
 		// Include stderr output in error message
 		stderrOutput := stderrBuf.String()
+		if resumeID != "" && strings.Contains(stderrOutput, sessionNotFoundMarker) {
+			return nil, "", fmt.Errorf("%w: %s", errSessionNotFound, resumeID)
+		}
+		if resultErr != nil {
+			return nil, "", resultErr
+		}
 		if stderrOutput != "" {
-			return nil, fmt.Errorf("claude execution failed: %w\nStderr: %s", err, stderrOutput)
+			return nil, "", fmt.Errorf("claude execution failed: %w\nStderr: %s", err, stderrOutput)
 		}
-		return nil, fmt.Errorf("claude execution failed: %w", err)
+		return nil, "", fmt.Errorf("claude execution failed: %w", err)
+	}
+	if resultErr != nil {
+		return nil, "", resultErr
 	}
 
 	if finalResponse == nil {
 		return &providers.MessageResponse{
 			StopReason: providers.StopReasonEndTurn,
-		}, nil
+			Content:    blocks,
+		}, sessionID, nil
+	}
+
+	return finalResponse, sessionID, nil
+}
+
+// SessionID returns the CLI session that the next call will resume, if any.
+func (p *Provider) SessionID() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.session.id
+}
+
+// Resume makes the next call resume a CLI session that served history,
+// such as one saved by SessionHandler before the process stopped. A request
+// that extends history sends only its new user input to that session.
+func (p *Provider) Resume(sessionID string, history []providers.Message) {
+	p.remember(providers.MessageRequest{Messages: history}, sessionID)
+}
+
+// Reset forgets the current CLI session so the next call starts a new one.
+func (p *Provider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.session = session{}
+}
+
+// conversation returns the prompt to send for req and the session to resume.
+// If req continues the conversation of the current session, only the user
+// text added since the last call is sent. Otherwise the full history is
+// flattened into a prompt for a new session. The session is matched against
+// req, while the prompt is built from spilled, the same request with its
+// media written to files.
+func (p *Provider) conversation(req, spilled providers.MessageRequest) (string, string) {
+	p.mu.Lock()
+	current := p.session
+	p.mu.Unlock()
+
+	if p.config.DisableResume || current.id == "" || len(req.Messages) <= current.sent {
+		return p.buildPrompt(spilled), ""
+	}
+	if digestMessages(req.Messages[:current.sent]) != current.digest {
+		return p.buildPrompt(spilled), ""
+	}
+
+	// The session already holds its own replies, so only new user input is sent
+	prompt := p.buildPrompt(providers.MessageRequest{Messages: spilled.Messages[current.sent:]})
+	if prompt == "" {
+		return p.buildPrompt(spilled), ""
 	}
 
-	return finalResponse, nil
+	return prompt, current.id
+}
+
+// remember records the session that served req so the next call can resume it.
+func (p *Provider) remember(req providers.MessageRequest, sessionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if sessionID == "" {
+		p.session = session{}
+		return
+	}
+
+	p.session = session{
+		id:     sessionID,
+		sent:   len(req.Messages),
+		digest: digestMessages(req.Messages),
+	}
+}
+
+// digestMessages returns a hash identifying a message history.
+func digestMessages(msgs []providers.Message) string {
+	data, _ := json.Marshal(msgs)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionNotFoundMarker is printed by the claude CLI when --resume names a
+// session it does not have.
+const sessionNotFoundMarker = "No conversation found with session ID"
+
+// errSessionNotFound is returned when the session to resume no longer exists.
+// Only this failure falls back to the full history, since rerunning it after
+// any other failure could repeat the CLI's file writes and commands.
+var errSessionNotFound = errors.New("claude session not found")
+
+// withResume adds the --resume flag to args if a session ID is given.
+func withResume(args []string, sessionID string) []string {
+	if sessionID == "" {
+		return args
+	}
+	return append([]string{"--resume", sessionID}, args...)
 }
 
 // buildPrompt constructs a prompt string from the message request.
@@ -214,6 +448,9 @@ func (p *Provider) buildPrompt(req providers.MessageRequest) string {
 				if block.Type == "text" {
 					parts = append(parts, block.Text)
 				}
+				if block.IsMedia() && block.Path != "" {
+					parts = append(parts, providers.MediaReference(block))
+				}
 			}
 		}
 	}
@@ -221,6 +458,23 @@ func (p *Provider) buildPrompt(req providers.MessageRequest) string {
 	return strings.Join(parts, "\n\n")
 }
 
+// mediaDirs returns the directories containing attached media files.
+func mediaDirs(req providers.MessageRequest) []string {
+	var dirs []string
+	for _, msg := range req.Messages {
+		for _, block := range msg.Content {
+			if !block.IsMedia() || block.Path == "" {
+				continue
+			}
+			dir, err := filepath.Abs(filepath.Dir(block.Path))
+			if err == nil && !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
 // buildArgs constructs command line arguments for non-streaming mode.
 func (p *Provider) buildArgs(req providers.MessageRequest, prompt string) []string {
 	args := []string{"--print", "--output-format", "json"}
@@ -258,6 +512,11 @@ func (p *Provider) buildArgs(req providers.MessageRequest, prompt string) []stri
 		args = append(args, "--permission-mode", p.config.PermissionMode)
 	}
 
+	// Allow the CLI to read attached images and documents
+	for _, dir := range mediaDirs(req) {
+		args = append(args, "--add-dir", dir)
+	}
+
 	// Add -- separator and prompt as positional argument
 	args = append(args, "--", prompt)
 
@@ -301,56 +560,132 @@ func (p *Provider) buildStreamArgs(req providers.MessageRequest, prompt string)
 		args = append(args, "--permission-mode", p.config.PermissionMode)
 	}
 
+	// Allow the CLI to read attached images and documents
+	for _, dir := range mediaDirs(req) {
+		args = append(args, "--add-dir", dir)
+	}
+
 	// Add -- separator and prompt as positional argument
 	args = append(args, "--", prompt)
 
 	return args
 }
 
-// parseJSONOutput parses the JSON output from claude --print --output-format json
-func (p *Provider) parseJSONOutput(output []byte) (*providers.MessageResponse, error) {
+// apiStatusPattern matches the API status in a CLI error result, e.g.
+// "API Error: 529 {...overloaded_error...}".
+var apiStatusPattern = regexp.MustCompile(`API Error: (\d{3})\b`)
+
+// ResultError is an error result reported by the claude CLI, such as an API
+// error it gave up on. It implements providers.HTTPError, so wrappers such
+// as providers/retry can classify the API status it names.
+type ResultError struct {
+	// Subtype is the result subtype (e.g. "error_during_execution")
+	Subtype string
+
+	// Message is the result text
+	Message string
+
+	// StatusCode is the API status named in Message (0 if none)
+	StatusCode int
+}
+
+// newResultError creates a ResultError from a CLI result.
+func newResultError(subtype, message string) *ResultError {
+	e := &ResultError{Subtype: subtype, Message: message}
+	if m := apiStatusPattern.FindStringSubmatch(message); m != nil {
+		e.StatusCode, _ = strconv.Atoi(m[1])
+	}
+	return e
+}
+
+// Error implements the error interface.
+func (e *ResultError) Error() string {
+	if e.Subtype != "" {
+		return fmt.Sprintf("claude error (%s): %s", e.Subtype, e.Message)
+	}
+	return "claude error: " + e.Message
+}
+
+// HTTPStatus implements providers.HTTPError.
+func (e *ResultError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// RetryAfterHint implements providers.HTTPError. The CLI does not report one.
+func (e *ResultError) RetryAfterHint() time.Duration {
+	return 0
+}
+
+// parseJSONOutput parses the JSON output from claude --print --output-format json.
+// It also returns the CLI session ID. An error result is returned as a
+// *ResultError.
+func (p *Provider) parseJSONOutput(output []byte) (*providers.MessageResponse, string, error) {
 	var result jsonResult
 	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse claude output: %w", err)
+		return nil, "", fmt.Errorf("failed to parse claude output: %w", err)
 	}
 
 	resp := &providers.MessageResponse{
 		StopReason: providers.StopReasonEndTurn,
+		Usage:      result.Usage.toUsage(result.TotalCostUSD),
 	}
 
 	if result.IsError {
-		resp.Content = []providers.ContentBlock{
-			{Type: "text", Text: fmt.Sprintf("Error: %s", result.Result)},
-		}
-	} else if result.Result != "" {
+		return nil, result.SessionID, newResultError(result.Subtype, result.Result)
+	}
+	if result.Result != "" {
 		resp.Content = []providers.ContentBlock{
 			{Type: "text", Text: result.Result},
 		}
 	}
 
-	return resp, nil
+	return resp, result.SessionID, nil
 }
 
 // jsonResult represents the JSON output from claude --output-format json
 type jsonResult struct {
-	Type      string `json:"type"`
-	Subtype   string `json:"subtype"`
-	IsError   bool   `json:"is_error"`
-	Result    string `json:"result"`
-	SessionID string `json:"session_id"`
-	NumTurns  int    `json:"num_turns"`
+	Type         string    `json:"type"`
+	Subtype      string    `json:"subtype"`
+	IsError      bool      `json:"is_error"`
+	Result       string    `json:"result"`
+	SessionID    string    `json:"session_id"`
+	NumTurns     int       `json:"num_turns"`
+	TotalCostUSD float64   `json:"total_cost_usd"`
+	Usage        jsonUsage `json:"usage"`
+}
+
+// jsonUsage represents the usage field in a result
+type jsonUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// toUsage converts CLI usage and reported cost to provider usage
+func (u jsonUsage) toUsage(costUSD float64) providers.Usage {
+	return providers.Usage{
+		InputTokens:              u.InputTokens,
+		OutputTokens:             u.OutputTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens,
+		CostUSD:                  costUSD,
+	}
 }
 
 // streamEvent represents a single event from stream-json output
 type streamEvent struct {
-	Type    string         `json:"type"`
-	Subtype string         `json:"subtype,omitempty"`
-	Message *streamMessage `json:"message,omitempty"`
-	Result  string         `json:"result,omitempty"`
-	IsError bool           `json:"is_error,omitempty"`
+	Type         string         `json:"type"`
+	Subtype      string         `json:"subtype,omitempty"`
+	SessionID    string         `json:"session_id,omitempty"`
+	Message      *streamMessage `json:"message,omitempty"`
+	Result       string         `json:"result,omitempty"`
+	IsError      bool           `json:"is_error,omitempty"`
+	TotalCostUSD float64        `json:"total_cost_usd,omitempty"`
+	Usage        jsonUsage      `json:"usage,omitempty"`
 }
 
-// streamMessage represents the message field in an assistant event
+// streamMessage represents the message field in an assistant or user event
 type streamMessage struct {
 	Role    string               `json:"role"`
 	Content []streamContentBlock `json:"content"`
@@ -358,11 +693,65 @@ type streamMessage struct {
 
 // streamContentBlock represents a content block in the stream
 type streamContentBlock struct {
-	Type  string          `json:"type"`
-	Text  string          `json:"text,omitempty"`
-	ID    string          `json:"id,omitempty"`
-	Name  string          `json:"name,omitempty"`
-	Input json.RawMessage `json:"input,omitempty"`
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// contentBlocks converts the text, tool_use and tool_result blocks of an
+// assistant or user event. Claude Code reports its own tool results as user
+// events, so the blocks of both appear in the order the tools ran.
+func (e *streamEvent) contentBlocks() []providers.ContentBlock {
+	if e.Message == nil {
+		return nil
+	}
+
+	var blocks []providers.ContentBlock
+	for _, block := range e.Message.Content {
+		switch block.Type {
+		case "text":
+			if e.Type == "assistant" {
+				blocks = append(blocks, providers.ContentBlock{Type: "text", Text: block.Text})
+			}
+		case "tool_use":
+			blocks = append(blocks, providers.ContentBlock{
+				Type:  "tool_use",
+				ID:    block.ID,
+				Name:  block.Name,
+				Input: block.Input,
+			})
+		case "tool_result":
+			blocks = append(blocks, providers.NewToolResult(block.ToolUseID, toolResultText(block.Content), block.IsError))
+		}
+	}
+	return blocks
+}
+
+// toolResultText returns the text of tool_result content, which is either
+// a string or a list of content blocks.
+func toolResultText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var parts []streamContentBlock
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return string(raw)
+	}
+
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
 }
 
 // parseStreamEvent parses a single line of stream-json output
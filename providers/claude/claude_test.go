@@ -2,8 +2,10 @@ package claude
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/lex00/wetwire-core-go/providers"
@@ -111,6 +113,16 @@ func TestBuildPromptFromMessages(t *testing.T) {
 			},
 			expected: "Create an S3 bucket\n\nwith versioning enabled",
 		},
+		{
+			name: "with attached image",
+			messages: []providers.Message{
+				{Role: "user", Content: []providers.ContentBlock{
+					{Type: "text", Text: "Build what's in this diagram"},
+					{Type: "image", MediaType: "image/png", Path: "/tmp/diagram.png"},
+				}},
+			},
+			expected: "Build what's in this diagram\n\n[Attached image: /tmp/diagram.png]",
+		},
 		{
 			name:     "empty messages",
 			messages: []providers.Message{},
@@ -191,6 +203,19 @@ func TestBuildArgs(t *testing.T) {
 				"--permission-mode", "acceptEdits",
 			},
 		},
+		{
+			name:   "with attached media",
+			config: Config{},
+			req: providers.MessageRequest{Messages: []providers.Message{
+				{Role: "user", Content: []providers.ContentBlock{
+					{Type: "document", MediaType: "application/pdf", Path: "/scenarios/s3/prompts/runbook.pdf"},
+				}},
+			}},
+			prompt: "hello",
+			contains: []string{
+				"--add-dir", "/scenarios/s3/prompts",
+			},
+		},
 		{
 			name: "with MCP config",
 			config: Config{
@@ -247,10 +272,9 @@ func TestParseJSONOutput(t *testing.T) {
 			wantErr:  false,
 		},
 		{
-			name:     "error result",
-			output:   `{"type":"result","subtype":"error","is_error":true,"result":"Something went wrong","session_id":"abc123"}`,
-			wantText: "Error: Something went wrong",
-			wantErr:  false,
+			name:    "error result",
+			output:  `{"type":"result","subtype":"error","is_error":true,"result":"Something went wrong","session_id":"abc123"}`,
+			wantErr: true,
 		},
 		{
 			name:     "empty result",
@@ -267,7 +291,7 @@ func TestParseJSONOutput(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := p.parseJSONOutput([]byte(tc.output))
+			result, _, err := p.parseJSONOutput([]byte(tc.output))
 			if tc.wantErr {
 				assert.Error(t, err)
 				return
@@ -282,6 +306,53 @@ func TestParseJSONOutput(t *testing.T) {
 	}
 }
 
+func TestParseJSONOutputErrorResult(t *testing.T) {
+	p := &Provider{}
+
+	_, sessionID, err := p.parseJSONOutput([]byte(`{"type":"result","subtype":"error_during_execution","is_error":true,"result":"API Error: 529 {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\"}}","session_id":"abc123"}`))
+	assert.Equal(t, "abc123", sessionID)
+
+	var resultErr *ResultError
+	require.ErrorAs(t, err, &resultErr)
+	assert.Equal(t, "error_during_execution", resultErr.Subtype)
+	assert.Equal(t, 529, resultErr.HTTPStatus())
+
+	var httpErr providers.HTTPError
+	assert.ErrorAs(t, err, &httpErr)
+
+	// Results without an API status have none
+	assert.Equal(t, 0, newResultError("error_max_turns", "Reached the maximum number of turns").HTTPStatus())
+}
+
+func TestParseJSONOutputUsage(t *testing.T) {
+	p := &Provider{}
+
+	output := `{"type":"result","subtype":"success","result":"Done","total_cost_usd":0.0421,` +
+		`"usage":{"input_tokens":12,"output_tokens":340,"cache_creation_input_tokens":2000,"cache_read_input_tokens":15000}}`
+
+	result, _, err := p.parseJSONOutput([]byte(output))
+	require.NoError(t, err)
+	assert.Equal(t, providers.Usage{
+		InputTokens:              12,
+		OutputTokens:             340,
+		CacheCreationInputTokens: 2000,
+		CacheReadInputTokens:     15000,
+		CostUSD:                  0.0421,
+	}, result.Usage)
+}
+
+func TestParseStreamEventUsage(t *testing.T) {
+	line := `{"type":"result","subtype":"success","result":"Done","total_cost_usd":0.01,"usage":{"input_tokens":5,"output_tokens":50}}`
+
+	event, err := parseStreamEvent(line)
+	require.NoError(t, err)
+
+	usage := event.Usage.toUsage(event.TotalCostUSD)
+	assert.Equal(t, 5, usage.InputTokens)
+	assert.Equal(t, 50, usage.OutputTokens)
+	assert.Equal(t, 0.01, usage.CostUSD)
+}
+
 func TestParseStreamEvent(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -427,3 +498,251 @@ func joinArgs(args []string) string {
 	}
 	return result
 }
+
+func TestParseJSONOutputSessionID(t *testing.T) {
+	p := &Provider{}
+
+	_, sessionID, err := p.parseJSONOutput([]byte(`{"type":"result","result":"Done","session_id":"abc-123"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "abc-123", sessionID)
+
+	event, err := parseStreamEvent(`{"type":"system","subtype":"init","session_id":"abc-123"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "abc-123", event.SessionID)
+}
+
+func TestConversationResumesSession(t *testing.T) {
+	p := &Provider{}
+
+	first := providers.MessageRequest{Messages: []providers.Message{
+		providers.NewUserMessage("Create an S3 bucket"),
+	}}
+
+	// No session yet: the full history is sent
+	prompt, resumeID := p.conversation(first, first)
+	assert.Equal(t, "Create an S3 bucket", prompt)
+	assert.Empty(t, resumeID)
+
+	p.remember(first, "session-1")
+	assert.Equal(t, "session-1", p.SessionID())
+
+	// Continuing the conversation sends only the new user input
+	next := providers.MessageRequest{Messages: append(first.Messages,
+		providers.NewAssistantMessage([]providers.ContentBlock{{Type: "text", Text: "Which region?"}}),
+		providers.NewUserMessage("us-east-1"),
+	)}
+	prompt, resumeID = p.conversation(next, next)
+	assert.Equal(t, "us-east-1", prompt)
+	assert.Equal(t, "session-1", resumeID)
+
+	// A diverged history starts a new session
+	diverged := providers.MessageRequest{Messages: []providers.Message{
+		providers.NewUserMessage("Create a Lambda function"),
+		providers.NewUserMessage("in Go"),
+	}}
+	prompt, resumeID = p.conversation(diverged, diverged)
+	assert.Equal(t, "Create a Lambda function\n\nin Go", prompt)
+	assert.Empty(t, resumeID)
+
+	// Reset forgets the session
+	p.Reset()
+	assert.Empty(t, p.SessionID())
+	_, resumeID = p.conversation(next, next)
+	assert.Empty(t, resumeID)
+}
+
+func TestConversationDisableResume(t *testing.T) {
+	p := &Provider{config: Config{DisableResume: true}}
+
+	first := providers.MessageRequest{Messages: []providers.Message{providers.NewUserMessage("hello")}}
+	p.remember(first, "session-1")
+
+	next := providers.MessageRequest{Messages: append(first.Messages, providers.NewUserMessage("again"))}
+	prompt, resumeID := p.conversation(next, next)
+	assert.Equal(t, "hello\n\nagain", prompt)
+	assert.Empty(t, resumeID)
+}
+
+func TestConversationResumeSavedSession(t *testing.T) {
+	p := &Provider{}
+
+	history := []providers.Message{providers.NewUserMessage("Create an S3 bucket")}
+	p.Resume("session-1", history)
+	assert.Equal(t, "session-1", p.SessionID())
+
+	next := providers.MessageRequest{Messages: append(history, providers.NewUserMessage("Continue"))}
+	prompt, resumeID := p.conversation(next, next)
+	assert.Equal(t, "Continue", prompt)
+	assert.Equal(t, "session-1", resumeID)
+}
+
+func TestWithResume(t *testing.T) {
+	args := []string{"--print", "--", "hello"}
+	assert.Equal(t, args, withResume(args, ""))
+	assert.Equal(t, []string{"--resume", "session-1", "--print", "--", "hello"}, withResume(args, "session-1"))
+}
+
+func TestStreamEventContentBlocks(t *testing.T) {
+	t.Run("assistant text and tool use", func(t *testing.T) {
+		event, err := parseStreamEvent(`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Writing"},{"type":"tool_use","id":"toolu_1","name":"Write","input":{"file_path":"main.go"}}]}}`)
+		require.NoError(t, err)
+
+		blocks := event.contentBlocks()
+		require.Len(t, blocks, 2)
+		assert.Equal(t, providers.ContentBlock{Type: "text", Text: "Writing"}, blocks[0])
+		assert.Equal(t, "tool_use", blocks[1].Type)
+		assert.Equal(t, "toolu_1", blocks[1].ID)
+		assert.Equal(t, "Write", blocks[1].Name)
+		assert.JSONEq(t, `{"file_path":"main.go"}`, string(blocks[1].Input))
+	})
+
+	t.Run("user tool result with string content", func(t *testing.T) {
+		event, err := parseStreamEvent(`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"File created"}]}}`)
+		require.NoError(t, err)
+
+		blocks := event.contentBlocks()
+		require.Len(t, blocks, 1)
+		assert.Equal(t, providers.NewToolResult("toolu_1", "File created", false), blocks[0])
+	})
+
+	t.Run("user tool result with block content", func(t *testing.T) {
+		event, err := parseStreamEvent(`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","is_error":true,"content":[{"type":"text","text":"line 1"},{"type":"text","text":"line 2"}]}]}}`)
+		require.NoError(t, err)
+
+		blocks := event.contentBlocks()
+		require.Len(t, blocks, 1)
+		assert.Equal(t, providers.NewToolResult("toolu_2", "line 1\nline 2", true), blocks[0])
+	})
+
+	t.Run("events without messages", func(t *testing.T) {
+		event, err := parseStreamEvent(`{"type":"system","subtype":"init","session_id":"abc123"}`)
+		require.NoError(t, err)
+		assert.Empty(t, event.contentBlocks())
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	p, err := New(Config{Model: "haiku"})
+	require.NoError(t, err)
+
+	caps := p.Capabilities("opus")
+	assert.True(t, caps.Agentic)
+	assert.False(t, caps.ToolCalling)
+	assert.True(t, caps.Streaming)
+	assert.True(t, caps.SystemPrompt)
+	assert.Equal(t, 64000, caps.MaxOutputTokens)
+
+	// Request tools are ignored, but Claude Code runs its own tools
+	assert.NoError(t, caps.Check(providers.MessageRequest{Tools: []providers.Tool{{Name: "run_lint"}}}))
+}
+
+func TestConversationResumesWithInlineMedia(t *testing.T) {
+	p := &Provider{}
+
+	image := providers.ContentBlock{Type: "image", MediaType: "image/png", Base64: "iVBORw0KGgo="}
+	first := providers.MessageRequest{Messages: []providers.Message{
+		{Role: "user", Content: []providers.ContentBlock{{Type: "text", Text: "Describe the diagram"}, image}},
+	}}
+	p.remember(first, "session-1")
+
+	// Media is spilled to new files on every call, but the session still matches
+	next := providers.MessageRequest{Messages: append(first.Messages,
+		providers.NewAssistantMessage([]providers.ContentBlock{{Type: "text", Text: "A VPC"}}),
+		providers.NewUserMessage("Build it"),
+	)}
+	spilled, cleanup, err := providers.SpillMedia(next, t.TempDir())
+	require.NoError(t, err)
+	defer cleanup()
+
+	prompt, resumeID := p.conversation(next, spilled)
+	assert.Equal(t, "Build it", prompt)
+	assert.Equal(t, "session-1", resumeID)
+}
+
+// fakeClaude installs a claude script on PATH that fails with resumeOutput
+// when asked to resume a session and otherwise prints a result. It returns
+// a file with a line per invocation holding its first argument.
+func fakeClaude(t *testing.T, resumeOutput string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$1" >> %q
+case "$*" in
+*--resume*) echo %q >&2; exit 1 ;;
+esac
+echo '{"type":"result","subtype":"success","result":"fresh","session_id":"session-2"}'
+`, log, resumeOutput)
+	installClaude(t, dir, script)
+	return log
+}
+
+// installClaude writes script as the claude CLI in dir and puts dir on PATH.
+func installClaude(t *testing.T, dir, script string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCreateMessageResumeFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell")
+	}
+
+	first := []providers.Message{providers.NewUserMessage("Create an S3 bucket")}
+	next := providers.MessageRequest{Messages: append(first,
+		providers.NewAssistantMessage([]providers.ContentBlock{{Type: "text", Text: "Which region?"}}),
+		providers.NewUserMessage("us-east-1"),
+	)}
+
+	t.Run("session not found", func(t *testing.T) {
+		log := fakeClaude(t, "No conversation found with session ID: session-1")
+		p := &Provider{config: Config{WorkDir: t.TempDir()}}
+		p.Resume("session-1", first)
+
+		resp, err := p.CreateMessage(context.Background(), next)
+		require.NoError(t, err)
+		assert.Equal(t, "fresh", resp.Content[0].Text)
+		assert.Equal(t, "session-2", p.SessionID())
+
+		calls, err := os.ReadFile(log)
+		require.NoError(t, err)
+		assert.Equal(t, "--resume\n--print\n", string(calls))
+	})
+
+	t.Run("other failure", func(t *testing.T) {
+		log := fakeClaude(t, "API Error: 500 Internal server error")
+		p := &Provider{config: Config{WorkDir: t.TempDir()}}
+		p.Resume("session-1", first)
+
+		_, err := p.CreateMessage(context.Background(), next)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Internal server error")
+
+		// The resumed call is not repeated with the full history
+		calls, err := os.ReadFile(log)
+		require.NoError(t, err)
+		assert.Equal(t, "--resume\n", string(calls))
+	})
+}
+
+func TestCreateMessageErrorResult(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell")
+	}
+	installClaude(t, t.TempDir(), `#!/bin/sh
+echo '{"type":"result","subtype":"error_during_execution","is_error":true,"result":"API Error: 429 rate_limit_error","session_id":"session-1"}'
+exit 1
+`)
+
+	p := &Provider{config: Config{WorkDir: t.TempDir()}}
+	_, err := p.CreateMessage(context.Background(), providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("hello")},
+	})
+
+	var resultErr *ResultError
+	require.ErrorAs(t, err, &resultErr)
+	assert.Equal(t, 429, resultErr.StatusCode)
+}
@@ -0,0 +1,35 @@
+package providers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPError is implemented by provider errors that carry an HTTP status, so
+// that wrappers can classify them without depending on a concrete provider.
+type HTTPError interface {
+	error
+
+	// HTTPStatus returns the HTTP status code of the failed response.
+	HTTPStatus() int
+
+	// RetryAfterHint returns the delay requested by the server (zero if none).
+	RetryAfterHint() time.Duration
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
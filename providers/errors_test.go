@@ -0,0 +1,24 @@
+package providers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), ParseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("soon"))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("-1"))
+	assert.Equal(t, 2*time.Second, ParseRetryAfter("2"))
+	assert.Equal(t, 1500*time.Millisecond, ParseRetryAfter("1.5"))
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	d := ParseRetryAfter(date)
+	assert.True(t, d > 55*time.Second && d <= time.Minute, "got %v", d)
+
+	past := time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)
+	assert.Equal(t, time.Duration(0), ParseRetryAfter(past))
+}
@@ -0,0 +1,440 @@
+// Package fake provides a scriptable Provider for unit-testing agent loops.
+//
+// A fake provider serves a scripted sequence of model turns and records every
+// request it receives, so tests can drive an agent through tool calls without
+// a real backend and then assert on what the agent sent.
+//
+// Usage:
+//
+//	p := fake.New().
+//		ExpectTools("write_file", "run_lint").
+//		CallTool("write_file", map[string]any{"path": "main.go", "content": "package main"}).
+//		ExpectToolResult("Wrote").
+//		CallTool("run_lint", map[string]any{"path": "."}).
+//		ReplyText("Done")
+//
+//	agent, _ := agents.NewAgent(agents.AgentConfig{Provider: p, ...})
+//	err := agent.Run(ctx, "create a bucket")
+//
+//	results := p.ToolResults()
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// ErrExhausted is returned when the agent requests more turns than were scripted.
+var ErrExhausted = errors.New("fake provider: script exhausted")
+
+// ErrExpectation is returned when a request does not satisfy an expectation.
+var ErrExpectation = errors.New("fake provider: expectation failed")
+
+// Expectation checks a request before the scripted turn is served.
+// A non-nil error fails the call.
+type Expectation func(req providers.MessageRequest) error
+
+// ToolCall describes a tool call the fake model should make.
+type ToolCall struct {
+	// Name of the tool to call
+	Name string
+
+	// Args are the tool arguments, encoded as JSON input
+	Args map[string]any
+}
+
+// Call is a shorthand for constructing a ToolCall.
+func Call(name string, args map[string]any) ToolCall {
+	return ToolCall{Name: name, Args: args}
+}
+
+// Turn is a single scripted model turn.
+type Turn struct {
+	// Response is returned to the agent (ignored if Err is set)
+	Response *providers.MessageResponse
+
+	// Err is returned instead of a response
+	Err error
+
+	// Expectations are checked against the request for this turn
+	Expectations []Expectation
+}
+
+// Exchange is a recorded request and the result that was served for it.
+type Exchange struct {
+	// Request is the request the agent sent
+	Request providers.MessageRequest
+
+	// Response is the response that was served (nil on error)
+	Response *providers.MessageResponse
+
+	// Err is the error that was served, if any
+	Err error
+
+	// Streamed is true if the request was made via StreamMessage
+	Streamed bool
+}
+
+// Provider is a scriptable implementation of providers.Provider.
+// All builder methods return the provider so calls can be chained.
+type Provider struct {
+	mu         sync.Mutex
+	name       string
+	turns      []Turn
+	pending    []Expectation
+	failOn     map[int]error
+	next       int
+	nextID     int
+	transcript []Exchange
+	caps       *providers.Capabilities
+}
+
+// New creates an empty fake provider.
+func New() *Provider {
+	return &Provider{
+		name:   "fake",
+		failOn: make(map[int]error),
+	}
+}
+
+// WithName sets the name returned by Name.
+func (p *Provider) WithName(name string) *Provider {
+	p.name = name
+	return p
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// WithCapabilities sets the capabilities returned by Capabilities.
+func (p *Provider) WithCapabilities(caps providers.Capabilities) *Provider {
+	p.caps = &caps
+	return p
+}
+
+// Capabilities returns the capabilities set with WithCapabilities, or
+// those of a raw model API supporting everything with unknown limits.
+func (p *Provider) Capabilities(model string) providers.Capabilities {
+	if p.caps != nil {
+		return *p.caps
+	}
+	return providers.Capabilities{
+		ToolCalling:  true,
+		Streaming:    true,
+		SystemPrompt: true,
+		Multimodal:   true,
+	}
+}
+
+// ReplyText scripts a turn that replies with text and ends the turn.
+func (p *Provider) ReplyText(text string) *Provider {
+	return p.Respond(&providers.MessageResponse{
+		Content:    []providers.ContentBlock{{Type: "text", Text: text}},
+		StopReason: providers.StopReasonEndTurn,
+	})
+}
+
+// CallTool scripts a turn that calls a single tool.
+func (p *Provider) CallTool(name string, args map[string]any) *Provider {
+	return p.CallTools(Call(name, args))
+}
+
+// CallTools scripts a turn that calls several tools at once.
+func (p *Provider) CallTools(calls ...ToolCall) *Provider {
+	return p.CallToolsWithText("", calls...)
+}
+
+// CallToolsWithText scripts a turn with leading text followed by tool calls.
+func (p *Provider) CallToolsWithText(text string, calls ...ToolCall) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp := &providers.MessageResponse{StopReason: providers.StopReasonToolUse}
+	if text != "" {
+		resp.Content = append(resp.Content, providers.ContentBlock{Type: "text", Text: text})
+	}
+
+	for _, call := range calls {
+		args := call.Args
+		if args == nil {
+			args = map[string]any{}
+		}
+		input, err := json.Marshal(args)
+		if err != nil {
+			panic(fmt.Sprintf("fake provider: cannot encode args for %s: %v", call.Name, err))
+		}
+
+		p.nextID++
+		resp.Content = append(resp.Content, providers.ContentBlock{
+			Type:  "tool_use",
+			ID:    fmt.Sprintf("toolu_fake_%d", p.nextID),
+			Name:  call.Name,
+			Input: input,
+		})
+	}
+
+	p.addTurn(Turn{Response: resp})
+	return p
+}
+
+// StopMaxTokens scripts a turn that is truncated by the token limit.
+func (p *Provider) StopMaxTokens(text string) *Provider {
+	return p.Respond(&providers.MessageResponse{
+		Content:    []providers.ContentBlock{{Type: "text", Text: text}},
+		StopReason: providers.StopReasonMaxTokens,
+	})
+}
+
+// Fail scripts a turn that returns an error.
+func (p *Provider) Fail(err error) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.addTurn(Turn{Err: err})
+	return p
+}
+
+// FailOnTurn makes the given call (1-based) return err without consuming
+// a scripted turn. This is useful for injecting transient failures that a
+// retrying caller is expected to recover from.
+func (p *Provider) FailOnTurn(n int, err error) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.failOn[n] = err
+	return p
+}
+
+// Respond scripts a turn that returns the given response verbatim.
+func (p *Provider) Respond(resp *providers.MessageResponse) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.addTurn(Turn{Response: resp})
+	return p
+}
+
+// Expect adds an expectation that is checked against the request for the
+// next scripted turn.
+func (p *Provider) Expect(e Expectation) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending = append(p.pending, e)
+	return p
+}
+
+// ExpectSystem expects the next request's system prompt to contain substr.
+func (p *Provider) ExpectSystem(substr string) *Provider {
+	return p.Expect(func(req providers.MessageRequest) error {
+		if !strings.Contains(req.System, substr) {
+			return fmt.Errorf("system prompt does not contain %q", substr)
+		}
+		return nil
+	})
+}
+
+// ExpectTools expects the next request to offer exactly the named tools, in any order.
+func (p *Provider) ExpectTools(names ...string) *Provider {
+	want := slices.Clone(names)
+	slices.Sort(want)
+
+	return p.Expect(func(req providers.MessageRequest) error {
+		got := ToolNames(req)
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			return fmt.Errorf("tools = %v, want %v", got, want)
+		}
+		return nil
+	})
+}
+
+// ExpectToolResult expects the last message of the next request to contain a
+// tool_result whose content contains substr.
+func (p *Provider) ExpectToolResult(substr string) *Provider {
+	return p.Expect(func(req providers.MessageRequest) error {
+		results := lastToolResults(req)
+		for _, r := range results {
+			if strings.Contains(r.Content, substr) {
+				return nil
+			}
+		}
+		contents := make([]string, len(results))
+		for i, r := range results {
+			contents[i] = r.Content
+		}
+		return fmt.Errorf("no tool_result containing %q in %q", substr, contents)
+	})
+}
+
+// addTurn appends a turn, attaching pending expectations. Caller holds p.mu.
+func (p *Provider) addTurn(turn Turn) {
+	turn.Expectations = append(turn.Expectations, p.pending...)
+	p.pending = nil
+	p.turns = append(p.turns, turn)
+}
+
+// CreateMessage serves the next scripted turn.
+func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageRequest) (*providers.MessageResponse, error) {
+	return p.serve(ctx, req, false)
+}
+
+// StreamMessage serves the next scripted turn, delivering text blocks through the handler.
+func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
+	resp, err := p.serve(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if handler != nil {
+		for _, block := range resp.Content {
+			if block.Type == "text" && block.Text != "" {
+				handler(block.Text)
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// serve records the request and returns the next scripted result.
+func (p *Provider) serve(ctx context.Context, req providers.MessageRequest, streamed bool) (*providers.MessageResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	exchange := Exchange{Request: req, Streamed: streamed}
+	defer func() { p.transcript = append(p.transcript, exchange) }()
+
+	if err := ctx.Err(); err != nil {
+		exchange.Err = err
+		return nil, err
+	}
+
+	call := len(p.transcript) + 1
+	if err, ok := p.failOn[call]; ok {
+		exchange.Err = err
+		return nil, err
+	}
+
+	if p.next >= len(p.turns) {
+		exchange.Err = fmt.Errorf("%w: call %d but only %d turns scripted", ErrExhausted, call, len(p.turns))
+		return nil, exchange.Err
+	}
+
+	turn := p.turns[p.next]
+	p.next++
+
+	for _, e := range turn.Expectations {
+		if err := e(req); err != nil {
+			exchange.Err = fmt.Errorf("%w on turn %d: %v", ErrExpectation, p.next, err)
+			return nil, exchange.Err
+		}
+	}
+
+	if turn.Err != nil {
+		exchange.Err = turn.Err
+		return nil, turn.Err
+	}
+
+	exchange.Response = turn.Response
+	return turn.Response, nil
+}
+
+// Transcript returns every request received and the result served for it.
+func (p *Provider) Transcript() []Exchange {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.transcript)
+}
+
+// Requests returns every request received, in order.
+func (p *Provider) Requests() []providers.MessageRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	reqs := make([]providers.MessageRequest, len(p.transcript))
+	for i, e := range p.transcript {
+		reqs[i] = e.Request
+	}
+	return reqs
+}
+
+// LastRequest returns the most recent request, or false if none was received.
+func (p *Provider) LastRequest() (providers.MessageRequest, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.transcript) == 0 {
+		return providers.MessageRequest{}, false
+	}
+	return p.transcript[len(p.transcript)-1].Request, true
+}
+
+// ToolResults returns every tool_result block the agent sent, in order.
+// Results repeated in later requests' history are only returned once.
+func (p *Provider) ToolResults() []providers.ContentBlock {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var results []providers.ContentBlock
+	for _, e := range p.transcript {
+		results = append(results, lastToolResults(e.Request)...)
+	}
+	return results
+}
+
+// Remaining returns the number of scripted turns not yet served.
+func (p *Provider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.turns) - p.next
+}
+
+// Err returns the first expectation failure or exhaustion error that was
+// served, or nil. Tests can check it after a run whose own error was swallowed.
+func (p *Provider) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.transcript {
+		if errors.Is(e.Err, ErrExpectation) || errors.Is(e.Err, ErrExhausted) {
+			return e.Err
+		}
+	}
+	return nil
+}
+
+// ToolNames returns the names of the tools offered in a request.
+func ToolNames(req providers.MessageRequest) []string {
+	names := make([]string, len(req.Tools))
+	for i, t := range req.Tools {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// lastToolResults returns the tool_result blocks in the request's final message.
+func lastToolResults(req providers.MessageRequest) []providers.ContentBlock {
+	if len(req.Messages) == 0 {
+		return nil
+	}
+
+	var results []providers.ContentBlock
+	for _, block := range req.Messages[len(req.Messages)-1].Content {
+		if block.Type == "tool_result" {
+			results = append(results, block)
+		}
+	}
+	return results
+}
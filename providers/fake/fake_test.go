@@ -0,0 +1,183 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/lex00/wetwire-core-go/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderImplementsInterface(t *testing.T) {
+	var _ providers.Provider = (*Provider)(nil)
+}
+
+func TestProviderName(t *testing.T) {
+	assert.Equal(t, "fake", New().Name())
+	assert.Equal(t, "anthropic", New().WithName("anthropic").Name())
+}
+
+func TestScriptedTurns(t *testing.T) {
+	p := New().
+		CallTools(
+			Call("write_file", map[string]any{"path": "main.go"}),
+			Call("run_lint", nil),
+		).
+		StopMaxTokens("partial").
+		ReplyText("Done")
+
+	ctx := context.Background()
+	req := providers.MessageRequest{Messages: []providers.Message{providers.NewUserMessage("go")}}
+
+	resp, err := p.CreateMessage(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, providers.StopReasonToolUse, resp.StopReason)
+	require.Len(t, resp.Content, 2)
+	assert.Equal(t, "write_file", resp.Content[0].Name)
+	assert.Equal(t, "toolu_fake_1", resp.Content[0].ID)
+	assert.JSONEq(t, `{"path":"main.go"}`, string(resp.Content[0].Input))
+	assert.Equal(t, "toolu_fake_2", resp.Content[1].ID)
+	assert.JSONEq(t, `{}`, string(resp.Content[1].Input))
+
+	resp, err = p.CreateMessage(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, providers.StopReasonMaxTokens, resp.StopReason)
+
+	var streamed []string
+	resp, err = p.StreamMessage(ctx, req, func(text string) { streamed = append(streamed, text) })
+	require.NoError(t, err)
+	assert.Equal(t, providers.StopReasonEndTurn, resp.StopReason)
+	assert.Equal(t, []string{"Done"}, streamed)
+
+	assert.Equal(t, 0, p.Remaining())
+	assert.Len(t, p.Transcript(), 3)
+	assert.True(t, p.Transcript()[2].Streamed)
+}
+
+func TestScriptExhausted(t *testing.T) {
+	p := New().ReplyText("only")
+
+	_, err := p.CreateMessage(context.Background(), providers.MessageRequest{})
+	require.NoError(t, err)
+
+	_, err = p.CreateMessage(context.Background(), providers.MessageRequest{})
+	require.ErrorIs(t, err, ErrExhausted)
+	assert.ErrorIs(t, p.Err(), ErrExhausted)
+}
+
+func TestFailAndFailOnTurn(t *testing.T) {
+	boom := errors.New("boom")
+	blip := errors.New("blip")
+
+	p := New().
+		ReplyText("first").
+		Fail(boom).
+		FailOnTurn(2, blip)
+
+	ctx := context.Background()
+
+	_, err := p.CreateMessage(ctx, providers.MessageRequest{})
+	require.NoError(t, err)
+
+	// Turn 2 fails without consuming the scripted Fail turn
+	_, err = p.CreateMessage(ctx, providers.MessageRequest{})
+	require.ErrorIs(t, err, blip)
+	assert.Equal(t, 1, p.Remaining())
+
+	_, err = p.CreateMessage(ctx, providers.MessageRequest{})
+	require.ErrorIs(t, err, boom)
+	assert.NoError(t, p.Err())
+}
+
+func TestExpectations(t *testing.T) {
+	p := New().
+		ExpectSystem("infrastructure").
+		ExpectTools("run_lint", "write_file").
+		CallTool("run_lint", nil).
+		ExpectToolResult("no issues").
+		ReplyText("Done")
+
+	ctx := context.Background()
+	req := providers.MessageRequest{
+		System: "You generate infrastructure code",
+		Tools:  []providers.Tool{{Name: "write_file"}, {Name: "run_lint"}},
+		Messages: []providers.Message{
+			providers.NewUserMessage("lint it"),
+		},
+	}
+
+	resp, err := p.CreateMessage(ctx, req)
+	require.NoError(t, err)
+
+	req.Messages = append(req.Messages,
+		providers.NewAssistantMessage(resp.Content),
+		providers.NewToolResultMessage([]providers.ContentBlock{
+			providers.NewToolResult(resp.Content[0].ID, "lint: no issues found", false),
+		}),
+	)
+
+	_, err = p.CreateMessage(ctx, req)
+	require.NoError(t, err)
+
+	results := p.ToolResults()
+	require.Len(t, results, 1)
+	assert.Equal(t, "lint: no issues found", results[0].Content)
+
+	last, ok := p.LastRequest()
+	require.True(t, ok)
+	assert.Len(t, last.Messages, 3)
+	assert.Len(t, p.Requests(), 2)
+}
+
+func TestExpectationFailure(t *testing.T) {
+	p := New().
+		ExpectTools("write_file").
+		ReplyText("Done")
+
+	_, err := p.CreateMessage(context.Background(), providers.MessageRequest{
+		Tools: []providers.Tool{{Name: "read_file"}},
+	})
+	require.ErrorIs(t, err, ErrExpectation)
+	assert.Contains(t, err.Error(), "read_file")
+	assert.ErrorIs(t, p.Err(), ErrExpectation)
+}
+
+func TestCustomExpectation(t *testing.T) {
+	p := New().
+		Expect(func(req providers.MessageRequest) error {
+			if req.MaxTokens != 1024 {
+				return errors.New("wrong max tokens")
+			}
+			return nil
+		}).
+		ReplyText("ok")
+
+	_, err := p.CreateMessage(context.Background(), providers.MessageRequest{MaxTokens: 1024})
+	require.NoError(t, err)
+}
+
+func TestRespondVerbatim(t *testing.T) {
+	input := json.RawMessage(`{"question":"Which region?"}`)
+	p := New().Respond(&providers.MessageResponse{
+		Content:    []providers.ContentBlock{{Type: "tool_use", ID: "q1", Name: "ask_developer", Input: input}},
+		StopReason: providers.StopReasonToolUse,
+	})
+
+	resp, err := p.CreateMessage(context.Background(), providers.MessageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "q1", resp.Content[0].ID)
+}
+
+func TestCancelledContext(t *testing.T) {
+	p := New().ReplyText("unused")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := p.CreateMessage(ctx, providers.MessageRequest{})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, p.Remaining())
+}
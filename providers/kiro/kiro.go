@@ -1,10 +1,21 @@
 // Package kiro provides a Kiro CLI implementation of the Provider interface.
+//
+// The provider runs kiro-cli in non-interactive mode and reads its output
+// incrementally. Text is streamed as it arrives, and the tools the agent
+// invokes are reported as tool_use and tool_result blocks.
 package kiro
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/lex00/wetwire-core-go/kiro"
 	"github.com/lex00/wetwire-core-go/providers"
@@ -31,6 +42,14 @@ type Config struct {
 
 	// WorkDir is the working directory for the agent
 	WorkDir string
+
+	// UsePTY runs kiro-cli under a pseudo-terminal via script(1), for
+	// versions that buffer their output when not attached to a terminal
+	UsePTY bool
+
+	// EventHandler receives each text, tool_use and tool_result block as
+	// it is parsed from the output (optional)
+	EventHandler func(block providers.ContentBlock)
 }
 
 // New creates a new Kiro provider.
@@ -45,14 +64,45 @@ func (p *Provider) Name() string {
 	return "kiro"
 }
 
+// Capabilities returns what kiro-cli supports.
+// Kiro runs its own agentic loop with the configured MCP server. Its agent
+// prompt comes from Config.AgentPrompt; MessageRequest.System is sent ahead
+// of the user messages. The model is chosen by kiro-cli, so its limits are
+// unknown.
+func (p *Provider) Capabilities(model string) providers.Capabilities {
+	return providers.Capabilities{
+		Streaming:    true,
+		SystemPrompt: true,
+		Multimodal:   true,
+		Agentic:      true,
+	}
+}
+
 // CreateMessage sends a message request and returns the complete response.
 // It runs kiro-cli in non-interactive mode and parses the output.
 func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageRequest) (*providers.MessageResponse, error) {
+	return p.run(ctx, req, nil)
+}
+
+// StreamMessage sends a message request and streams the response via the handler.
+// Text is forwarded line by line as kiro-cli prints it; tool invocations are
+// not streamed as text but returned as tool_use and tool_result blocks.
+func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
+	return p.run(ctx, req, handler)
+}
+
+// run executes kiro-cli for the request, parsing its output as it is read.
+func (p *Provider) run(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
 	if !kiro.KiroAvailable() {
 		return nil, fmt.Errorf("kiro-cli not found in PATH")
 	}
 
-	prompt := p.buildPrompt(req)
+	// Write inline images and documents to files the agent can read
+	req, cleanup, err := providers.SpillMedia(req, "")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
 
 	kiroConfig := kiro.Config{
 		AgentName:   p.config.AgentName,
@@ -61,38 +111,107 @@ func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageReque
 		MCPArgs:     p.config.MCPArgs,
 		WorkDir:     p.config.WorkDir,
 	}
+	if err := kiro.Install(kiroConfig); err != nil {
+		return nil, fmt.Errorf("failed to install kiro configs: %w", err)
+	}
 
-	result, err := kiro.RunTest(ctx, kiroConfig, prompt)
+	args, err := kiro.BuildCommand(p.config.AgentName, p.buildPrompt(req), true)
 	if err != nil {
-		return nil, fmt.Errorf("kiro execution failed: %w", err)
+		return nil, err
+	}
+	if p.config.UsePTY {
+		args = ptyCommand(args)
 	}
 
-	return p.parseOutput(result.Output, result.ExitCode), nil
-}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = p.config.WorkDir
+	// MCP servers started by kiro-cli may outlive it and hold the output open
+	cmd.WaitDelay = time.Second
 
-// StreamMessage sends a message request and streams the response via the handler.
-// Note: Kiro CLI doesn't support true streaming, so this calls CreateMessage
-// and delivers the full response through the handler.
-func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
-	resp, err := p.CreateMessage(ctx, req)
-	if err != nil {
-		return nil, err
+	// Read stdout and stderr together, as kiro-cli reports tools on stderr
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start kiro: %w", err)
 	}
 
-	// Deliver the full response through the handler
-	for _, block := range resp.Content {
-		if block.Type == "text" {
-			handler(block.Text)
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		_ = pw.Close()
+		done <- err
+	}()
+
+	parser := newOutputParser(handler, p.config.EventHandler)
+	var output strings.Builder
+	reader := bufio.NewReader(pr)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			output.WriteString(line)
+			parser.line(line)
+		}
+		if readErr != nil {
+			break
 		}
 	}
 
+	waitErr := <-done
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	resp := parser.response()
+	if waitErr != nil && resp.StopReason != providers.StopReasonMaxTokens {
+		return nil, exitError(waitErr, output.String())
+	}
+
 	return resp, nil
 }
 
+// exitError describes a failed kiro-cli run, including the end of its output.
+func exitError(err error, output string) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return fmt.Errorf("kiro execution failed: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stripANSI(output)), "\n")
+	if len(lines) > 20 {
+		lines = lines[len(lines)-20:]
+	}
+	tail := strings.TrimSpace(strings.Join(lines, "\n"))
+	if tail == "" {
+		return fmt.Errorf("kiro execution failed: %w", err)
+	}
+	return fmt.Errorf("kiro execution failed: %w\nOutput: %s", err, tail)
+}
+
+// ptyCommand wraps args with script(1) so the command runs under a
+// pseudo-terminal and flushes its output line by line.
+func ptyCommand(args []string) []string {
+	if runtime.GOOS == "darwin" || strings.HasSuffix(runtime.GOOS, "bsd") {
+		return append([]string{"script", "-q", os.DevNull}, args...)
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return []string{"script", "-qefc", strings.Join(quoted, " "), os.DevNull}
+}
+
 // buildPrompt constructs a prompt string from the message request.
-// It extracts user messages and concatenates them.
+// It extracts user messages and concatenates them, referencing attached
+// media files by path. kiro-cli has no system prompt flag, so a system
+// prompt is placed first unless it repeats the agent prompt.
 func (p *Provider) buildPrompt(req providers.MessageRequest) string {
 	var userMessages []string
+	if req.System != "" && req.System != p.config.AgentPrompt {
+		userMessages = append(userMessages, req.System)
+	}
 
 	for _, msg := range req.Messages {
 		if msg.Role == "user" {
@@ -100,27 +219,12 @@ func (p *Provider) buildPrompt(req providers.MessageRequest) string {
 				if block.Type == "text" {
 					userMessages = append(userMessages, block.Text)
 				}
+				if block.IsMedia() && block.Path != "" {
+					userMessages = append(userMessages, providers.MediaReference(block))
+				}
 			}
 		}
 	}
 
 	return strings.Join(userMessages, "\n\n")
 }
-
-// parseOutput converts Kiro CLI output into a MessageResponse.
-func (p *Provider) parseOutput(output string, exitCode int) *providers.MessageResponse {
-	resp := &providers.MessageResponse{
-		StopReason: providers.StopReasonEndTurn,
-	}
-
-	if output != "" {
-		resp.Content = []providers.ContentBlock{
-			{
-				Type: "text",
-				Text: output,
-			},
-		}
-	}
-
-	return resp
-}
@@ -2,7 +2,11 @@ package kiro
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/lex00/wetwire-core-go/providers"
@@ -107,6 +111,16 @@ func TestBuildPromptFromMessages(t *testing.T) {
 			},
 			expected: "Create an S3 bucket\n\nwith versioning enabled",
 		},
+		{
+			name: "with attached image",
+			messages: []providers.Message{
+				{Role: "user", Content: []providers.ContentBlock{
+					{Type: "text", Text: "Build what's in this diagram"},
+					{Type: "image", MediaType: "image/png", Path: "/tmp/diagram.png"},
+				}},
+			},
+			expected: "Build what's in this diagram\n\n[Attached image: /tmp/diagram.png]",
+		},
 		{
 			name:     "empty messages",
 			messages: []providers.Message{},
@@ -123,38 +137,57 @@ func TestBuildPromptFromMessages(t *testing.T) {
 	}
 }
 
-func TestParseKiroOutput(t *testing.T) {
-	p := &Provider{}
+func TestBuildPromptWithSystem(t *testing.T) {
+	p := &Provider{config: Config{AgentPrompt: "You generate GitLab pipelines."}}
+
+	req := providers.MessageRequest{
+		System:   "Answer in English.",
+		Messages: []providers.Message{providers.NewUserMessage("Create a pipeline")},
+	}
+	assert.Equal(t, "Answer in English.\n\nCreate a pipeline", p.buildPrompt(req))
+
+	// The agent prompt is already loaded by kiro-cli
+	req.System = p.config.AgentPrompt
+	assert.Equal(t, "Create a pipeline", p.buildPrompt(req))
+}
 
+// parse feeds complete output through a parser line by line.
+func parse(output string) *providers.MessageResponse {
+	parser := newOutputParser(nil, nil)
+	for _, line := range strings.SplitAfter(output, "\n") {
+		if line != "" {
+			parser.line(line)
+		}
+	}
+	return parser.response()
+}
+
+func TestParseKiroOutput(t *testing.T) {
 	tests := []struct {
 		name       string
 		output     string
 		expectText bool
-		expectErr  bool
 	}{
 		{
 			name:       "simple text output",
 			output:     "Generated successfully: test.go",
 			expectText: true,
-			expectErr:  false,
 		},
 		{
 			name:       "empty output",
 			output:     "",
 			expectText: false,
-			expectErr:  false,
 		},
 		{
 			name:       "multiline output",
 			output:     "Line 1\nLine 2\nLine 3",
 			expectText: true,
-			expectErr:  false,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result := p.parseOutput(tc.output, 0)
+			result := parse(tc.output)
 			if tc.expectText {
 				require.NotEmpty(t, result.Content)
 				assert.Equal(t, "text", result.Content[0].Type)
@@ -167,12 +200,176 @@ func TestParseKiroOutput(t *testing.T) {
 	}
 }
 
-func TestParseKiroOutputWithExitCode(t *testing.T) {
-	p := &Provider{}
+func TestParseKiroOutputToolCalls(t *testing.T) {
+	output := "I'll write the bucket.\n" +
+		"\x1b[38;5;13m🛠️  Using tool: wetwire_write\x1b[0m (trusted) from mcp server wetwire-aws\n" +
+		" ⋮ \n" +
+		" ● Wrote bucket.go\n" +
+		" ● Completed in 0.2s\n" +
+		"🛠️  Using tool: wetwire_lint (trusted) from mcp server wetwire-aws\n" +
+		" ● 1 issue found\n" +
+		" ● Execution failed after 0.1s\n" +
+		"Done.\n"
+
+	var events []string
+	var streamed strings.Builder
+	parser := newOutputParser(func(text string) {
+		streamed.WriteString(text)
+	}, func(block providers.ContentBlock) {
+		events = append(events, block.Type)
+	})
+	for _, line := range strings.SplitAfter(output, "\n") {
+		parser.line(line)
+	}
+	resp := parser.response()
+
+	require.Len(t, resp.Content, 6)
+	assert.Equal(t, providers.ContentBlock{Type: "text", Text: "I'll write the bucket.\n"}, resp.Content[0])
+
+	assert.Equal(t, "tool_use", resp.Content[1].Type)
+	assert.Equal(t, "wetwire_write", resp.Content[1].Name)
+	assert.JSONEq(t, `{"mcp_server":"wetwire-aws"}`, string(resp.Content[1].Input))
+	assert.Equal(t, providers.NewToolResult(resp.Content[1].ID, "Wrote bucket.go", false), resp.Content[2])
+
+	assert.Equal(t, "wetwire_lint", resp.Content[3].Name)
+	assert.NotEqual(t, resp.Content[1].ID, resp.Content[3].ID)
+	assert.Equal(t, providers.NewToolResult(resp.Content[3].ID, "1 issue found", true), resp.Content[4])
+
+	assert.Equal(t, providers.ContentBlock{Type: "text", Text: "Done.\n"}, resp.Content[5])
+
+	assert.Equal(t, "I'll write the bucket.\nDone.\n", streamed.String())
+	assert.Equal(t, []string{"text", "tool_use", "tool_result", "tool_use", "tool_result", "text"}, events)
+	assert.Equal(t, providers.StopReasonEndTurn, resp.StopReason)
+}
+
+func TestParseKiroOutputContextOverflow(t *testing.T) {
+	resp := parse("Partial answer\n⚠️ The context window is full. Use /compact to continue.\n")
+	assert.Equal(t, providers.StopReasonMaxTokens, resp.StopReason)
+
+	// Model text about context windows is not a CLI status line
+	resp = parse("When the context window is full, older turns are dropped.\n")
+	assert.Equal(t, providers.StopReasonEndTurn, resp.StopReason)
+}
+
+func TestParseKiroOutputToolWithoutCompletion(t *testing.T) {
+	resp := parse("🛠️  Using tool: wetwire_build (trusted)\n" +
+		" ⋮ \n" +
+		" ● Built stack.json\n" +
+		"\n" +
+		"The stack is ready.\n")
+
+	require.Len(t, resp.Content, 3)
+	assert.Equal(t, "wetwire_build", resp.Content[0].Name)
+	assert.Equal(t, providers.NewToolResult(resp.Content[0].ID, "Built stack.json", false), resp.Content[1])
+	assert.Equal(t, providers.ContentBlock{Type: "text", Text: "The stack is ready.\n"}, resp.Content[2])
+}
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain", "hello\n", "hello\n"},
+		{"colors", "\x1b[1m\x1b[32mhello\x1b[0m\n", "hello\n"},
+		{"spinner overwrite", "⠋ Thinking...\r⠙ Thinking...\rhello\n", "hello\n"},
+		{"pty line ending", "hello\r\n", "hello\n"},
+		{"title sequence", "\x1b]0;kiro\x07hello", "hello"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, stripANSI(tc.input))
+		})
+	}
+}
+
+func TestPTYCommand(t *testing.T) {
+	args := ptyCommand([]string{"kiro-cli", "chat", "it's done"})
+	assert.Equal(t, "script", args[0])
+	if runtime.GOOS == "linux" {
+		assert.Equal(t, []string{"script", "-qefc", `'kiro-cli' 'chat' 'it'\''s done'`, os.DevNull}, args)
+	}
+}
+
+// fakeKiro installs a kiro-cli script on PATH that prints output and exits
+// with the given code. Agent configs are installed into a temporary HOME.
+func fakeKiro(t *testing.T, output string, exitCode int) {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "output.txt"), []byte(output), 0644))
+	script := fmt.Sprintf("#!/bin/sh\ncat %q\nexit %d\n", filepath.Join(dir, "output.txt"), exitCode)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kiro-cli"), []byte(script), 0755))
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("HOME", t.TempDir())
+}
+
+func TestStreamMessageFakeKiro(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell")
+	}
+	fakeKiro(t, "\x1b[32mCreating bucket\x1b[0m\n🛠️  Using tool: wetwire_write (trusted)\n ● Completed in 0.1s\nDone\n", 0)
+
+	p, err := New(Config{AgentName: "test-agent", MCPCommand: "test-mcp", WorkDir: t.TempDir()})
+	require.NoError(t, err)
+
+	var chunks []string
+	resp, err := p.StreamMessage(context.Background(), providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("hello")},
+	}, func(text string) {
+		chunks = append(chunks, text)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Creating bucket\n", "Done\n"}, chunks)
+	require.Len(t, resp.Content, 4)
+	assert.Equal(t, "tool_use", resp.Content[1].Type)
+	assert.Equal(t, "wetwire_write", resp.Content[1].Name)
+	assert.Equal(t, providers.StopReasonEndTurn, resp.StopReason)
+}
+
+func TestCreateMessageFakeKiroFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell")
+	}
+	fakeKiro(t, "Error: agent not found\n", 1)
+
+	p, err := New(Config{AgentName: "test-agent", MCPCommand: "test-mcp", WorkDir: t.TempDir()})
+	require.NoError(t, err)
+
+	_, err = p.CreateMessage(context.Background(), providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("hello")},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit status 1")
+	assert.Contains(t, err.Error(), "agent not found")
+}
+
+func TestCreateMessageFakeKiroContextOverflow(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell")
+	}
+	fakeKiro(t, "Partial\n✘ The context window is full.\n", 1)
+
+	p, err := New(Config{AgentName: "test-agent", MCPCommand: "test-mcp", WorkDir: t.TempDir()})
+	require.NoError(t, err)
+
+	resp, err := p.CreateMessage(context.Background(), providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("hello")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, providers.StopReasonMaxTokens, resp.StopReason)
+}
+
+func TestCapabilities(t *testing.T) {
+	p, err := New(Config{AgentName: "test-agent"})
+	require.NoError(t, err)
 
-	// Non-zero exit code should still parse output but may indicate error
-	result := p.parseOutput("Error occurred", 1)
-	require.NotEmpty(t, result.Content)
-	assert.Equal(t, "Error occurred", result.Content[0].Text)
-	assert.Equal(t, providers.StopReasonEndTurn, result.StopReason)
+	caps := p.Capabilities("")
+	assert.True(t, caps.Agentic)
+	assert.True(t, caps.Streaming)
+	assert.True(t, caps.SystemPrompt)
+	assert.NoError(t, caps.Check(providers.MessageRequest{System: "You are..."}))
 }
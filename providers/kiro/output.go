@@ -0,0 +1,166 @@
+package kiro
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+var (
+	// ansiPattern matches ANSI CSI and OSC escape sequences.
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[()][0-9A-Za-z]|\x1b[=>78]`)
+
+	// toolUsePattern matches the line kiro-cli prints when it invokes a tool,
+	// e.g. "🛠️  Using tool: wetwire_lint (trusted) from mcp server wetwire-aws".
+	toolUsePattern = regexp.MustCompile(`Using tool:\s*(\S+)(?:\s+\(trusted\))?(?:\s+from mcp server\s+(\S+))?`)
+
+	// toolDonePattern matches the line that ends a tool invocation.
+	toolDonePattern = regexp.MustCompile(`^[●•]?\s*(Completed in|Execution failed|Tool execution failed)`)
+
+	// statusPattern matches lines kiro-cli prints about its own state rather
+	// than model text, e.g. "⚠️ The context window is full" or "Error: ...".
+	statusPattern = regexp.MustCompile(`^(?:[⚠✘✗❌]|(?i:error|warning):)`)
+
+	// contextOverflowPattern matches kiro-cli status messages about a full context window.
+	contextOverflowPattern = regexp.MustCompile(`(?i)context window (is )?(full|overflow)|exceeds? the (maximum )?context`)
+)
+
+// toolLineMarkers prefix the lines kiro-cli prints while a tool runs.
+const toolLineMarkers = "⋮●•"
+
+// stripANSI removes terminal escape sequences and carriage-return overwrites
+// (spinners, progress lines) from a line of output.
+func stripANSI(line string) string {
+	line = ansiPattern.ReplaceAllString(line, "")
+
+	newline := strings.HasSuffix(line, "\n")
+	line = strings.TrimRight(line, "\r\n")
+	if i := strings.LastIndex(line, "\r"); i >= 0 {
+		line = line[i+1:]
+	}
+	if newline {
+		line += "\n"
+	}
+	return line
+}
+
+// outputParser turns kiro-cli output, read line by line, into content blocks.
+// Text is forwarded to the handler as it arrives; tool invocations become
+// tool_use blocks followed by a tool_result holding the tool's output. A tool
+// section ends at its completion line or at the first line without a tool
+// marker, so a missing completion line does not swallow the answer.
+type outputParser struct {
+	handler providers.StreamHandler
+	onEvent func(block providers.ContentBlock)
+
+	blocks     []providers.ContentBlock
+	text       strings.Builder
+	tool       *providers.ContentBlock
+	toolOutput []string
+	tools      int
+	stopReason providers.StopReason
+}
+
+// newOutputParser creates a parser that streams text to handler and reports
+// each block to onEvent. Both are optional.
+func newOutputParser(handler providers.StreamHandler, onEvent func(providers.ContentBlock)) *outputParser {
+	return &outputParser{
+		handler:    handler,
+		onEvent:    onEvent,
+		stopReason: providers.StopReasonEndTurn,
+	}
+}
+
+// line processes a single line of output, including its trailing newline.
+func (o *outputParser) line(raw string) {
+	line := stripANSI(raw)
+	trimmed := strings.TrimSpace(line)
+
+	if m := toolUsePattern.FindStringSubmatch(trimmed); m != nil {
+		o.flushText()
+		o.endTool(false)
+		o.startTool(m[1], m[2])
+		return
+	}
+
+	if o.tool != nil {
+		if m := toolDonePattern.FindStringSubmatch(trimmed); m != nil {
+			o.endTool(m[1] != "Completed in")
+			return
+		}
+		if trimmed == "" || strings.IndexAny(trimmed, toolLineMarkers) == 0 {
+			if detail := strings.TrimSpace(strings.TrimLeft(trimmed, toolLineMarkers+" ")); detail != "" {
+				o.toolOutput = append(o.toolOutput, detail)
+			}
+			return
+		}
+		o.endTool(false)
+	}
+
+	if statusPattern.MatchString(trimmed) && contextOverflowPattern.MatchString(trimmed) {
+		o.stopReason = providers.StopReasonMaxTokens
+	}
+
+	o.text.WriteString(line)
+	if o.handler != nil && line != "" {
+		o.handler(line)
+	}
+}
+
+// startTool records a tool_use block for a tool invocation.
+func (o *outputParser) startTool(name, server string) {
+	o.tools++
+	input := "{}"
+	if server != "" {
+		input = fmt.Sprintf(`{"mcp_server":%q}`, server)
+	}
+	o.tool = &providers.ContentBlock{
+		Type:  "tool_use",
+		ID:    fmt.Sprintf("kiro_tool_%d", o.tools),
+		Name:  name,
+		Input: []byte(input),
+	}
+	o.emit(*o.tool)
+}
+
+// endTool records the tool_result block of the current tool, if any.
+func (o *outputParser) endTool(isError bool) {
+	if o.tool == nil {
+		return
+	}
+	o.emit(providers.NewToolResult(o.tool.ID, strings.Join(o.toolOutput, "\n"), isError))
+	o.tool = nil
+	o.toolOutput = nil
+}
+
+// flushText records the text seen since the last block.
+func (o *outputParser) flushText() {
+	if o.text.Len() == 0 {
+		return
+	}
+	o.blocks = append(o.blocks, providers.ContentBlock{Type: "text", Text: o.text.String()})
+	if o.onEvent != nil {
+		o.onEvent(o.blocks[len(o.blocks)-1])
+	}
+	o.text.Reset()
+}
+
+// emit records a tool block and reports it to onEvent.
+func (o *outputParser) emit(block providers.ContentBlock) {
+	o.blocks = append(o.blocks, block)
+	if o.onEvent != nil {
+		o.onEvent(block)
+	}
+}
+
+// response finishes parsing and returns the response.
+func (o *outputParser) response() *providers.MessageResponse {
+	o.endTool(false)
+	o.flushText()
+	return &providers.MessageResponse{
+		Content:    o.blocks,
+		StopReason: o.stopReason,
+	}
+}
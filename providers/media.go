@@ -0,0 +1,183 @@
+package providers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mediaTypes maps supported file extensions to media types.
+var mediaTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".pdf":  "application/pdf",
+}
+
+// MediaTypeByPath returns the media type for a file extension,
+// or "" if the extension is not a supported image or document type.
+func MediaTypeByPath(path string) string {
+	return mediaTypes[strings.ToLower(filepath.Ext(path))]
+}
+
+// NewImage creates an image content block from raw image bytes.
+func NewImage(mediaType string, data []byte) ContentBlock {
+	return ContentBlock{
+		Type:      "image",
+		MediaType: mediaType,
+		Base64:    base64.StdEncoding.EncodeToString(data),
+	}
+}
+
+// NewDocument creates a document content block from raw document bytes.
+func NewDocument(mediaType string, data []byte) ContentBlock {
+	return ContentBlock{
+		Type:      "document",
+		MediaType: mediaType,
+		Base64:    base64.StdEncoding.EncodeToString(data),
+	}
+}
+
+// NewMediaFile creates an image or document block referencing a local file.
+// The file is read when the request is sent, not when the block is created.
+func NewMediaFile(path string) (ContentBlock, error) {
+	mediaType := MediaTypeByPath(path)
+	if mediaType == "" {
+		return ContentBlock{}, fmt.Errorf("unsupported media file: %s", path)
+	}
+
+	blockType := "document"
+	if strings.HasPrefix(mediaType, "image/") {
+		blockType = "image"
+	}
+
+	return ContentBlock{
+		Type:      blockType,
+		MediaType: mediaType,
+		Path:      path,
+	}, nil
+}
+
+// IsMedia returns true for image and document blocks.
+func (b ContentBlock) IsMedia() bool {
+	return b.Type == "image" || b.Type == "document"
+}
+
+// InlineMedia returns a copy of the request with file-backed media blocks
+// read into base64 content, for providers that send media inline.
+func InlineMedia(req MessageRequest) (MessageRequest, error) {
+	return mapMedia(req, func(b ContentBlock) (ContentBlock, error) {
+		if b.Base64 != "" || b.Path == "" {
+			return b, nil
+		}
+
+		data, err := os.ReadFile(b.Path)
+		if err != nil {
+			return b, fmt.Errorf("failed to read %s: %w", b.Type, err)
+		}
+		b.Base64 = base64.StdEncoding.EncodeToString(data)
+		return b, nil
+	})
+}
+
+// SpillMedia returns a copy of the request with base64 media blocks written
+// to files in a new temporary directory under dir (os.TempDir() if empty),
+// for providers that can only reference files. The directory holds only
+// this request's files, so it can be shared with a CLI without exposing
+// anything else. The returned cleanup function removes it.
+func SpillMedia(req MessageRequest, dir string) (MessageRequest, func(), error) {
+	var spillDir string
+	cleanup := func() {
+		if spillDir != "" {
+			_ = os.RemoveAll(spillDir)
+		}
+	}
+
+	req, err := mapMedia(req, func(b ContentBlock) (ContentBlock, error) {
+		if b.Path != "" || b.Base64 == "" {
+			return b, nil
+		}
+
+		data, err := base64.StdEncoding.DecodeString(b.Base64)
+		if err != nil {
+			return b, fmt.Errorf("failed to decode %s: %w", b.Type, err)
+		}
+
+		if spillDir == "" {
+			spillDir, err = os.MkdirTemp(dir, "wetwire-media-*")
+			if err != nil {
+				return b, fmt.Errorf("failed to create media directory: %w", err)
+			}
+		}
+
+		f, err := os.CreateTemp(spillDir, "attachment-*"+extensionFor(b.MediaType))
+		if err != nil {
+			return b, fmt.Errorf("failed to create %s file: %w", b.Type, err)
+		}
+
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return b, fmt.Errorf("failed to write %s file: %w", b.Type, err)
+		}
+
+		b.Path = f.Name()
+		b.Base64 = ""
+		return b, nil
+	})
+	if err != nil {
+		cleanup()
+		return req, func() {}, err
+	}
+
+	return req, cleanup, nil
+}
+
+// MediaReference returns a text reference to a file-backed media block,
+// for providers that only accept text prompts.
+func MediaReference(b ContentBlock) string {
+	return fmt.Sprintf("[Attached %s: %s]", b.Type, b.Path)
+}
+
+// mapMedia returns a copy of the request with fn applied to each media block.
+func mapMedia(req MessageRequest, fn func(ContentBlock) (ContentBlock, error)) (MessageRequest, error) {
+	messages := make([]Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		content := make([]ContentBlock, len(msg.Content))
+		for j, block := range msg.Content {
+			if block.IsMedia() {
+				var err error
+				if block, err = fn(block); err != nil {
+					return req, err
+				}
+			}
+			content[j] = block
+		}
+		messages[i] = Message{Role: msg.Role, Content: content}
+	}
+
+	req.Messages = messages
+	return req, nil
+}
+
+// extensionFor returns a file extension for a media type.
+func extensionFor(mediaType string) string {
+	switch mediaType {
+	case "image/jpeg":
+		return ".jpg"
+	case "":
+		return ""
+	}
+	for ext, mt := range mediaTypes {
+		if mt == mediaType {
+			return ext
+		}
+	}
+	return ""
+}
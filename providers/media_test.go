@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMediaTypeByPath(t *testing.T) {
+	assert.Equal(t, "image/png", MediaTypeByPath("diagram.PNG"))
+	assert.Equal(t, "image/jpeg", MediaTypeByPath("photo.jpeg"))
+	assert.Equal(t, "application/pdf", MediaTypeByPath("runbook.pdf"))
+	assert.Equal(t, "", MediaTypeByPath("prompt.md"))
+	assert.Equal(t, "", MediaTypeByPath("beginner.txt"))
+}
+
+func TestNewMediaFile(t *testing.T) {
+	img, err := NewMediaFile("prompts/diagram.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image", img.Type)
+	assert.Equal(t, "image/png", img.MediaType)
+	assert.True(t, img.IsMedia())
+
+	doc, err := NewMediaFile("prompts/runbook.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "document", doc.Type)
+
+	_, err = NewMediaFile("prompts/beginner.md")
+	assert.Error(t, err)
+}
+
+func TestInlineMedia(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diagram.png")
+	require.NoError(t, os.WriteFile(path, []byte("png-bytes"), 0644))
+
+	block, err := NewMediaFile(path)
+	require.NoError(t, err)
+
+	msg := NewUserMessage("build this")
+	msg.Content = append(msg.Content, block)
+	req := MessageRequest{Messages: []Message{msg}}
+
+	inlined, err := InlineMedia(req)
+	require.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("png-bytes")), inlined.Messages[0].Content[1].Base64)
+
+	// The original request is not modified
+	assert.Empty(t, req.Messages[0].Content[1].Base64)
+
+	msg.Content[1].Path = filepath.Join(t.TempDir(), "missing.png")
+	_, err = InlineMedia(MessageRequest{Messages: []Message{msg}})
+	assert.Error(t, err)
+}
+
+func TestSpillMedia(t *testing.T) {
+	msg := NewUserMessage("read this")
+	msg.Content = append(msg.Content, NewDocument("application/pdf", []byte("%PDF-1.4")))
+	req := MessageRequest{Messages: []Message{msg}}
+
+	parent := t.TempDir()
+	spilled, cleanup, err := SpillMedia(req, parent)
+	require.NoError(t, err)
+
+	block := spilled.Messages[0].Content[1]
+	assert.Empty(t, block.Base64)
+	assert.Equal(t, ".pdf", filepath.Ext(block.Path))
+
+	// Files go in a directory of their own, not directly in parent
+	spillDir := filepath.Dir(block.Path)
+	assert.Equal(t, parent, filepath.Dir(spillDir))
+	assert.Equal(t, "[Attached document: "+block.Path+"]", MediaReference(block))
+
+	data, err := os.ReadFile(block.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4", string(data))
+
+	cleanup()
+	_, err = os.Stat(spillDir)
+	assert.True(t, os.IsNotExist(err))
+}
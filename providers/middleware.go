@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"context"
+	"time"
+)
+
+// Middleware wraps a provider with cross-cutting behavior such as logging,
+// redaction or metrics. The returned provider should implement
+// Unwrap() Provider so that CapabilitiesOf sees through it.
+type Middleware func(next Provider) Provider
+
+// Chain composes middlewares into one. The first middleware is the
+// outermost: it sees each request first and each response last.
+//
+// Example:
+//
+//	provider = providers.Chain(
+//		middleware.Logging(logger),
+//		middleware.Redact(),
+//	)(provider)
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next Provider) Provider {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			if middlewares[i] != nil {
+				next = middlewares[i](next)
+			}
+		}
+		return next
+	}
+}
+
+// Interceptor holds request and response hooks for Intercept.
+type Interceptor struct {
+	// BeforeRequest is called before each request (optional). It may modify
+	// the request, for example to override the model; slices must be copied
+	// before their elements are changed, since they belong to the caller.
+	// An error fails the request without calling the provider.
+	BeforeRequest func(ctx context.Context, req *MessageRequest) error
+
+	// AfterResponse is called after each request with the request as sent,
+	// the response or error and the latency (optional)
+	AfterResponse func(ctx context.Context, req MessageRequest, resp *MessageResponse, err error, elapsed time.Duration)
+}
+
+// Intercept returns a middleware that calls the interceptor's hooks around
+// every CreateMessage and StreamMessage call.
+func Intercept(i Interceptor) Middleware {
+	return func(next Provider) Provider {
+		return &intercepted{next: next, hooks: i}
+	}
+}
+
+// intercepted is a provider wrapped by Intercept.
+type intercepted struct {
+	next  Provider
+	hooks Interceptor
+}
+
+// Name returns the name of the wrapped provider.
+func (p *intercepted) Name() string {
+	return p.next.Name()
+}
+
+// Unwrap returns the wrapped provider.
+func (p *intercepted) Unwrap() Provider {
+	return p.next
+}
+
+// CreateMessage calls the hooks around the wrapped provider's CreateMessage.
+func (p *intercepted) CreateMessage(ctx context.Context, req MessageRequest) (*MessageResponse, error) {
+	return p.call(ctx, req, func(req MessageRequest) (*MessageResponse, error) {
+		return p.next.CreateMessage(ctx, req)
+	})
+}
+
+// StreamMessage calls the hooks around the wrapped provider's StreamMessage.
+func (p *intercepted) StreamMessage(ctx context.Context, req MessageRequest, handler StreamHandler) (*MessageResponse, error) {
+	return p.call(ctx, req, func(req MessageRequest) (*MessageResponse, error) {
+		return p.next.StreamMessage(ctx, req, handler)
+	})
+}
+
+// call runs the request hook, the request and the response hook.
+func (p *intercepted) call(ctx context.Context, req MessageRequest, send func(MessageRequest) (*MessageResponse, error)) (*MessageResponse, error) {
+	if p.hooks.BeforeRequest != nil {
+		if err := p.hooks.BeforeRequest(ctx, &req); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	resp, err := send(req)
+	if p.hooks.AfterResponse != nil {
+		p.hooks.AfterResponse(ctx, req, resp, err, time.Since(start))
+	}
+	return resp, err
+}
@@ -0,0 +1,53 @@
+// Package middleware provides built-in providers.Middleware implementations.
+//
+// Compose them with providers.Chain and wrap any provider, or pass the
+// chain to agents.AgentConfig.Middleware:
+//
+//	provider = providers.Chain(
+//		middleware.Logging(slog.Default()),
+//		middleware.Redact(),
+//	)(provider)
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// Logging returns a middleware that logs every request with its model,
+// size, latency, stop reason and token usage. Message content is not logged.
+// Successful requests are logged at Info level and failures at Error level.
+// A nil logger uses slog.Default().
+func Logging(logger *slog.Logger) providers.Middleware {
+	return func(next providers.Provider) providers.Provider {
+		log := logger
+		if log == nil {
+			log = slog.Default()
+		}
+
+		return providers.Intercept(providers.Interceptor{
+			AfterResponse: func(ctx context.Context, req providers.MessageRequest, resp *providers.MessageResponse, err error, elapsed time.Duration) {
+				attrs := []any{
+					slog.String("provider", providers.ServedBy(next, resp)),
+					slog.String("model", providers.ServedModel(req.Model, resp)),
+					slog.Int("messages", len(req.Messages)),
+					slog.Int("tools", len(req.Tools)),
+					slog.Duration("latency", elapsed),
+				}
+				if err != nil {
+					log.ErrorContext(ctx, "provider request failed", append(attrs, slog.Any("error", err))...)
+					return
+				}
+
+				log.InfoContext(ctx, "provider request", append(attrs,
+					slog.String("stop_reason", string(resp.StopReason)),
+					slog.Int("input_tokens", resp.Usage.InputTokens),
+					slog.Int("output_tokens", resp.Usage.OutputTokens),
+				)...)
+			},
+		})(next)
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// Redacted replaces each secret removed by Redact.
+const Redacted = "[REDACTED]"
+
+// DefaultSecretPatterns match common credentials: API keys for Anthropic,
+// OpenAI, GitHub, GitLab and Slack, AWS access key IDs, private key blocks,
+// quoted literals assigned to password, secret, token and API key fields,
+// and environment-style PASSWORD=..., SECRET=... and TOKEN=... lines.
+// References such as `Password: cfg.Password` are left alone.
+var DefaultSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-ant-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`sk-(?:proj-)?[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`),
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`),
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`),
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api_?key)(?:\\?["'])?\s*[:=]\s*\\?["'])([^"'\s\\]{8,})`),
+	regexp.MustCompile(`(?m)^((?:export\s+)?[A-Z0-9_]*(?:PASSWORD|SECRET|TOKEN|API_KEY)[A-Z0-9_]*=)(\S{8,})`),
+}
+
+// Redact returns a middleware that replaces secrets in outgoing requests
+// with Redacted, so they never leave the machine. It rewrites the system
+// prompt, text blocks, tool inputs and tool results; thinking blocks are
+// left alone since they must be sent back unchanged. Without patterns it
+// uses DefaultSecretPatterns. A pattern with two or more groups keeps the
+// first group (such as "password=") and redacts the rest of the match.
+func Redact(patterns ...*regexp.Regexp) providers.Middleware {
+	if len(patterns) == 0 {
+		patterns = DefaultSecretPatterns
+	}
+	r := redactor(patterns)
+
+	return providers.Intercept(providers.Interceptor{
+		BeforeRequest: func(ctx context.Context, req *providers.MessageRequest) error {
+			req.System = r.text(req.System)
+
+			messages := make([]providers.Message, len(req.Messages))
+			for i, msg := range req.Messages {
+				content := make([]providers.ContentBlock, len(msg.Content))
+				for j, block := range msg.Content {
+					content[j] = r.block(block)
+				}
+				messages[i] = providers.Message{Role: msg.Role, Content: content}
+			}
+			req.Messages = messages
+			return nil
+		},
+	})
+}
+
+// redactor replaces matches of its patterns.
+type redactor []*regexp.Regexp
+
+// text returns s with every match replaced.
+func (r redactor) text(s string) string {
+	for _, p := range r {
+		if p.NumSubexp() >= 2 {
+			s = p.ReplaceAllString(s, "${1}"+Redacted)
+		} else {
+			s = p.ReplaceAllLiteralString(s, Redacted)
+		}
+	}
+	return s
+}
+
+// block returns a copy of block with secrets replaced.
+func (r redactor) block(block providers.ContentBlock) providers.ContentBlock {
+	switch block.Type {
+	case "text":
+		block.Text = r.text(block.Text)
+	case "tool_result":
+		block.Content = r.text(block.Content)
+	case "tool_use":
+		// Keep the input if redaction would break its JSON
+		if input := json.RawMessage(r.text(string(block.Input))); json.Valid(input) {
+			block.Input = input
+		}
+	}
+	return block
+}
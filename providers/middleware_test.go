@@ -0,0 +1,106 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tagging returns a middleware that appends tag to the system prompt on the
+// way in and records the order in which responses come back.
+func tagging(tag string, order *[]string) Middleware {
+	return Intercept(Interceptor{
+		BeforeRequest: func(ctx context.Context, req *MessageRequest) error {
+			req.System += tag
+			return nil
+		},
+		AfterResponse: func(ctx context.Context, req MessageRequest, resp *MessageResponse, err error, elapsed time.Duration) {
+			*order = append(*order, tag)
+		},
+	})
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	var sent string
+	stub := &stubProvider{name: "stub", text: "ok"}
+	record := Intercept(Interceptor{
+		BeforeRequest: func(ctx context.Context, req *MessageRequest) error {
+			sent = req.System
+			return nil
+		},
+	})
+
+	p := Chain(tagging("a", &order), nil, tagging("b", &order), record)(stub)
+
+	resp, err := p.CreateMessage(context.Background(), MessageRequest{System: ">"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Content[0].Text)
+	assert.Equal(t, ">ab", sent)
+	assert.Equal(t, []string{"b", "a"}, order)
+	assert.Equal(t, "stub", p.Name())
+}
+
+func TestChainEmpty(t *testing.T) {
+	stub := &stubProvider{name: "stub"}
+	assert.Same(t, Provider(stub), Chain()(stub))
+}
+
+func TestInterceptOverridesModel(t *testing.T) {
+	stub := &stubProvider{name: "stub", text: "ok"}
+	p := Intercept(Interceptor{
+		BeforeRequest: func(ctx context.Context, req *MessageRequest) error {
+			req.Model = "haiku"
+			return nil
+		},
+	})(stub)
+
+	var streamed string
+	_, err := p.StreamMessage(context.Background(), MessageRequest{Model: "opus"}, func(text string) { streamed += text })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"haiku"}, stub.models)
+	assert.Equal(t, "ok", streamed)
+}
+
+func TestInterceptBeforeRequestError(t *testing.T) {
+	stub := &stubProvider{name: "stub"}
+	called := false
+	p := Intercept(Interceptor{
+		BeforeRequest: func(ctx context.Context, req *MessageRequest) error {
+			return errors.New("blocked")
+		},
+		AfterResponse: func(ctx context.Context, req MessageRequest, resp *MessageResponse, err error, elapsed time.Duration) {
+			called = true
+		},
+	})(stub)
+
+	_, err := p.CreateMessage(context.Background(), MessageRequest{})
+	assert.EqualError(t, err, "blocked")
+	assert.Empty(t, stub.models)
+	assert.False(t, called)
+}
+
+func TestInterceptAfterResponseSeesError(t *testing.T) {
+	stub := &stubProvider{name: "stub", err: errors.New("overloaded")}
+	var got error
+	p := Intercept(Interceptor{
+		AfterResponse: func(ctx context.Context, req MessageRequest, resp *MessageResponse, err error, elapsed time.Duration) {
+			got = err
+		},
+	})(stub)
+
+	_, err := p.CreateMessage(context.Background(), MessageRequest{})
+	assert.EqualError(t, err, "overloaded")
+	assert.Equal(t, err, got)
+}
+
+func TestInterceptKeepsCapabilities(t *testing.T) {
+	inner := &capableProvider{stubProvider: stubProvider{name: "cli"}, caps: Capabilities{Agentic: true}}
+	p := Chain(Intercept(Interceptor{}), Intercept(Interceptor{}))(inner)
+
+	assert.True(t, CapabilitiesOf(p, "").Agentic)
+}
@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxTokens is the output token limit used when a request sets none.
+const DefaultMaxTokens = 4096
+
+// Model describes a concrete model and its limits.
+type Model struct {
+	// ID is the concrete model identifier sent to the API
+	ID string
+
+	// Aliases are alternative names that resolve to this model
+	Aliases []string
+
+	// ContextWindow is the maximum number of input and output tokens
+	ContextWindow int
+
+	// MaxOutputTokens is the maximum number of tokens in a response
+	MaxOutputTokens int
+
+	// Multimodal reports whether the model accepts images and documents
+	Multimodal bool
+}
+
+// ModelCatalog is a list of known models.
+type ModelCatalog []Model
+
+// DefaultModels contains the models commonly used with wetwire.
+// The short aliases "opus", "sonnet" and "haiku" used in scenario.yaml
+// resolve to the latest model of each family.
+var DefaultModels = ModelCatalog{
+	{ID: "claude-opus-4-5-20251101", Aliases: []string{"opus", "claude-opus-4-5"}, ContextWindow: 200000, MaxOutputTokens: 64000, Multimodal: true},
+	{ID: "claude-opus-4-1-20250805", Aliases: []string{"claude-opus-4-1"}, ContextWindow: 200000, MaxOutputTokens: 32000, Multimodal: true},
+	{ID: "claude-opus-4-20250514", Aliases: []string{"claude-opus-4-0"}, ContextWindow: 200000, MaxOutputTokens: 32000, Multimodal: true},
+	{ID: "claude-sonnet-4-5-20250929", Aliases: []string{"sonnet", "claude-sonnet-4-5"}, ContextWindow: 200000, MaxOutputTokens: 64000, Multimodal: true},
+	{ID: "claude-sonnet-4-20250514", Aliases: []string{"claude-sonnet-4-0"}, ContextWindow: 200000, MaxOutputTokens: 64000, Multimodal: true},
+	{ID: "claude-3-7-sonnet-20250219", Aliases: []string{"claude-3-7-sonnet-latest"}, ContextWindow: 200000, MaxOutputTokens: 64000, Multimodal: true},
+	{ID: "claude-haiku-4-5-20251001", Aliases: []string{"haiku", "claude-haiku-4-5"}, ContextWindow: 200000, MaxOutputTokens: 64000, Multimodal: true},
+	{ID: "claude-3-5-haiku-20241022", Aliases: []string{"claude-3-5-haiku-latest"}, ContextWindow: 200000, MaxOutputTokens: 8192, Multimodal: true},
+	{ID: "gpt-4o", ContextWindow: 128000, MaxOutputTokens: 16384, Multimodal: true},
+	{ID: "gpt-4o-mini", ContextWindow: 128000, MaxOutputTokens: 16384, Multimodal: true},
+}
+
+// Lookup returns the model for an ID or alias. Names that extend a known ID,
+// such as a dated snapshot, match the longest such ID.
+func (c ModelCatalog) Lookup(name string) (Model, bool) {
+	for _, m := range c {
+		if m.ID == name {
+			return m, true
+		}
+		for _, alias := range m.Aliases {
+			if alias == name {
+				return m, true
+			}
+		}
+	}
+
+	var best Model
+	for _, m := range c {
+		if strings.HasPrefix(name, m.ID) && len(m.ID) > len(best.ID) {
+			best = m
+		}
+	}
+	return best, best.ID != ""
+}
+
+// Resolve returns the concrete ID for a model name.
+// Unknown names are returned unchanged.
+func (c ModelCatalog) Resolve(name string) string {
+	if m, ok := c.Lookup(name); ok && m.ID != name && !strings.HasPrefix(name, m.ID) {
+		return m.ID
+	}
+	return name
+}
+
+// Validate returns an error if name is not a known model ID or alias.
+func (c ModelCatalog) Validate(name string) error {
+	if _, ok := c.Lookup(name); !ok {
+		return fmt.Errorf("unknown model %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelCatalogLookup(t *testing.T) {
+	tests := []struct {
+		name   string
+		wantID string
+		wantOK bool
+	}{
+		{"sonnet", "claude-sonnet-4-5-20250929", true},
+		{"haiku", "claude-haiku-4-5-20251001", true},
+		{"opus", "claude-opus-4-5-20251101", true},
+		{"claude-sonnet-4-20250514", "claude-sonnet-4-20250514", true},
+		{"claude-3-5-haiku-latest", "claude-3-5-haiku-20241022", true},
+		{"gpt-4o-mini-2024-07-18", "gpt-4o-mini", true},
+		{"gpt-4o-2024-08-06", "gpt-4o", true},
+		{"qwen2.5-coder", "", false},
+		{"sonet", "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, ok := DefaultModels.Lookup(tc.name)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantID, m.ID)
+		})
+	}
+}
+
+func TestModelCatalogResolve(t *testing.T) {
+	assert.Equal(t, "claude-sonnet-4-5-20250929", DefaultModels.Resolve("sonnet"))
+	assert.Equal(t, "claude-sonnet-4-20250514", DefaultModels.Resolve("claude-sonnet-4-20250514"))
+	assert.Equal(t, "gpt-4o-2024-08-06", DefaultModels.Resolve("gpt-4o-2024-08-06"))
+	assert.Equal(t, "qwen2.5-coder", DefaultModels.Resolve("qwen2.5-coder"))
+}
+
+func TestModelCatalogValidate(t *testing.T) {
+	assert.NoError(t, DefaultModels.Validate("haiku"))
+	assert.ErrorContains(t, DefaultModels.Validate("sonet"), `unknown model "sonet"`)
+}
+
+func TestDefaultModelsHavePrices(t *testing.T) {
+	for _, m := range DefaultModels {
+		_, ok := DefaultPrices.Lookup(m.ID)
+		assert.True(t, ok, "no price for %s", m.ID)
+		for _, alias := range m.Aliases {
+			_, ok := DefaultPrices.Lookup(alias)
+			assert.True(t, ok, "no price for %s", alias)
+		}
+	}
+}
@@ -0,0 +1,663 @@
+// Package openai provides an OpenAI-compatible chat completions implementation
+// of the Provider interface.
+//
+// The provider talks to any server exposing an OpenAI-style
+// /v1/chat/completions endpoint (OpenAI, Azure-compatible gateways, vLLM,
+// Ollama, LiteLLM, etc.), including tool calling and SSE streaming.
+//
+// Usage:
+//
+//	provider, err := openai.New(openai.Config{
+//		BaseURL: "http://localhost:11434/v1",
+//		Model:   "llama3.1",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	resp, err := provider.CreateMessage(ctx, providers.MessageRequest{
+//		System:   "You are a helpful assistant.",
+//		Messages: []providers.Message{providers.NewUserMessage("Hello")},
+//	})
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// DefaultModel is the default model used by the OpenAI provider.
+const DefaultModel = "gpt-4o"
+
+// DefaultBaseURL is the default API base URL.
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// Provider implements the providers.Provider interface using an
+// OpenAI-compatible chat completions API.
+type Provider struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// Config contains configuration for the OpenAI provider.
+type Config struct {
+	// APIKey for the API (defaults to OPENAI_API_KEY env var).
+	// Optional, since many self-hosted compatible servers do not require one.
+	APIKey string
+
+	// BaseURL is the API base URL including the version prefix
+	// (defaults to OPENAI_BASE_URL env var, then https://api.openai.com/v1)
+	BaseURL string
+
+	// Model is used when MessageRequest.Model is empty (defaults to gpt-4o)
+	Model string
+
+	// HTTPClient overrides the HTTP client (optional)
+	HTTPClient *http.Client
+}
+
+// New creates a new OpenAI-compatible provider.
+func New(config Config) (*Provider, error) {
+	apiKey := config.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = os.Getenv("OPENAI_BASE_URL")
+	}
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	model := config.Model
+	if model == "" {
+		model = DefaultModel
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Provider{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "openai"
+}
+
+// Capabilities returns what the Chat Completions API supports for model.
+// Images and documents are not sent by this provider.
+func (p *Provider) Capabilities(model string) providers.Capabilities {
+	if model == "" {
+		model = p.model
+	}
+	caps := providers.Capabilities{
+		ToolCalling:  true,
+		Streaming:    true,
+		SystemPrompt: true,
+	}
+	return caps.WithModel(model)
+}
+
+// APIError is returned when the server responds with a non-2xx status.
+type APIError struct {
+	// StatusCode is the HTTP status code
+	StatusCode int
+
+	// Type is the error type reported by the server (may be empty)
+	Type string
+
+	// Message is the error message reported by the server
+	Message string
+
+	// RetryAfter is the delay requested by the server's Retry-After header (zero if absent)
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("status %d (%s): %s", e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+}
+
+// HTTPStatus implements providers.HTTPError.
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// RetryAfterHint implements providers.HTTPError.
+func (e *APIError) RetryAfterHint() time.Duration {
+	return e.RetryAfter
+}
+
+// CreateMessage sends a message request and returns the complete response.
+func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageRequest) (*providers.MessageResponse, error) {
+	body := p.buildRequest(req, false)
+
+	httpResp, err := p.do(ctx, body)
+	if err != nil {
+		return nil, fmt.Errorf("API call failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp chatResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return convertResponse(&resp), nil
+}
+
+// StreamMessage sends a message request and streams the response via the handler.
+// The response is read as server-sent events and tool call fragments are
+// accumulated into complete tool_use blocks.
+func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
+	body := p.buildRequest(req, true)
+
+	httpResp, err := p.do(ctx, body)
+	if err != nil {
+		return nil, fmt.Errorf("API call failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	thinkingHandler := providers.ThinkingHandlerFrom(ctx)
+
+	var text, reasoning strings.Builder
+	var finishReason string
+	var usage *chatUsage
+	toolCalls := make(map[int]*chatToolCall)
+
+	scanner := bufio.NewScanner(httpResp.Body)
+	// Increase buffer size for large tool arguments
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue // Skip comments, event names and keep-alives
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue // Skip unparseable lines
+		}
+
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Delta.ReasoningContent != "" {
+				reasoning.WriteString(choice.Delta.ReasoningContent)
+				if thinkingHandler != nil {
+					thinkingHandler(choice.Delta.ReasoningContent)
+				}
+			}
+
+			if choice.Delta.Content != "" {
+				text.WriteString(choice.Delta.Content)
+				if handler != nil {
+					handler(choice.Delta.Content)
+				}
+			}
+
+			for _, delta := range choice.Delta.ToolCalls {
+				call, ok := toolCalls[delta.Index]
+				if !ok {
+					call = &chatToolCall{Type: "function"}
+					toolCalls[delta.Index] = call
+				}
+				if delta.ID != "" {
+					call.ID = delta.ID
+				}
+				if delta.Function.Name != "" {
+					call.Function.Name += delta.Function.Name
+				}
+				call.Function.Arguments += delta.Function.Arguments
+			}
+
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	msg := chatMessage{Role: "assistant", Content: text.String(), ReasoningContent: reasoning.String()}
+
+	indexes := make([]int, 0, len(toolCalls))
+	for idx := range toolCalls {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+	for _, idx := range indexes {
+		msg.ToolCalls = append(msg.ToolCalls, *toolCalls[idx])
+	}
+
+	return convertResponse(&chatResponse{
+		Choices: []chatChoice{{Message: msg, FinishReason: finishReason}},
+		Usage:   usage,
+	}), nil
+}
+
+// do sends a chat completions request and returns the HTTP response.
+// Non-2xx responses are converted into an *APIError.
+func (p *Provider) do(ctx context.Context, body chatRequest) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if body.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	httpResp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		defer httpResp.Body.Close()
+		return nil, parseAPIError(httpResp)
+	}
+
+	return httpResp, nil
+}
+
+// parseAPIError builds an APIError from a non-2xx response.
+func parseAPIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: providers.ParseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	var errBody struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &errBody) == nil && errBody.Error.Message != "" {
+		apiErr.Message = errBody.Error.Message
+		apiErr.Type = errBody.Error.Type
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+
+	return apiErr
+}
+
+// buildRequest converts a MessageRequest to a chat completions request body.
+func (p *Provider) buildRequest(req providers.MessageRequest, stream bool) chatRequest {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = providers.DefaultMaxTokens
+	}
+
+	body := chatRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		Stream:    stream,
+	}
+	if stream {
+		body.StreamOptions = &chatStreamOptions{IncludeUsage: true}
+	}
+
+	if req.System != "" {
+		body.Messages = append(body.Messages, chatMessage{Role: "system", Content: req.System})
+	}
+	body.Messages = append(body.Messages, convertMessages(req.Messages)...)
+	body.Tools = convertTools(req.Tools)
+	if len(req.Tools) > 0 {
+		body.ToolChoice = convertToolChoice(req.ToolChoice)
+	}
+
+	return body
+}
+
+// convertToolChoice converts a provider tool choice to the chat completions
+// tool_choice value (nil leaves the choice to the model).
+func convertToolChoice(choice string) any {
+	switch choice {
+	case "":
+		return nil
+	case "any":
+		return "required"
+	default:
+		return map[string]any{
+			"type":     "function",
+			"function": map[string]string{"name": choice},
+		}
+	}
+}
+
+// convertMessages converts provider messages to chat completions messages.
+// Tool results become separate "tool" role messages, since the chat
+// completions format has no tool_result content part.
+func convertMessages(msgs []providers.Message) []chatMessage {
+	var result []chatMessage
+
+	for _, msg := range msgs {
+		var texts []string
+		var toolCalls []chatToolCall
+
+		for _, block := range msg.Content {
+			switch block.Type {
+			case "text":
+				texts = append(texts, block.Text)
+			case "tool_use":
+				args := string(block.Input)
+				if args == "" {
+					args = "{}"
+				}
+				toolCalls = append(toolCalls, chatToolCall{
+					ID:   block.ID,
+					Type: "function",
+					Function: chatFunctionCall{
+						Name:      block.Name,
+						Arguments: args,
+					},
+				})
+			case "tool_result":
+				content := block.Content
+				if block.IsError {
+					content = "Error: " + content
+				}
+				result = append(result, chatMessage{
+					Role:       "tool",
+					ToolCallID: block.ToolUseID,
+					Content:    content,
+				})
+			}
+		}
+
+		if len(texts) == 0 && len(toolCalls) == 0 {
+			continue
+		}
+
+		role := msg.Role
+		if role != "user" {
+			role = "assistant"
+		}
+
+		result = append(result, chatMessage{
+			Role:      role,
+			Content:   strings.Join(texts, "\n"),
+			ToolCalls: toolCalls,
+		})
+	}
+
+	return result
+}
+
+// convertTools converts provider tools to chat completions function tools.
+func convertTools(tools []providers.Tool) []chatTool {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	result := make([]chatTool, 0, len(tools))
+	for _, tool := range tools {
+		properties := tool.InputSchema.Properties
+		if properties == nil {
+			properties = map[string]any{}
+		}
+
+		params := map[string]any{
+			"type":       "object",
+			"properties": properties,
+		}
+		if len(tool.InputSchema.Required) > 0 {
+			params["required"] = tool.InputSchema.Required
+		}
+
+		result = append(result, chatTool{
+			Type: "function",
+			Function: chatFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  params,
+			},
+		})
+	}
+
+	return result
+}
+
+// convertResponse converts a chat completions response to a provider response.
+func convertResponse(resp *chatResponse) *providers.MessageResponse {
+	if resp == nil || len(resp.Choices) == 0 {
+		return &providers.MessageResponse{StopReason: providers.StopReasonEndTurn}
+	}
+
+	choice := resp.Choices[0]
+	result := &providers.MessageResponse{
+		StopReason: convertFinishReason(choice.FinishReason),
+		Usage:      resp.Usage.toUsage(),
+	}
+
+	if choice.Message.ReasoningContent != "" {
+		result.Content = append(result.Content, providers.ContentBlock{
+			Type:     "thinking",
+			Thinking: choice.Message.ReasoningContent,
+		})
+	}
+
+	if choice.Message.Content != "" {
+		result.Content = append(result.Content, providers.ContentBlock{
+			Type: "text",
+			Text: choice.Message.Content,
+		})
+	}
+
+	for _, call := range choice.Message.ToolCalls {
+		args := call.Function.Arguments
+		if args == "" {
+			args = "{}"
+		}
+		result.Content = append(result.Content, providers.ContentBlock{
+			Type:  "tool_use",
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: json.RawMessage(args),
+		})
+	}
+
+	// Some servers report "stop" even when tool calls are present
+	if len(choice.Message.ToolCalls) > 0 && result.StopReason == providers.StopReasonEndTurn {
+		result.StopReason = providers.StopReasonToolUse
+	}
+
+	return result
+}
+
+// convertFinishReason converts a chat completions finish reason to a provider stop reason.
+func convertFinishReason(reason string) providers.StopReason {
+	switch reason {
+	case "stop", "":
+		return providers.StopReasonEndTurn
+	case "tool_calls", "function_call":
+		return providers.StopReasonToolUse
+	case "length":
+		return providers.StopReasonMaxTokens
+	default:
+		return providers.StopReason(reason)
+	}
+}
+
+// chatRequest is the chat completions request body.
+type chatRequest struct {
+	Model     string        `json:"model"`
+	MaxTokens int           `json:"max_tokens,omitempty"`
+	Messages  []chatMessage `json:"messages"`
+	Tools     []chatTool    `json:"tools,omitempty"`
+	Stream    bool          `json:"stream,omitempty"`
+
+	// ToolChoice is "required" or a function selector (nil for auto)
+	ToolChoice any `json:"tool_choice,omitempty"`
+
+	StreamOptions *chatStreamOptions `json:"stream_options,omitempty"`
+}
+
+// chatStreamOptions configures streaming behavior.
+type chatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatUsage reports token usage for a completion.
+type chatUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// toUsage converts chat completions usage to provider usage.
+// Prompt tokens include cached tokens, which are reported separately.
+func (u *chatUsage) toUsage() providers.Usage {
+	if u == nil {
+		return providers.Usage{}
+	}
+	cached := u.PromptTokensDetails.CachedTokens
+	return providers.Usage{
+		InputTokens:          u.PromptTokens - cached,
+		OutputTokens:         u.CompletionTokens,
+		CacheReadInputTokens: cached,
+	}
+}
+
+// chatMessage is a single message in a chat completions request or response.
+type chatMessage struct {
+	Role       string         `json:"role"`
+	Content    string         `json:"content"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+
+	// ReasoningContent is returned by reasoning models on some
+	// OpenAI-compatible servers; it is never sent back
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// chatToolCall is a function call requested by the model.
+type chatToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function chatFunctionCall `json:"function"`
+}
+
+// chatFunctionCall holds the name and JSON-encoded arguments of a call.
+type chatFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// chatTool is a tool definition in a chat completions request.
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+// chatFunction describes a callable function.
+type chatFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// chatResponse is the chat completions response body.
+type chatResponse struct {
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *chatUsage   `json:"usage,omitempty"`
+}
+
+// chatChoice is a single completion choice.
+type chatChoice struct {
+	Index        int         `json:"index"`
+	Message      chatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// chatStreamChunk is a single server-sent event payload in streaming mode.
+type chatStreamChunk struct {
+	ID      string             `json:"id"`
+	Choices []chatStreamChoice `json:"choices"`
+	Usage   *chatUsage         `json:"usage,omitempty"`
+}
+
+// chatStreamChoice is a choice delta in a streaming chunk.
+type chatStreamChoice struct {
+	Index        int             `json:"index"`
+	Delta        chatStreamDelta `json:"delta"`
+	FinishReason string          `json:"finish_reason"`
+}
+
+// chatStreamDelta holds incremental content for a streaming choice.
+type chatStreamDelta struct {
+	Role             string                `json:"role,omitempty"`
+	Content          string                `json:"content,omitempty"`
+	ReasoningContent string                `json:"reasoning_content,omitempty"`
+	ToolCalls        []chatStreamToolDelta `json:"tool_calls,omitempty"`
+}
+
+// chatStreamToolDelta holds an incremental fragment of a tool call.
+type chatStreamToolDelta struct {
+	Index    int              `json:"index"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function chatFunctionCall `json:"function"`
+}
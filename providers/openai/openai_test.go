@@ -0,0 +1,361 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lex00/wetwire-core-go/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderImplementsInterface(t *testing.T) {
+	var _ providers.Provider = (*Provider)(nil)
+}
+
+func TestProviderName(t *testing.T) {
+	p := &Provider{}
+	assert.Equal(t, "openai", p.Name())
+}
+
+func TestNewDefaults(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_BASE_URL", "")
+
+	p, err := New(Config{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultBaseURL, p.baseURL)
+	assert.Equal(t, DefaultModel, p.model)
+	assert.Empty(t, p.apiKey)
+}
+
+func TestNewWithEnv(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "env-key")
+	t.Setenv("OPENAI_BASE_URL", "http://localhost:8000/v1/")
+
+	p, err := New(Config{})
+	require.NoError(t, err)
+	assert.Equal(t, "env-key", p.apiKey)
+	assert.Equal(t, "http://localhost:8000/v1", p.baseURL)
+}
+
+func TestBuildRequest(t *testing.T) {
+	p, err := New(Config{APIKey: "k", Model: "test-model"})
+	require.NoError(t, err)
+
+	req := providers.MessageRequest{
+		System: "You are helpful",
+		Messages: []providers.Message{
+			providers.NewUserMessage("write a file"),
+			providers.NewAssistantMessage([]providers.ContentBlock{
+				{Type: "text", Text: "Sure."},
+				{Type: "tool_use", ID: "call_1", Name: "write_file", Input: json.RawMessage(`{"path":"a.go"}`)},
+			}),
+			providers.NewToolResultMessage([]providers.ContentBlock{
+				providers.NewToolResult("call_1", "disk full", true),
+			}),
+		},
+		Tools: []providers.Tool{
+			{
+				Name:        "write_file",
+				Description: "Write a file",
+				InputSchema: providers.ToolInputSchema{
+					Properties: map[string]any{"path": map[string]any{"type": "string"}},
+					Required:   []string{"path"},
+				},
+			},
+		},
+	}
+
+	body := p.buildRequest(req, false)
+
+	assert.Equal(t, "test-model", body.Model)
+	assert.Equal(t, 4096, body.MaxTokens)
+	require.Len(t, body.Messages, 4)
+
+	assert.Equal(t, "system", body.Messages[0].Role)
+	assert.Equal(t, "You are helpful", body.Messages[0].Content)
+
+	assert.Equal(t, "user", body.Messages[1].Role)
+	assert.Equal(t, "write a file", body.Messages[1].Content)
+
+	assert.Equal(t, "assistant", body.Messages[2].Role)
+	assert.Equal(t, "Sure.", body.Messages[2].Content)
+	require.Len(t, body.Messages[2].ToolCalls, 1)
+	assert.Equal(t, "call_1", body.Messages[2].ToolCalls[0].ID)
+	assert.Equal(t, "write_file", body.Messages[2].ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"path":"a.go"}`, body.Messages[2].ToolCalls[0].Function.Arguments)
+
+	assert.Equal(t, "tool", body.Messages[3].Role)
+	assert.Equal(t, "call_1", body.Messages[3].ToolCallID)
+	assert.Equal(t, "Error: disk full", body.Messages[3].Content)
+
+	require.Len(t, body.Tools, 1)
+	assert.Equal(t, "function", body.Tools[0].Type)
+	assert.Equal(t, "write_file", body.Tools[0].Function.Name)
+	assert.Equal(t, "object", body.Tools[0].Function.Parameters["type"])
+	assert.Equal(t, []string{"path"}, body.Tools[0].Function.Parameters["required"])
+}
+
+func TestBuildRequestToolChoice(t *testing.T) {
+	p, err := New(Config{APIKey: "k"})
+	require.NoError(t, err)
+	tools := []providers.Tool{{Name: "respond"}}
+
+	assert.Nil(t, p.buildRequest(providers.MessageRequest{Tools: tools}, false).ToolChoice)
+	assert.Nil(t, p.buildRequest(providers.MessageRequest{ToolChoice: "any"}, false).ToolChoice)
+	assert.Equal(t, "required", p.buildRequest(providers.MessageRequest{Tools: tools, ToolChoice: "any"}, false).ToolChoice)
+
+	data, err := json.Marshal(p.buildRequest(providers.MessageRequest{Tools: tools, ToolChoice: "respond"}, false))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"tool_choice":{"function":{"name":"respond"},"type":"function"}`)
+}
+
+func TestConvertFinishReason(t *testing.T) {
+	tests := []struct {
+		reason   string
+		expected providers.StopReason
+	}{
+		{"stop", providers.StopReasonEndTurn},
+		{"", providers.StopReasonEndTurn},
+		{"tool_calls", providers.StopReasonToolUse},
+		{"function_call", providers.StopReasonToolUse},
+		{"length", providers.StopReasonMaxTokens},
+		{"content_filter", providers.StopReason("content_filter")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			assert.Equal(t, tt.expected, convertFinishReason(tt.reason))
+		})
+	}
+}
+
+func TestCreateMessageText(t *testing.T) {
+	var received chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{
+			"id": "chatcmpl-1",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hello!"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 120, "completion_tokens": 8, "prompt_tokens_details": {"cached_tokens": 100}}
+		}`)
+	}))
+	defer server.Close()
+
+	p, err := New(Config{APIKey: "test-key", BaseURL: server.URL + "/v1"})
+	require.NoError(t, err)
+
+	resp, err := p.CreateMessage(context.Background(), providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("hi")},
+	})
+	require.NoError(t, err)
+
+	assert.False(t, received.Stream)
+	assert.Equal(t, DefaultModel, received.Model)
+	assert.Equal(t, providers.StopReasonEndTurn, resp.StopReason)
+	require.Len(t, resp.Content, 1)
+	assert.Equal(t, "text", resp.Content[0].Type)
+	assert.Equal(t, "Hello!", resp.Content[0].Text)
+	assert.Equal(t, providers.Usage{InputTokens: 20, OutputTokens: 8, CacheReadInputTokens: 100}, resp.Usage)
+}
+
+func TestCreateMessageToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{
+			"choices": [{
+				"message": {
+					"role": "assistant",
+					"content": null,
+					"tool_calls": [{"id": "call_abc", "type": "function", "function": {"name": "run_lint", "arguments": "{\"path\":\".\"}"}}]
+				},
+				"finish_reason": "tool_calls"
+			}]
+		}`)
+	}))
+	defer server.Close()
+
+	p, err := New(Config{BaseURL: server.URL})
+	require.NoError(t, err)
+
+	resp, err := p.CreateMessage(context.Background(), providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("lint")},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, providers.StopReasonToolUse, resp.StopReason)
+	require.Len(t, resp.Content, 1)
+	assert.Equal(t, "tool_use", resp.Content[0].Type)
+	assert.Equal(t, "call_abc", resp.Content[0].ID)
+	assert.Equal(t, "run_lint", resp.Content[0].Name)
+	assert.JSONEq(t, `{"path":"."}`, string(resp.Content[0].Input))
+}
+
+func TestCreateMessageAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"error": {"message": "invalid api key", "type": "invalid_request_error"}}`)
+	}))
+	defer server.Close()
+
+	p, err := New(Config{BaseURL: server.URL})
+	require.NoError(t, err)
+
+	_, err = p.CreateMessage(context.Background(), providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("hi")},
+	})
+	require.Error(t, err)
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.Equal(t, "invalid api key", apiErr.Message)
+	assert.Equal(t, "invalid_request_error", apiErr.Type)
+}
+
+func TestStreamMessage(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":"Let me "}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"check."}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read_file","arguments":""}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"main.go\"}"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"run_lint","arguments":"{}"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":50,"completion_tokens":30}}`,
+	}
+
+	var received chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, ": keep-alive\n\n")
+		for _, c := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p, err := New(Config{BaseURL: server.URL})
+	require.NoError(t, err)
+
+	var streamed []string
+	resp, err := p.StreamMessage(context.Background(), providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("read main.go")},
+	}, func(text string) {
+		streamed = append(streamed, text)
+	})
+	require.NoError(t, err)
+
+	assert.True(t, received.Stream)
+	require.NotNil(t, received.StreamOptions)
+	assert.True(t, received.StreamOptions.IncludeUsage)
+	assert.Equal(t, providers.Usage{InputTokens: 50, OutputTokens: 30}, resp.Usage)
+	assert.Equal(t, []string{"Let me ", "check."}, streamed)
+	assert.Equal(t, providers.StopReasonToolUse, resp.StopReason)
+
+	require.Len(t, resp.Content, 3)
+	assert.Equal(t, "text", resp.Content[0].Type)
+	assert.Equal(t, "Let me check.", resp.Content[0].Text)
+	assert.Equal(t, "tool_use", resp.Content[1].Type)
+	assert.Equal(t, "call_1", resp.Content[1].ID)
+	assert.Equal(t, "read_file", resp.Content[1].Name)
+	assert.JSONEq(t, `{"path":"main.go"}`, string(resp.Content[1].Input))
+	assert.Equal(t, "call_2", resp.Content[2].ID)
+	assert.Equal(t, "run_lint", resp.Content[2].Name)
+}
+
+func TestStreamMessageReasoning(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Think "}}]}`,
+		`{"choices":[{"index":0,"delta":{"reasoning_content":"first."}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"Answer"},"finish_reason":"stop"}]}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p, err := New(Config{BaseURL: server.URL})
+	require.NoError(t, err)
+
+	var streamed, thinking []string
+	ctx := providers.WithThinkingHandler(context.Background(), func(text string) {
+		thinking = append(thinking, text)
+	})
+	resp, err := p.StreamMessage(ctx, providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("hi")},
+	}, func(text string) {
+		streamed = append(streamed, text)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Answer"}, streamed)
+	assert.Equal(t, []string{"Think ", "first."}, thinking)
+	require.Len(t, resp.Content, 2)
+	assert.Equal(t, "thinking", resp.Content[0].Type)
+	assert.Equal(t, "Think first.", resp.Content[0].Thinking)
+	assert.Equal(t, "Answer", resp.Content[1].Text)
+
+	// Reasoning is not sent back to the server
+	messages := convertMessages([]providers.Message{providers.NewAssistantMessage(resp.Content)})
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Answer", messages[0].Content)
+	assert.Empty(t, messages[0].ReasoningContent)
+}
+
+func TestStreamMessageAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, "upstream unavailable")
+	}))
+	defer server.Close()
+
+	p, err := New(Config{BaseURL: server.URL})
+	require.NoError(t, err)
+
+	_, err = p.StreamMessage(context.Background(), providers.MessageRequest{
+		Messages: []providers.Message{providers.NewUserMessage("hi")},
+	}, nil)
+	require.Error(t, err)
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Equal(t, "upstream unavailable", apiErr.Message)
+}
+
+func TestAPIErrorRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"error": {"message": "rate limited", "type": "rate_limit_error"}}`)
+	}))
+	defer server.Close()
+
+	p, err := New(Config{BaseURL: server.URL})
+	require.NoError(t, err)
+
+	_, err = p.CreateMessage(context.Background(), providers.MessageRequest{})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Equal(t, 2*time.Second, apiErr.RetryAfter)
+}
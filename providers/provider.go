@@ -27,6 +27,22 @@ type Provider interface {
 // StreamHandler is called for each text chunk during streaming.
 type StreamHandler func(text string)
 
+// thinkingHandlerKey is the context key for the thinking stream handler.
+type thinkingHandlerKey struct{}
+
+// WithThinkingHandler returns a context that streams thinking chunks to handler.
+// Thinking is streamed separately from response text so that callers can
+// display or discard it independently. Providers without thinking ignore it.
+func WithThinkingHandler(ctx context.Context, handler StreamHandler) context.Context {
+	return context.WithValue(ctx, thinkingHandlerKey{}, handler)
+}
+
+// ThinkingHandlerFrom returns the thinking stream handler set on ctx, or nil.
+func ThinkingHandlerFrom(ctx context.Context) StreamHandler {
+	handler, _ := ctx.Value(thinkingHandlerKey{}).(StreamHandler)
+	return handler
+}
+
 // MessageRequest contains the parameters for creating a message.
 type MessageRequest struct {
 	// Model identifier (e.g., "claude-sonnet-4-20250514")
@@ -43,6 +59,39 @@ type MessageRequest struct {
 
 	// Tools available to the model
 	Tools []Tool
+
+	// ToolChoice controls tool use: "" lets the model decide, "any" requires
+	// a tool call, and a tool name requires a call to that tool.
+	// Providers without tool calling ignore it.
+	ToolChoice string
+
+	// CacheSystem marks a prompt cache breakpoint after the system prompt,
+	// caching the tools and system prompt together
+	CacheSystem bool
+
+	// ThinkingBudget enables extended thinking with the given token budget
+	// (0 disables thinking). Providers without extended thinking ignore it.
+	ThinkingBudget int
+}
+
+// HasCacheBreakpoints returns true if the request sets any cache breakpoint.
+func (r MessageRequest) HasCacheBreakpoints() bool {
+	if r.CacheSystem {
+		return true
+	}
+	for _, tool := range r.Tools {
+		if tool.CacheBreakpoint {
+			return true
+		}
+	}
+	for _, msg := range r.Messages {
+		for _, block := range msg.Content {
+			if block.CacheBreakpoint {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // MessageResponse contains the response from the AI.
@@ -52,6 +101,17 @@ type MessageResponse struct {
 
 	// StopReason indicates why the model stopped generating
 	StopReason StopReason
+
+	// Usage reports the tokens consumed by the request (zero if unknown)
+	Usage Usage
+
+	// Provider is the name of the backend that served the request.
+	// Set by Router; empty when the request was served directly.
+	Provider string
+
+	// Model is the model the request was sent to after routing.
+	// Set by Router; empty when the request was served directly.
+	Model string
 }
 
 // Message represents a conversation message.
@@ -65,7 +125,8 @@ type Message struct {
 
 // ContentBlock represents a content block in a message.
 type ContentBlock struct {
-	// Type is "text" or "tool_use" or "tool_result"
+	// Type is "text", "tool_use", "tool_result", "thinking", "redacted_thinking",
+	// "image" or "document"
 	Type string
 
 	// Text content (for Type="text")
@@ -80,6 +141,25 @@ type ContentBlock struct {
 	ToolUseID string
 	Content   string
 	IsError   bool
+
+	// Thinking fields (for Type="thinking").
+	// Signature must be echoed back unchanged in tool-use continuations.
+	Thinking  string
+	Signature string
+
+	// Data is the encrypted reasoning (for Type="redacted_thinking")
+	Data string
+
+	// Media fields (for Type="image" or Type="document").
+	// Base64 holds the encoded content; alternatively Path names a local file.
+	MediaType string
+	Base64    string
+	Path      string
+
+	// CacheBreakpoint marks a prompt cache breakpoint after this block,
+	// caching the conversation prefix up to and including it.
+	// Providers without prompt caching ignore it.
+	CacheBreakpoint bool
 }
 
 // Tool defines a tool that can be used by the model.
@@ -92,6 +172,11 @@ type Tool struct {
 
 	// InputSchema defines the JSON schema for tool input
 	InputSchema ToolInputSchema
+
+	// CacheBreakpoint marks a prompt cache breakpoint after this tool,
+	// caching the tool list up to and including it.
+	// Providers without prompt caching ignore it.
+	CacheBreakpoint bool
 }
 
 // ToolInputSchema defines the JSON schema for tool parameters.
@@ -146,6 +231,11 @@ func NewToolResultMessage(results []ContentBlock) Message {
 	}
 }
 
+// IsThinking returns true for thinking and redacted_thinking blocks.
+func (b ContentBlock) IsThinking() bool {
+	return b.Type == "thinking" || b.Type == "redacted_thinking"
+}
+
 // NewToolResult creates a tool result content block.
 func NewToolResult(toolUseID, content string, isError bool) ContentBlock {
 	return ContentBlock{
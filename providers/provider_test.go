@@ -230,3 +230,20 @@ func TestMessageRequestWithTools(t *testing.T) {
 	require.Len(t, req.Tools, 1)
 	assert.Equal(t, "read_file", req.Tools[0].Name)
 }
+
+func TestMessageRequestHasCacheBreakpoints(t *testing.T) {
+	req := MessageRequest{
+		Tools:    []Tool{{Name: "read_file"}},
+		Messages: []Message{NewUserMessage("hello")},
+	}
+	assert.False(t, req.HasCacheBreakpoints())
+
+	req.Tools[0].CacheBreakpoint = true
+	assert.True(t, req.HasCacheBreakpoints())
+
+	req.Tools[0].CacheBreakpoint = false
+	req.Messages[0].Content[0].CacheBreakpoint = true
+	assert.True(t, req.HasCacheBreakpoints())
+
+	assert.True(t, MessageRequest{CacheSystem: true}.HasCacheBreakpoints())
+}
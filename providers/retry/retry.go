@@ -0,0 +1,343 @@
+// Package retry provides a Provider wrapper that retries transient failures.
+//
+// The wrapper classifies errors returned by the wrapped provider (HTTP 429,
+// 5xx and overloaded responses, connection resets, and error results from
+// the Claude CLI that name such a status), retries them with jittered exponential backoff that honors
+// retry-after hints, and optionally enforces a client-side concurrency and
+// request-rate limit that can be shared across several wrapped providers.
+//
+// Usage:
+//
+//	limiter := retry.NewLimiter(retry.LimiterConfig{
+//		MaxConcurrent:     4,
+//		RequestsPerMinute: 50,
+//	})
+//
+//	provider := retry.New(anthropicProvider, retry.Config{
+//		MaxAttempts: 5,
+//		Limiter:     limiter,
+//	})
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// Default retry settings.
+const (
+	DefaultMaxAttempts = 4
+	DefaultBaseDelay   = time.Second
+	DefaultMaxDelay    = 30 * time.Second
+)
+
+// Decision is the result of classifying an error.
+type Decision struct {
+	// Retry is true if the call should be retried
+	Retry bool
+
+	// RetryAfter is a server-provided minimum delay before retrying (zero if none)
+	RetryAfter time.Duration
+}
+
+// Classifier decides whether an error is transient.
+type Classifier func(err error) Decision
+
+// Config contains configuration for the retry wrapper.
+type Config struct {
+	// MaxAttempts is the total number of attempts including the first (default: 4)
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry (default: 1s)
+	BaseDelay time.Duration
+
+	// MaxDelay caps the exponential backoff delay (default: 30s).
+	// Retry-after hints from the server may exceed it.
+	MaxDelay time.Duration
+
+	// Classifier overrides the default error classification (optional)
+	Classifier Classifier
+
+	// Limiter enforces a concurrency and rate limit (optional).
+	// Share one Limiter across providers to limit them jointly.
+	Limiter *Limiter
+
+	// OnRetry is called before each retry with the attempt number that
+	// failed, its error and the delay before the next attempt (optional)
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Provider wraps a providers.Provider with retries and rate limiting.
+type Provider struct {
+	provider providers.Provider
+	config   Config
+}
+
+// New wraps a provider with retry and rate-limit handling.
+func New(provider providers.Provider, config Config) *Provider {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.BaseDelay <= 0 {
+		config.BaseDelay = DefaultBaseDelay
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = DefaultMaxDelay
+	}
+	if config.Classifier == nil {
+		config.Classifier = Classify
+	}
+
+	return &Provider{
+		provider: provider,
+		config:   config,
+	}
+}
+
+// Name returns the wrapped provider's name.
+func (p *Provider) Name() string {
+	return p.provider.Name()
+}
+
+// Unwrap returns the wrapped provider.
+func (p *Provider) Unwrap() providers.Provider {
+	return p.provider
+}
+
+// CreateMessage sends a message request, retrying transient failures.
+func (p *Provider) CreateMessage(ctx context.Context, req providers.MessageRequest) (*providers.MessageResponse, error) {
+	return p.do(ctx, func() (*providers.MessageResponse, bool, error) {
+		resp, err := p.provider.CreateMessage(ctx, req)
+		return resp, true, err
+	})
+}
+
+// StreamMessage sends a streaming request, retrying transient failures.
+// A stream is only retried if it failed before any text or thinking reached
+// a handler, so callers never see duplicated output.
+func (p *Provider) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
+	return p.do(ctx, func() (*providers.MessageResponse, bool, error) {
+		delivered := false
+		callCtx := ctx
+		if thinking := providers.ThinkingHandlerFrom(ctx); thinking != nil {
+			callCtx = providers.WithThinkingHandler(ctx, func(text string) {
+				delivered = true
+				thinking(text)
+			})
+		}
+		resp, err := p.provider.StreamMessage(callCtx, req, func(text string) {
+			delivered = true
+			if handler != nil {
+				handler(text)
+			}
+		})
+		return resp, !delivered, err
+	})
+}
+
+// do runs call until it succeeds, fails permanently or attempts are exhausted.
+// The call reports whether it is safe to retry after a failure.
+func (p *Provider) do(ctx context.Context, call func() (*providers.MessageResponse, bool, error)) (*providers.MessageResponse, error) {
+	var lastErr error
+
+	for attempt := 1; attempt <= p.config.MaxAttempts; attempt++ {
+		release, err := p.config.Limiter.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, retryable, err := call()
+		release()
+
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		decision := p.config.Classifier(err)
+		if !decision.Retry || !retryable || attempt == p.config.MaxAttempts {
+			break
+		}
+
+		delay := p.backoff(attempt)
+		if decision.RetryAfter > delay {
+			delay = decision.RetryAfter
+		}
+
+		if p.config.OnRetry != nil {
+			p.config.OnRetry(attempt, err, delay)
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, lastErr
+}
+
+// backoff returns the jittered exponential delay after the given attempt.
+// The delay is drawn uniformly from [d/2, d] where d doubles each attempt.
+func (p *Provider) backoff(attempt int) time.Duration {
+	d := p.config.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.config.MaxDelay {
+		d = p.config.MaxDelay
+	}
+
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Classify is the default Classifier.
+func Classify(err error) Decision {
+	if err == nil {
+		return Decision{}
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return Decision{}
+	}
+
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		var retryAfter time.Duration
+		if anthropicErr.Response != nil {
+			retryAfter = providers.ParseRetryAfter(anthropicErr.Response.Header.Get("Retry-After"))
+		}
+		return Decision{Retry: retryableStatus(anthropicErr.StatusCode), RetryAfter: retryAfter}
+	}
+
+	var httpErr providers.HTTPError
+	if errors.As(err, &httpErr) {
+		return Decision{Retry: retryableStatus(httpErr.HTTPStatus()), RetryAfter: httpErr.RetryAfterHint()}
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return Decision{Retry: true}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return Decision{Retry: true}
+	}
+
+	return Decision{}
+}
+
+// retryableStatus reports whether an HTTP status code indicates a transient failure.
+func retryableStatus(code int) bool {
+	switch {
+	case code == http.StatusRequestTimeout, code == http.StatusConflict, code == http.StatusTooManyRequests:
+		return true
+	case code >= 500:
+		return true
+	default:
+		return false
+	}
+}
+
+// LimiterConfig contains configuration for a Limiter.
+type LimiterConfig struct {
+	// MaxConcurrent is the maximum number of in-flight requests (0 = unlimited)
+	MaxConcurrent int
+
+	// RequestsPerMinute is the maximum request start rate (0 = unlimited)
+	RequestsPerMinute int
+}
+
+// Limiter enforces a client-side concurrency and request-rate limit.
+// A nil *Limiter imposes no limit.
+type Limiter struct {
+	sem      chan struct{}
+	interval time.Duration
+	next     chan time.Time
+}
+
+// NewLimiter creates a limiter that can be shared by several providers.
+func NewLimiter(config LimiterConfig) *Limiter {
+	l := &Limiter{}
+
+	if config.MaxConcurrent > 0 {
+		l.sem = make(chan struct{}, config.MaxConcurrent)
+	}
+
+	if config.RequestsPerMinute > 0 {
+		l.interval = time.Minute / time.Duration(config.RequestsPerMinute)
+		l.next = make(chan time.Time, 1)
+		l.next <- time.Time{}
+	}
+
+	return l
+}
+
+// Acquire blocks until a request may start and returns a function that must
+// be called when the request completes.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {
+		if l.sem != nil {
+			<-l.sem
+		}
+	}
+
+	if l.next != nil {
+		var slot time.Time
+		select {
+		case slot = <-l.next:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+
+		now := time.Now()
+		if slot.Before(now) {
+			slot = now
+		}
+		l.next <- slot.Add(l.interval)
+
+		if err := sleep(ctx, time.Until(slot)); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
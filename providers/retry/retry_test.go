@@ -0,0 +1,288 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/lex00/wetwire-core-go/providers"
+	"github.com/lex00/wetwire-core-go/providers/claude"
+	"github.com/lex00/wetwire-core-go/providers/fake"
+	"github.com/lex00/wetwire-core-go/providers/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fastConfig() Config {
+	return Config{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+	}
+}
+
+func request() providers.MessageRequest {
+	return providers.MessageRequest{Messages: []providers.Message{providers.NewUserMessage("hi")}}
+}
+
+func TestProviderImplementsInterface(t *testing.T) {
+	var _ providers.Provider = (*Provider)(nil)
+}
+
+func TestNameDelegates(t *testing.T) {
+	p := New(fake.New().WithName("anthropic"), Config{})
+	assert.Equal(t, "anthropic", p.Name())
+	assert.Equal(t, DefaultMaxAttempts, p.config.MaxAttempts)
+}
+
+func TestRetriesTransientErrors(t *testing.T) {
+	inner := fake.New().
+		FailOnTurn(1, &openai.APIError{StatusCode: http.StatusTooManyRequests}).
+		FailOnTurn(2, &claude.ResultError{Message: "API Error: 529 overloaded_error", StatusCode: 529}).
+		ReplyText("ok")
+
+	var retries []int
+	config := fastConfig()
+	config.OnRetry = func(attempt int, err error, delay time.Duration) {
+		retries = append(retries, attempt)
+	}
+
+	resp, err := New(inner, config).CreateMessage(context.Background(), request())
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Content[0].Text)
+	assert.Equal(t, []int{1, 2}, retries)
+	assert.Len(t, inner.Transcript(), 3)
+}
+
+func TestDoesNotRetryPermanentErrors(t *testing.T) {
+	inner := fake.New().
+		FailOnTurn(1, &openai.APIError{StatusCode: http.StatusBadRequest, Message: "bad request"}).
+		ReplyText("unused")
+
+	_, err := New(inner, fastConfig()).CreateMessage(context.Background(), request())
+	require.Error(t, err)
+	assert.Len(t, inner.Transcript(), 1)
+}
+
+func TestGivesUpAfterMaxAttempts(t *testing.T) {
+	blip := &openai.APIError{StatusCode: http.StatusServiceUnavailable}
+	inner := fake.New().
+		FailOnTurn(1, blip).
+		FailOnTurn(2, blip).
+		FailOnTurn(3, blip).
+		ReplyText("unused")
+
+	_, err := New(inner, fastConfig()).CreateMessage(context.Background(), request())
+	require.ErrorIs(t, err, blip)
+	assert.Len(t, inner.Transcript(), 3)
+}
+
+func TestHonorsRetryAfter(t *testing.T) {
+	inner := fake.New().
+		FailOnTurn(1, &openai.APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 20 * time.Millisecond}).
+		ReplyText("ok")
+
+	var delay time.Duration
+	config := fastConfig()
+	config.OnRetry = func(attempt int, err error, d time.Duration) { delay = d }
+
+	start := time.Now()
+	_, err := New(inner, config).CreateMessage(context.Background(), request())
+	require.NoError(t, err)
+	assert.Equal(t, 20*time.Millisecond, delay)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestStopsOnContextCancel(t *testing.T) {
+	inner := fake.New().
+		FailOnTurn(1, syscall.ECONNRESET).
+		ReplyText("unused")
+
+	config := fastConfig()
+	config.BaseDelay = time.Hour
+	config.MaxDelay = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	config.OnRetry = func(int, error, time.Duration) { cancel() }
+
+	_, err := New(inner, config).CreateMessage(ctx, request())
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// partialStream delivers a chunk and then fails.
+// partialStream fails after streaming a text chunk, or a thinking chunk if
+// thinking is set.
+type partialStream struct {
+	calls    int
+	thinking bool
+}
+
+func (p *partialStream) Name() string { return "partial" }
+
+func (p *partialStream) CreateMessage(ctx context.Context, req providers.MessageRequest) (*providers.MessageResponse, error) {
+	return nil, errors.New("not used")
+}
+
+func (p *partialStream) StreamMessage(ctx context.Context, req providers.MessageRequest, handler providers.StreamHandler) (*providers.MessageResponse, error) {
+	p.calls++
+	if p.thinking {
+		providers.ThinkingHandlerFrom(ctx)("pondering ")
+	} else {
+		handler("partial ")
+	}
+	return nil, syscall.ECONNRESET
+}
+
+func TestStreamNotRetriedAfterOutput(t *testing.T) {
+	inner := &partialStream{}
+
+	var chunks []string
+	_, err := New(inner, fastConfig()).StreamMessage(context.Background(), request(), func(text string) {
+		chunks = append(chunks, text)
+	})
+	require.ErrorIs(t, err, syscall.ECONNRESET)
+	assert.Equal(t, 1, inner.calls)
+	assert.Equal(t, []string{"partial "}, chunks)
+}
+
+func TestStreamNotRetriedAfterThinking(t *testing.T) {
+	inner := &partialStream{thinking: true}
+
+	var thinking []string
+	ctx := providers.WithThinkingHandler(context.Background(), func(text string) {
+		thinking = append(thinking, text)
+	})
+	_, err := New(inner, fastConfig()).StreamMessage(ctx, request(), nil)
+	require.ErrorIs(t, err, syscall.ECONNRESET)
+	assert.Equal(t, 1, inner.calls)
+	assert.Equal(t, []string{"pondering "}, thinking)
+}
+
+func TestStreamRetriedBeforeOutput(t *testing.T) {
+	inner := fake.New().
+		FailOnTurn(1, syscall.ECONNRESET).
+		ReplyText("streamed")
+
+	var chunks []string
+	resp, err := New(inner, fastConfig()).StreamMessage(context.Background(), request(), func(text string) {
+		chunks = append(chunks, text)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "streamed", resp.Content[0].Text)
+	assert.Equal(t, []string{"streamed"}, chunks)
+}
+
+func TestClassify(t *testing.T) {
+	anthropicErr := func(code int, header http.Header) error {
+		return fmt.Errorf("API call failed: %w", &anthropic.Error{
+			StatusCode: code,
+			Response:   &http.Response{StatusCode: code, Header: header},
+		})
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		retry      bool
+		retryAfter time.Duration
+	}{
+		{"nil", nil, false, 0},
+		{"canceled", context.Canceled, false, 0},
+		{"deadline", fmt.Errorf("wrapped: %w", context.DeadlineExceeded), false, 0},
+		{"anthropic 429", anthropicErr(429, http.Header{"Retry-After": []string{"3"}}), true, 3 * time.Second},
+		{"anthropic 529", anthropicErr(529, http.Header{}), true, 0},
+		{"anthropic 401", anthropicErr(401, http.Header{}), false, 0},
+		{"openai 500", &openai.APIError{StatusCode: 500}, true, 0},
+		{"openai 404", &openai.APIError{StatusCode: 404}, false, 0},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true, 0},
+		{"claude overloaded", &claude.ResultError{Message: "API Error: 529 overloaded_error", StatusCode: 529}, true, 0},
+		{"claude max turns", &claude.ResultError{Subtype: "error_max_turns"}, false, 0},
+		{"cli exit", fmt.Errorf("claude execution failed: %w", &exec.ExitError{}), false, 0},
+		{"cli model text", errors.New("claude execution failed: rate limit 429 timeout"), false, 0},
+		{"claude missing", errors.New("claude CLI not found in PATH"), false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Classify(tt.err)
+			assert.Equal(t, tt.retry, d.Retry)
+			assert.Equal(t, tt.retryAfter, d.RetryAfter)
+		})
+	}
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	limiter := NewLimiter(LimiterConfig{MaxConcurrent: 2})
+
+	var inFlight, maxInFlight int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.Acquire(context.Background())
+			require.NoError(t, err)
+			defer release()
+
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, maxInFlight, int32(2))
+}
+
+func TestLimiterRate(t *testing.T) {
+	// 6000 per minute = one request every 10ms
+	limiter := NewLimiter(LimiterConfig{RequestsPerMinute: 6000})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		release, err := limiter.Acquire(context.Background())
+		require.NoError(t, err)
+		release()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+}
+
+func TestLimiterSharedAcrossProviders(t *testing.T) {
+	limiter := NewLimiter(LimiterConfig{MaxConcurrent: 1})
+
+	release, err := limiter.Acquire(context.Background())
+	require.NoError(t, err)
+
+	// A second provider sharing the limiter blocks until the slot is released
+	p := New(fake.New().ReplyText("ok"), Config{Limiter: limiter})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = p.CreateMessage(ctx, request())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	_, err = p.CreateMessage(context.Background(), request())
+	require.NoError(t, err)
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	release, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
@@ -0,0 +1,319 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Default router settings.
+const (
+	DefaultMaxFailures = 3
+	DefaultCooldown    = time.Minute
+)
+
+// ErrNoBackend is returned when a Router has no backend for a request.
+var ErrNoBackend = errors.New("router: no backend available")
+
+// Backend is a provider registered with a Router.
+type Backend struct {
+	// Provider serves requests for this backend
+	Provider Provider
+
+	// Name identifies the backend in routes and responses (default: Provider.Name())
+	Name string
+
+	// HealthCheck reports whether the backend can currently serve requests (optional).
+	// For example, claude.Available for the Claude CLI.
+	HealthCheck func(ctx context.Context) error
+}
+
+// Route sends requests for a model alias to a backend.
+type Route struct {
+	// Backend is the name of the backend to use
+	Backend string
+
+	// Model replaces the requested model when sent to the backend (optional)
+	Model string
+}
+
+// RouterConfig contains configuration for a Router.
+type RouterConfig struct {
+	// Backends is the default fallback chain, in order of preference
+	Backends []Backend
+
+	// Routes maps model aliases to an ordered chain of routes.
+	// Models without a route are tried against all backends in order.
+	//
+	// Example:
+	//
+	//	Routes: map[string][]providers.Route{
+	//		"haiku":  {{Backend: "anthropic", Model: "claude-3-5-haiku-latest"}},
+	//		"sonnet": {{Backend: "claude"}, {Backend: "anthropic", Model: "claude-sonnet-4-20250514"}},
+	//		"local":  {{Backend: "openai", Model: "qwen2.5-coder"}},
+	//	}
+	Routes map[string][]Route
+
+	// MaxFailures is the number of consecutive failures after which a backend
+	// is skipped for Cooldown (default: 3)
+	MaxFailures int
+
+	// Cooldown is how long a failing backend is skipped (default: 1m)
+	Cooldown time.Duration
+
+	// OnFallback is called when a backend fails and the next one is tried (optional)
+	OnFallback func(backend string, err error)
+}
+
+// Router is a Provider that dispatches requests across several backends.
+//
+// Requests are routed by model alias and fall back along the route chain
+// when a backend fails its health check, errors, or has failed persistently.
+// MessageResponse.Provider reports the backend that served each request.
+type Router struct {
+	config   RouterConfig
+	backends map[string]Backend
+
+	mu     sync.Mutex
+	health map[string]*backendHealth
+}
+
+// backendHealth tracks consecutive failures of a backend.
+type backendHealth struct {
+	failures  int
+	downUntil time.Time
+}
+
+// routeTarget is a resolved backend and the model to send it.
+type routeTarget struct {
+	backend Backend
+	model   string
+}
+
+// NewRouter creates a router over the given backends.
+func NewRouter(config RouterConfig) (*Router, error) {
+	if len(config.Backends) == 0 {
+		return nil, fmt.Errorf("router requires at least one backend")
+	}
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = DefaultMaxFailures
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = DefaultCooldown
+	}
+
+	config.Backends = slices.Clone(config.Backends)
+
+	r := &Router{
+		config:   config,
+		backends: make(map[string]Backend),
+		health:   make(map[string]*backendHealth),
+	}
+
+	for i, b := range config.Backends {
+		if b.Provider == nil {
+			return nil, fmt.Errorf("backend %d has no provider", i)
+		}
+		if b.Name == "" {
+			b.Name = b.Provider.Name()
+		}
+		if _, exists := r.backends[b.Name]; exists {
+			return nil, fmt.Errorf("duplicate backend %q", b.Name)
+		}
+		r.backends[b.Name] = b
+		r.config.Backends[i] = b
+		r.health[b.Name] = &backendHealth{}
+	}
+
+	for alias, routes := range config.Routes {
+		for _, route := range routes {
+			if _, ok := r.backends[route.Backend]; !ok {
+				return nil, fmt.Errorf("route %q references unknown backend %q", alias, route.Backend)
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// Name returns the provider name.
+func (r *Router) Name() string {
+	return "router"
+}
+
+// Capabilities returns the capabilities shared by every backend that may
+// serve model, since any of them can end up handling a request.
+func (r *Router) Capabilities(model string) Capabilities {
+	var caps Capabilities
+	for i, target := range r.chain(model) {
+		m := model
+		if target.model != "" {
+			m = target.model
+		}
+		c := CapabilitiesOf(target.backend.Provider, m)
+		if i == 0 {
+			caps = c
+		} else {
+			caps = caps.Merge(c)
+		}
+	}
+	return caps
+}
+
+// CreateMessage sends a message request to the first available backend.
+func (r *Router) CreateMessage(ctx context.Context, req MessageRequest) (*MessageResponse, error) {
+	return r.do(ctx, req, func(p Provider, req MessageRequest) (*MessageResponse, bool, error) {
+		resp, err := p.CreateMessage(ctx, req)
+		return resp, true, err
+	})
+}
+
+// StreamMessage streams a message request from the first available backend.
+// A backend is only abandoned if it failed before any text reached the handler,
+// so callers never see output from two backends in one response.
+func (r *Router) StreamMessage(ctx context.Context, req MessageRequest, handler StreamHandler) (*MessageResponse, error) {
+	return r.do(ctx, req, func(p Provider, req MessageRequest) (*MessageResponse, bool, error) {
+		delivered := false
+		resp, err := p.StreamMessage(ctx, req, func(text string) {
+			delivered = true
+			if handler != nil {
+				handler(text)
+			}
+		})
+		return resp, !delivered, err
+	})
+}
+
+// do tries each target for the request until one succeeds.
+// The call reports whether it is safe to fall back after a failure.
+func (r *Router) do(ctx context.Context, req MessageRequest, call func(Provider, MessageRequest) (*MessageResponse, bool, error)) (*MessageResponse, error) {
+	targets := r.targets(ctx, req.Model)
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%w for model %q", ErrNoBackend, req.Model)
+	}
+
+	var errs []error
+	for i, target := range targets {
+		routed := req
+		if target.model != "" {
+			routed.Model = target.model
+		}
+
+		resp, fallback, err := call(target.backend.Provider, routed)
+		if err == nil {
+			r.recordSuccess(target.backend.Name)
+			if resp.Provider == "" {
+				resp.Provider = target.backend.Name
+			}
+			if resp.Model == "" {
+				resp.Model = routed.Model
+			}
+			return resp, nil
+		}
+
+		// A cancelled or expired context says nothing about the backend.
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+
+		r.recordFailure(target.backend.Name)
+		errs = append(errs, fmt.Errorf("%s: %w", target.backend.Name, err))
+
+		if ctx.Err() != nil || !fallback {
+			break
+		}
+		if i < len(targets)-1 && r.config.OnFallback != nil {
+			r.config.OnFallback(target.backend.Name, err)
+		}
+	}
+
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+	return nil, fmt.Errorf("all backends failed: %w", errors.Join(errs...))
+}
+
+// targets returns the healthy targets for a model in order of preference.
+// If every target is unhealthy they are all returned, so a recovered backend
+// is not locked out until its cooldown expires.
+func (r *Router) targets(ctx context.Context, model string) []routeTarget {
+	var healthy, degraded []routeTarget
+	for _, target := range r.chain(model) {
+		if hc := target.backend.HealthCheck; hc != nil && hc(ctx) != nil {
+			continue
+		}
+		if r.isDown(target.backend.Name) {
+			degraded = append(degraded, target)
+			continue
+		}
+		healthy = append(healthy, target)
+	}
+
+	if len(healthy) == 0 {
+		return degraded
+	}
+	return healthy
+}
+
+// chain returns every target for a model in order of preference.
+func (r *Router) chain(model string) []routeTarget {
+	var chain []routeTarget
+	if routes, ok := r.config.Routes[model]; ok {
+		for _, route := range routes {
+			chain = append(chain, routeTarget{backend: r.backends[route.Backend], model: route.Model})
+		}
+	} else {
+		for _, b := range r.config.Backends {
+			chain = append(chain, routeTarget{backend: b})
+		}
+	}
+	return chain
+}
+
+// ServedBy returns the name of the backend that served resp.
+// Responses not served through a Router are attributed to p.
+func ServedBy(p Provider, resp *MessageResponse) string {
+	if resp != nil && resp.Provider != "" {
+		return resp.Provider
+	}
+	return p.Name()
+}
+
+// ServedModel returns the model that served resp, so usage is priced with
+// the model a route resolved to rather than the requested alias.
+// Responses not served through a Router are attributed to model.
+func ServedModel(model string, resp *MessageResponse) string {
+	if resp != nil && resp.Model != "" {
+		return resp.Model
+	}
+	return model
+}
+
+// isDown reports whether a backend is in its failure cooldown.
+func (r *Router) isDown(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Now().Before(r.health[name].downUntil)
+}
+
+// recordSuccess resets the failure count of a backend.
+func (r *Router) recordSuccess(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.health[name].failures = 0
+	r.health[name].downUntil = time.Time{}
+}
+
+// recordFailure counts a failure and starts a cooldown once MaxFailures is reached.
+func (r *Router) recordFailure(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.health[name]
+	h.failures++
+	if h.failures >= r.config.MaxFailures {
+		h.downUntil = time.Now().Add(r.config.Cooldown)
+	}
+}
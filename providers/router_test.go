@@ -0,0 +1,229 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider returns a fixed text or error and records requested models.
+type stubProvider struct {
+	name   string
+	text   string
+	err    error
+	models []string
+}
+
+func (s *stubProvider) Name() string { return s.name }
+
+func (s *stubProvider) CreateMessage(ctx context.Context, req MessageRequest) (*MessageResponse, error) {
+	s.models = append(s.models, req.Model)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &MessageResponse{
+		Content:    []ContentBlock{{Type: "text", Text: s.text}},
+		StopReason: StopReasonEndTurn,
+	}, nil
+}
+
+func (s *stubProvider) StreamMessage(ctx context.Context, req MessageRequest, handler StreamHandler) (*MessageResponse, error) {
+	resp, err := s.CreateMessage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if handler != nil {
+		handler(s.text)
+	}
+	return resp, nil
+}
+
+func TestRouterImplementsInterface(t *testing.T) {
+	var _ Provider = (*Router)(nil)
+}
+
+func TestNewRouterValidation(t *testing.T) {
+	_, err := NewRouter(RouterConfig{})
+	assert.Error(t, err)
+
+	a := &stubProvider{name: "a"}
+	_, err = NewRouter(RouterConfig{Backends: []Backend{{Provider: a}, {Provider: a}}})
+	assert.ErrorContains(t, err, "duplicate backend")
+
+	_, err = NewRouter(RouterConfig{
+		Backends: []Backend{{Provider: a}},
+		Routes:   map[string][]Route{"haiku": {{Backend: "missing"}}},
+	})
+	assert.ErrorContains(t, err, "unknown backend")
+}
+
+func TestRouterReportsServingBackend(t *testing.T) {
+	a := &stubProvider{name: "a", text: "from a"}
+	b := &stubProvider{name: "b", text: "from b"}
+
+	r, err := NewRouter(RouterConfig{Backends: []Backend{{Provider: a}, {Provider: b}}})
+	require.NoError(t, err)
+
+	resp, err := r.CreateMessage(context.Background(), MessageRequest{Model: "any"})
+	require.NoError(t, err)
+	assert.Equal(t, "a", resp.Provider)
+	assert.Equal(t, "a", ServedBy(r, resp))
+	assert.Empty(t, b.models)
+}
+
+func TestRouterFallsBackOnError(t *testing.T) {
+	a := &stubProvider{name: "a", err: errors.New("boom")}
+	b := &stubProvider{name: "b", text: "from b"}
+
+	var fallbacks []string
+	r, err := NewRouter(RouterConfig{
+		Backends: []Backend{{Provider: a}, {Provider: b}},
+		OnFallback: func(backend string, err error) {
+			fallbacks = append(fallbacks, backend)
+		},
+	})
+	require.NoError(t, err)
+
+	var streamed string
+	resp, err := r.StreamMessage(context.Background(), MessageRequest{}, func(text string) { streamed += text })
+	require.NoError(t, err)
+	assert.Equal(t, "b", resp.Provider)
+	assert.Equal(t, "from b", streamed)
+	assert.Equal(t, []string{"a"}, fallbacks)
+}
+
+func TestRouterSkipsUnhealthyBackend(t *testing.T) {
+	a := &stubProvider{name: "a", text: "from a"}
+	b := &stubProvider{name: "b", text: "from b"}
+
+	r, err := NewRouter(RouterConfig{Backends: []Backend{
+		{Provider: a, HealthCheck: func(ctx context.Context) error { return errors.New("not installed") }},
+		{Provider: b},
+	}})
+	require.NoError(t, err)
+
+	resp, err := r.CreateMessage(context.Background(), MessageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "b", resp.Provider)
+	assert.Empty(t, a.models)
+}
+
+func TestRouterCooldownAfterPersistentFailures(t *testing.T) {
+	a := &stubProvider{name: "a", err: errors.New("boom")}
+	b := &stubProvider{name: "b", text: "from b"}
+
+	r, err := NewRouter(RouterConfig{
+		Backends:    []Backend{{Provider: a}, {Provider: b}},
+		MaxFailures: 2,
+		Cooldown:    time.Hour,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		_, err := r.CreateMessage(context.Background(), MessageRequest{})
+		require.NoError(t, err)
+	}
+
+	// a is tried until it reaches MaxFailures, then skipped
+	assert.Len(t, a.models, 2)
+	assert.Len(t, b.models, 4)
+}
+
+func TestRouterRoutesByAlias(t *testing.T) {
+	api := &stubProvider{name: "anthropic", text: "api"}
+	cli := &stubProvider{name: "claude", text: "cli"}
+	local := &stubProvider{name: "openai", text: "local"}
+
+	r, err := NewRouter(RouterConfig{
+		Backends: []Backend{{Provider: cli}, {Provider: api}, {Provider: local, Name: "local"}},
+		Routes: map[string][]Route{
+			"haiku": {{Backend: "anthropic", Model: "claude-3-5-haiku-latest"}},
+			"local": {{Backend: "local", Model: "qwen2.5-coder"}},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := r.CreateMessage(context.Background(), MessageRequest{Model: "haiku"})
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", resp.Provider)
+	assert.Equal(t, []string{"claude-3-5-haiku-latest"}, api.models)
+
+	resp, err = r.CreateMessage(context.Background(), MessageRequest{Model: "local"})
+	require.NoError(t, err)
+	assert.Equal(t, "local", resp.Provider)
+	assert.Equal(t, []string{"qwen2.5-coder"}, local.models)
+
+	// Unrouted models use the default chain unchanged
+	resp, err = r.CreateMessage(context.Background(), MessageRequest{Model: "sonnet"})
+	require.NoError(t, err)
+	assert.Equal(t, "claude", resp.Provider)
+	assert.Equal(t, []string{"sonnet"}, cli.models)
+}
+
+func TestRouterReportsRoutedModel(t *testing.T) {
+	api := &stubProvider{name: "anthropic", text: "api"}
+	r, err := NewRouter(RouterConfig{
+		Backends: []Backend{{Provider: api}},
+		Routes:   map[string][]Route{"haiku": {{Backend: "anthropic", Model: "claude-3-5-haiku-latest"}}},
+	})
+	require.NoError(t, err)
+
+	resp, err := r.CreateMessage(context.Background(), MessageRequest{Model: "haiku"})
+	require.NoError(t, err)
+	assert.Equal(t, "claude-3-5-haiku-latest", resp.Model)
+	assert.Equal(t, "claude-3-5-haiku-latest", ServedModel("haiku", resp))
+	assert.Equal(t, "haiku", ServedModel("haiku", &MessageResponse{}))
+}
+
+func TestRouterIgnoresContextErrors(t *testing.T) {
+	for _, ctxErr := range []error{context.Canceled, context.DeadlineExceeded} {
+		primary := &stubProvider{name: "primary", err: fmt.Errorf("request: %w", ctxErr)}
+		secondary := &stubProvider{name: "secondary", text: "ok"}
+		r, err := NewRouter(RouterConfig{
+			Backends:    []Backend{{Provider: primary}, {Provider: secondary}},
+			MaxFailures: 1,
+		})
+		require.NoError(t, err)
+
+		_, err = r.CreateMessage(context.Background(), MessageRequest{})
+		assert.ErrorIs(t, err, ctxErr)
+		assert.Empty(t, secondary.models, "no fallback after %v", ctxErr)
+
+		primary.err = nil
+		primary.text = "recovered"
+		resp, err := r.CreateMessage(context.Background(), MessageRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "primary", resp.Provider, "backend not marked down after %v", ctxErr)
+	}
+}
+
+func TestRouterAllBackendsFail(t *testing.T) {
+	a := &stubProvider{name: "a", err: errors.New("boom a")}
+	b := &stubProvider{name: "b", err: errors.New("boom b")}
+
+	r, err := NewRouter(RouterConfig{Backends: []Backend{{Provider: a}, {Provider: b}}})
+	require.NoError(t, err)
+
+	_, err = r.CreateMessage(context.Background(), MessageRequest{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all backends failed")
+	assert.Contains(t, err.Error(), "boom a")
+	assert.Contains(t, err.Error(), "boom b")
+}
+
+func TestRouterNoHealthyBackend(t *testing.T) {
+	a := &stubProvider{name: "a"}
+
+	r, err := NewRouter(RouterConfig{Backends: []Backend{
+		{Provider: a, HealthCheck: func(ctx context.Context) error { return errors.New("down") }},
+	}})
+	require.NoError(t, err)
+
+	_, err = r.CreateMessage(context.Background(), MessageRequest{})
+	assert.ErrorIs(t, err, ErrNoBackend)
+}
@@ -0,0 +1,279 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SchemaFor returns the JSON schema of T. See SchemaOf.
+func SchemaFor[T any]() map[string]any {
+	return SchemaOf(reflect.TypeFor[T]())
+}
+
+// SchemaOf returns the JSON schema of a Go type.
+//
+// Struct fields are named by their json tag. Fields are required unless they
+// are pointers or tagged omitempty. A `description` tag documents a field,
+// and an `enum` tag lists its allowed values, separated by commas:
+//
+//	type Plan struct {
+//		Action string   `json:"action" enum:"create,update,delete" description:"What to do"`
+//		Files  []string `json:"files,omitempty"`
+//	}
+func SchemaOf(t reflect.Type) map[string]any {
+	return schemaOf(t, map[reflect.Type]bool{})
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	rawJSONType   = reflect.TypeFor[json.RawMessage]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// schemaOf returns the schema of t. Seen holds the struct types being
+// expanded, so recursive types become unconstrained instead of looping.
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawJSONType, t.Implements(marshalerType):
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]any{}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		properties := map[string]any{}
+		var required []string
+		addFields(t, properties, &required, seen)
+
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]any{}
+	}
+}
+
+// addFields adds the JSON fields of struct type t to properties, inlining
+// embedded structs the way encoding/json does.
+func addFields(t reflect.Type, properties map[string]any, required *[]string, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(ft, properties, required, seen)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := schemaOf(field.Type, seen)
+		if desc := field.Tag.Get("description"); desc != "" {
+			schema["description"] = desc
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			var values []any
+			for _, v := range strings.Split(enum, ",") {
+				values = append(values, strings.TrimSpace(v))
+			}
+			schema["enum"] = values
+		}
+		properties[name] = schema
+
+		if field.Type.Kind() != reflect.Pointer && !strings.Contains(","+opts+",", ",omitempty,") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// Validate checks a JSON document against a schema and returns a
+// description of each violation. It supports the subset of JSON Schema
+// produced by SchemaOf: type, properties, required, additionalProperties,
+// items and enum. Optional properties may be null.
+func Validate(schema map[string]any, data json.RawMessage) []string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+
+	var problems []string
+	validate(schema, value, "$", &problems)
+	return problems
+}
+
+// validate appends the violations of value against schema to problems.
+func validate(schema map[string]any, value any, path string, problems *[]string) {
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return hasType(value, t) }) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, " or "), typeName(value)))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(v any) bool { return fmt.Sprint(v) == fmt.Sprint(value) }) {
+		*problems = append(*problems, fmt.Sprintf("%s: %v is not one of %v", path, value, enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		required := requiredNames(schema["required"])
+		for _, name := range required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			// Optional properties may be null, which decodes as absent
+			if v[k] == nil && !slices.Contains(required, k) {
+				continue
+			}
+			if prop, ok := properties[k].(map[string]any); ok {
+				validate(prop, v[k], path+"."+k, problems)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					*problems = append(*problems, fmt.Sprintf("%s: unexpected property %q", path, k))
+				}
+			case map[string]any:
+				validate(extra, v[k], path+"."+k, problems)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validate(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	}
+}
+
+// schemaTypes returns the allowed types of a schema "type" keyword.
+func schemaTypes(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []any:
+		var types []string
+		for _, s := range t {
+			if s, ok := s.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// requiredNames returns the property names of a schema "required" keyword.
+func requiredNames(v any) []string {
+	if names, ok := v.([]string); ok {
+		return names
+	}
+	var names []string
+	if list, ok := v.([]any); ok {
+		for _, name := range list {
+			if name, ok := name.(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// hasType reports whether a decoded JSON value is of a JSON Schema type.
+func hasType(value any, t string) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	default:
+		return typeName(value) == t || (t == "number" && typeName(value) == "integer")
+	}
+}
+
+// typeName returns the JSON Schema type of a decoded JSON value.
+func typeName(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,126 @@
+package structured
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type plan struct {
+	Action  string            `json:"action" enum:"create,delete" description:"What to do"`
+	Files   []string          `json:"files,omitempty"`
+	Retries int               `json:"retries"`
+	Note    *string           `json:"note"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Due     time.Time         `json:"due,omitempty"`
+	Skipped string            `json:"-"`
+	hidden  string
+	embedded
+}
+
+type embedded struct {
+	Owner string `json:"owner"`
+}
+
+type node struct {
+	Name     string `json:"name"`
+	Children []node `json:"children,omitempty"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema := SchemaFor[plan]()
+
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []string{"action", "retries", "owner"}, schema["required"])
+
+	properties := schema["properties"].(map[string]any)
+	assert.Len(t, properties, 7)
+	assert.Equal(t, map[string]any{
+		"type":        "string",
+		"description": "What to do",
+		"enum":        []any{"create", "delete"},
+	}, properties["action"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, properties["files"])
+	assert.Equal(t, map[string]any{"type": "integer"}, properties["retries"])
+	assert.Equal(t, map[string]any{"type": "string"}, properties["note"])
+	assert.Equal(t, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}}, properties["labels"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, properties["due"])
+	assert.Contains(t, properties, "owner")
+	assert.NotContains(t, properties, "Skipped")
+	assert.NotContains(t, properties, "hidden")
+}
+
+func TestSchemaOf_Recursive(t *testing.T) {
+	schema := SchemaFor[node]()
+
+	children := schema["properties"].(map[string]any)["children"].(map[string]any)
+	assert.Equal(t, "array", children["type"])
+	assert.Equal(t, map[string]any{}, children["items"])
+}
+
+func TestSchemaOf_NonObject(t *testing.T) {
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "number"}}, SchemaFor[[]float64]())
+	assert.Equal(t, map[string]any{"type": "boolean"}, SchemaFor[bool]())
+	assert.Equal(t, map[string]any{}, SchemaFor[any]())
+}
+
+func TestValidate(t *testing.T) {
+	schema := SchemaFor[plan]()
+
+	tests := []struct {
+		name     string
+		data     string
+		problems []string
+	}{
+		{
+			name: "valid",
+			data: `{"action": "create", "retries": 2, "owner": "me", "files": ["a.go"], "note": null}`,
+		},
+		{
+			name:     "invalid json",
+			data:     `{"action":`,
+			problems: []string{"invalid JSON: unexpected EOF"},
+		},
+		{
+			name:     "missing required",
+			data:     `{"action": "create"}`,
+			problems: []string{`$: missing required property "retries"`, `$: missing required property "owner"`},
+		},
+		{
+			name:     "wrong types",
+			data:     `{"action": "create", "retries": 1.5, "owner": "me", "files": ["a.go", 3]}`,
+			problems: []string{"$.files[1]: expected string, got integer", "$.retries: expected integer, got number"},
+		},
+		{
+			name:     "enum",
+			data:     `{"action": "rename", "retries": 0, "owner": "me"}`,
+			problems: []string{"$.action: rename is not one of [create delete]"},
+		},
+		{
+			name:     "not an object",
+			data:     `["create"]`,
+			problems: []string{"$: expected object, got array"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.problems, Validate(schema, json.RawMessage(tt.data)))
+		})
+	}
+}
+
+func TestValidate_AdditionalProperties(t *testing.T) {
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           map[string]any{"name": map[string]any{"type": "string"}},
+		"additionalProperties": false,
+	}
+
+	problems := Validate(schema, json.RawMessage(`{"name": "a", "extra": 1}`))
+	require.Len(t, problems, 1)
+	assert.Equal(t, `$: unexpected property "extra"`, problems[0])
+}
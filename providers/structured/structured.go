@@ -0,0 +1,378 @@
+// Package structured gets machine-readable responses from a provider.
+//
+// Generate asks the model for a value matching a JSON schema, validates it
+// and decodes it into a Go type. Providers with tool calling are forced to
+// call a single tool whose input is the value. Other providers (the Claude
+// and Kiro CLIs) are asked for a bare JSON document, which is extracted from
+// the response text. Either way, an invalid response is sent back to the
+// model with the validation errors until it is valid or attempts run out.
+//
+// Example:
+//
+//	type Verdict struct {
+//		Score  int    `json:"score" description:"Score from 0 to 3"`
+//		Reason string `json:"reason"`
+//	}
+//
+//	verdict, err := structured.Generate[Verdict](ctx, provider, structured.Request{
+//		Model:    "haiku",
+//		Messages: []providers.Message{providers.NewUserMessage(prompt)},
+//	})
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lex00/wetwire-core-go/providers"
+)
+
+// Default request settings.
+const (
+	DefaultName        = "respond"
+	DefaultMaxAttempts = 3
+)
+
+// resultProperty wraps non-object schemas, since tool inputs must be objects.
+const resultProperty = "result"
+
+// ErrInvalid is returned when the model does not produce a valid value
+// within the allowed attempts.
+var ErrInvalid = errors.New("invalid structured output")
+
+// Request describes a structured generation.
+type Request struct {
+	// Model identifier (optional)
+	Model string
+
+	// MaxTokens is the maximum number of tokens per attempt (default: providers.DefaultMaxTokens)
+	MaxTokens int
+
+	// System is the system prompt (optional)
+	System string
+
+	// Messages is the conversation asking for the value
+	Messages []providers.Message
+
+	// Schema is the JSON schema of the value (default: derived from the result type)
+	Schema map[string]any
+
+	// Name names the value; it is used as the tool name (default: "respond")
+	Name string
+
+	// Description tells the model what the value is for (optional)
+	Description string
+
+	// MaxAttempts is the number of responses requested before giving up (default: 3)
+	MaxAttempts int
+}
+
+// Generate asks the model for a value of type T and returns it decoded.
+// If req.Schema is empty, the schema is derived from T with SchemaFor.
+func Generate[T any](ctx context.Context, provider providers.Provider, req Request) (T, error) {
+	var value T
+	if req.Schema == nil {
+		req.Schema = SchemaFor[T]()
+	}
+
+	data, err := GenerateJSON(ctx, provider, req)
+	if err != nil {
+		return value, err
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return value, nil
+}
+
+// GenerateJSON asks the model for a JSON document matching req.Schema and
+// returns it once it validates.
+func GenerateJSON(ctx context.Context, provider providers.Provider, req Request) (json.RawMessage, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("provider is required")
+	}
+	if req.Schema == nil {
+		return nil, fmt.Errorf("schema is required")
+	}
+	if req.Name == "" {
+		req.Name = DefaultName
+	}
+	if req.MaxAttempts <= 0 {
+		req.MaxAttempts = DefaultMaxAttempts
+	}
+
+	caps := providers.CapabilitiesOf(provider, req.Model)
+	if caps.ToolCalling && !caps.Agentic {
+		return generateWithTool(ctx, provider, req)
+	}
+	return generateWithText(ctx, provider, req, caps.SystemPrompt)
+}
+
+// generateWithTool forces the model to call a tool whose input is the value.
+func generateWithTool(ctx context.Context, provider providers.Provider, req Request) (json.RawMessage, error) {
+	schema, wrapped := toolSchema(req.Schema)
+	properties, _ := schema["properties"].(map[string]any)
+
+	description := req.Description
+	if description == "" {
+		description = "Respond with the requested data."
+	}
+
+	msgReq := providers.MessageRequest{
+		Model:     req.Model,
+		MaxTokens: req.MaxTokens,
+		System:    req.System,
+		Messages:  append([]providers.Message{}, req.Messages...),
+		Tools: []providers.Tool{{
+			Name:        req.Name,
+			Description: description,
+			InputSchema: providers.ToolInputSchema{
+				Properties: properties,
+				Required:   requiredNames(schema["required"]),
+			},
+		}},
+		ToolChoice: req.Name,
+	}
+
+	var problems []string
+	for attempt := 0; attempt < req.MaxAttempts; attempt++ {
+		resp, err := provider.CreateMessage(ctx, msgReq)
+		if err != nil {
+			return nil, err
+		}
+
+		call, ok := findToolUse(resp.Content, req.Name)
+		if !ok {
+			// The provider ignored the tool choice; accept JSON in the text
+			data, textProblems := parseText(responseText(resp), req.Schema, resp.StopReason)
+			if len(textProblems) == 0 {
+				return data, nil
+			}
+			problems = textProblems
+			msgReq.Messages = append(msgReq.Messages,
+				providers.NewAssistantMessage(resp.Content),
+				providers.NewUserMessage(fmt.Sprintf("Call the %s tool with the requested data.", req.Name)))
+			continue
+		}
+
+		if problems = Validate(schema, call.Input); len(problems) == 0 {
+			if wrapped {
+				return unwrap(call.Input)
+			}
+			return call.Input, nil
+		}
+
+		// Send the problems back as the tool result and ask again
+		msgReq.Messages = append(msgReq.Messages,
+			providers.NewAssistantMessage(resp.Content),
+			providers.NewToolResultMessage(toolResults(resp.Content, call.ID, retryMessage(problems))))
+	}
+
+	return nil, fmt.Errorf("%w after %d attempts: %s", ErrInvalid, req.MaxAttempts, strings.Join(problems, "; "))
+}
+
+// generateWithText asks the model for a bare JSON document in its response.
+// The schema goes in the system prompt, or in the last user message for
+// providers that do not accept one.
+func generateWithText(ctx context.Context, provider providers.Provider, req Request, systemPrompt bool) (json.RawMessage, error) {
+	instructions := textInstructions(req)
+
+	msgReq := providers.MessageRequest{
+		Model:     req.Model,
+		MaxTokens: req.MaxTokens,
+		System:    req.System,
+		Messages:  append([]providers.Message{}, req.Messages...),
+	}
+	switch {
+	case systemPrompt && req.System != "":
+		msgReq.System = req.System + "\n\n" + instructions
+	case systemPrompt:
+		msgReq.System = instructions
+	default:
+		msgReq.Messages = appendText(msgReq.Messages, instructions)
+	}
+
+	var problems []string
+	for attempt := 0; attempt < req.MaxAttempts; attempt++ {
+		resp, err := provider.CreateMessage(ctx, msgReq)
+		if err != nil {
+			return nil, err
+		}
+
+		var data json.RawMessage
+		if data, problems = parseText(responseText(resp), req.Schema, resp.StopReason); len(problems) == 0 {
+			return data, nil
+		}
+
+		msgReq.Messages = append(msgReq.Messages,
+			providers.NewAssistantMessage(textOnly(resp.Content)),
+			providers.NewUserMessage(retryMessage(problems)+"\nRespond with only the corrected JSON document."))
+	}
+
+	return nil, fmt.Errorf("%w after %d attempts: %s", ErrInvalid, req.MaxAttempts, strings.Join(problems, "; "))
+}
+
+// textInstructions tells the model to answer with JSON matching the schema.
+func textInstructions(req Request) string {
+	schema, _ := json.MarshalIndent(req.Schema, "", "  ")
+
+	var b strings.Builder
+	if req.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", req.Description)
+	}
+	fmt.Fprintf(&b, "Respond with a single JSON document matching this JSON schema, and nothing else:\n\n%s", schema)
+	return b.String()
+}
+
+// toolSchema returns an object schema for the tool input, wrapping other
+// schemas in a single required property.
+func toolSchema(schema map[string]any) (map[string]any, bool) {
+	if schema["type"] == "object" {
+		return schema, false
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{resultProperty: schema},
+		"required":   []string{resultProperty},
+	}, true
+}
+
+// unwrap returns the value wrapped by toolSchema.
+func unwrap(input json.RawMessage) (json.RawMessage, error) {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(input, &wrapper); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return wrapper[resultProperty], nil
+}
+
+// parseText extracts a JSON document from response text and validates it.
+func parseText(text string, schema map[string]any, stop providers.StopReason) (json.RawMessage, []string) {
+	data, ok := ExtractJSON(text)
+	if !ok {
+		if stop == providers.StopReasonMaxTokens {
+			return nil, []string{"the response was cut off by the token limit; respond more concisely"}
+		}
+		return nil, []string{"the response did not contain a JSON document"}
+	}
+	if problems := Validate(schema, data); len(problems) > 0 {
+		return nil, problems
+	}
+	return data, nil
+}
+
+// ExtractJSON returns the first JSON object or array in text. It accepts a
+// bare document, a fenced code block, or a document surrounded by prose.
+func ExtractJSON(text string) (json.RawMessage, bool) {
+	text = strings.TrimSpace(text)
+	if json.Valid([]byte(text)) {
+		return json.RawMessage(text), true
+	}
+
+	// Prefer fenced code blocks, then any object or array in the text
+	for rest := text; ; {
+		_, after, ok := strings.Cut(rest, "```")
+		if !ok {
+			break
+		}
+		body, tail, ok := strings.Cut(after, "```")
+		if !ok {
+			break
+		}
+		if _, code, ok := strings.Cut(body, "\n"); ok && json.Valid([]byte(strings.TrimSpace(code))) {
+			return json.RawMessage(strings.TrimSpace(code)), true
+		}
+		rest = tail
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' && text[i] != '[' {
+			continue
+		}
+		var raw json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&raw); err == nil {
+			return raw, true
+		}
+	}
+	return nil, false
+}
+
+// retryMessage describes the problems with a response.
+func retryMessage(problems []string) string {
+	var b strings.Builder
+	b.WriteString("The response did not match the schema:\n")
+	for _, p := range problems {
+		fmt.Fprintf(&b, "- %s\n", p)
+	}
+	b.WriteString("Try again, fixing these problems.")
+	return b.String()
+}
+
+// findToolUse returns the first call to the named tool.
+func findToolUse(blocks []providers.ContentBlock, name string) (providers.ContentBlock, bool) {
+	for _, block := range blocks {
+		if block.Type == "tool_use" && block.Name == name {
+			return block, true
+		}
+	}
+	return providers.ContentBlock{}, false
+}
+
+// toolResults answers every tool call in blocks, so the conversation stays
+// valid if the model made more than one call.
+func toolResults(blocks []providers.ContentBlock, id, message string) []providers.ContentBlock {
+	var results []providers.ContentBlock
+	for _, block := range blocks {
+		if block.Type != "tool_use" {
+			continue
+		}
+		if block.ID == id {
+			results = append(results, providers.NewToolResult(block.ID, message, true))
+		} else {
+			results = append(results, providers.NewToolResult(block.ID, "Only the first call is used.", true))
+		}
+	}
+	return results
+}
+
+// responseText returns the text blocks of a response.
+func responseText(resp *providers.MessageResponse) string {
+	var b strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			b.WriteString(block.Text)
+		}
+	}
+	return b.String()
+}
+
+// textOnly returns the text blocks of content, dropping tool activity that
+// agentic providers report alongside their answer.
+func textOnly(content []providers.ContentBlock) []providers.ContentBlock {
+	var blocks []providers.ContentBlock
+	for _, block := range content {
+		if block.Type == "text" {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) == 0 {
+		blocks = []providers.ContentBlock{{Type: "text", Text: "(no response)"}}
+	}
+	return blocks
+}
+
+// appendText returns messages with text added to the last user message,
+// or as a new user message.
+func appendText(messages []providers.Message, text string) []providers.Message {
+	n := len(messages)
+	if n == 0 || messages[n-1].Role != "user" {
+		return append(messages, providers.NewUserMessage(text))
+	}
+	last := messages[n-1]
+	last.Content = append(append([]providers.ContentBlock{}, last.Content...), providers.ContentBlock{Type: "text", Text: text})
+	messages[n-1] = last
+	return messages
+}
@@ -0,0 +1,168 @@
+package structured
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lex00/wetwire-core-go/providers"
+	"github.com/lex00/wetwire-core-go/providers/fake"
+)
+
+type verdict struct {
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+func request(prompt string) Request {
+	return Request{
+		Model:    "haiku",
+		Messages: []providers.Message{providers.NewUserMessage(prompt)},
+	}
+}
+
+func TestGenerate_Tool(t *testing.T) {
+	provider := fake.New().
+		CallTool("respond", map[string]any{"score": 3, "reason": "complete"})
+
+	v, err := Generate[verdict](context.Background(), provider, request("Score this"))
+	require.NoError(t, err)
+	assert.Equal(t, verdict{Score: 3, Reason: "complete"}, v)
+
+	req, ok := provider.LastRequest()
+	require.True(t, ok)
+	assert.Equal(t, "respond", req.ToolChoice)
+	require.Len(t, req.Tools, 1)
+	assert.Equal(t, []string{"score", "reason"}, req.Tools[0].InputSchema.Required)
+}
+
+func TestGenerate_ToolRetry(t *testing.T) {
+	provider := fake.New().
+		CallTool("respond", map[string]any{"score": "high"}).
+		CallTool("respond", map[string]any{"score": 2, "reason": "missing tests"})
+
+	v, err := Generate[verdict](context.Background(), provider, request("Score this"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, v.Score)
+
+	results := provider.ToolResults()
+	require.Len(t, results, 1)
+	assert.True(t, results[0].IsError)
+	assert.Contains(t, results[0].Content, "$.score: expected integer, got string")
+	assert.Contains(t, results[0].Content, `missing required property "reason"`)
+}
+
+func TestGenerate_ToolWrapsNonObject(t *testing.T) {
+	provider := fake.New().
+		CallTool("files", map[string]any{"result": []string{"a.go", "b.go"}})
+
+	req := request("List the files")
+	req.Name = "files"
+
+	files, err := Generate[[]string](context.Background(), provider, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "b.go"}, files)
+
+	sent, _ := provider.LastRequest()
+	assert.Equal(t, []string{"result"}, sent.Tools[0].InputSchema.Required)
+}
+
+func TestGenerate_ToolIgnored(t *testing.T) {
+	provider := fake.New().
+		ReplyText(`{"score": 1, "reason": "no tool"}`)
+
+	v, err := Generate[verdict](context.Background(), provider, request("Score this"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, v.Score)
+}
+
+func TestGenerate_Text(t *testing.T) {
+	provider := fake.New().
+		WithCapabilities(providers.Capabilities{SystemPrompt: true, Agentic: true}).
+		ReplyText("Here you go:\n```json\n{\"score\": 3}\n```").
+		ReplyText("Sorry.\n```json\n{\"score\": 3, \"reason\": \"all files present\"}\n```")
+
+	req := request("Score this")
+	req.System = "You are a judge."
+
+	v, err := Generate[verdict](context.Background(), provider, req)
+	require.NoError(t, err)
+	assert.Equal(t, verdict{Score: 3, Reason: "all files present"}, v)
+
+	requests := provider.Requests()
+	require.Len(t, requests, 2)
+	assert.Empty(t, requests[0].Tools)
+	assert.True(t, strings.HasPrefix(requests[0].System, "You are a judge.\n\n"))
+	assert.Contains(t, requests[0].System, `"reason"`)
+
+	retry := requests[1].Messages[len(requests[1].Messages)-1]
+	assert.Contains(t, retry.Content[0].Text, `missing required property "reason"`)
+}
+
+func TestGenerate_TextWithoutSystemPrompt(t *testing.T) {
+	provider := fake.New().
+		WithCapabilities(providers.Capabilities{Agentic: true}).
+		ReplyText(`{"score": 0, "reason": "empty"}`)
+
+	req := request("Score this")
+	_, err := Generate[verdict](context.Background(), provider, req)
+	require.NoError(t, err)
+
+	sent, _ := provider.LastRequest()
+	assert.Empty(t, sent.System)
+	require.Len(t, sent.Messages, 1)
+	require.Len(t, sent.Messages[0].Content, 2)
+	assert.Contains(t, sent.Messages[0].Content[1].Text, "JSON schema")
+	assert.Len(t, req.Messages[0].Content, 1, "request messages must not be modified")
+}
+
+func TestGenerate_GivesUp(t *testing.T) {
+	provider := fake.New().
+		WithCapabilities(providers.Capabilities{Agentic: true}).
+		ReplyText("I cannot do that").
+		ReplyText("Still no")
+
+	req := request("Score this")
+	req.MaxAttempts = 2
+
+	_, err := Generate[verdict](context.Background(), provider, req)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalid))
+	assert.Contains(t, err.Error(), "did not contain a JSON document")
+}
+
+func TestGenerate_ProviderError(t *testing.T) {
+	provider := fake.New().Fail(errors.New("overloaded"))
+
+	_, err := Generate[verdict](context.Background(), provider, request("Score this"))
+	assert.EqualError(t, err, "overloaded")
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"bare", ` {"a": 1} `, `{"a": 1}`},
+		{"fenced", "Result:\n```json\n{\"a\": 1}\n```\nDone.", `{"a": 1}`},
+		{"prose", `The answer is {"a": "}"} as requested.`, `{"a": "}"}`},
+		{"array", `Files: ["a.go"]`, `["a.go"]`},
+		{"skips invalid", `Use {braces} like {"a": 1}`, `{"a": 1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ExtractJSON(tt.text)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+
+	_, ok := ExtractJSON("no json here")
+	assert.False(t, ok)
+}
@@ -0,0 +1,118 @@
+package providers
+
+import "strings"
+
+// Usage reports the tokens consumed by a request.
+type Usage struct {
+	// InputTokens is the number of uncached input tokens
+	InputTokens int `json:"input_tokens"`
+
+	// OutputTokens is the number of generated tokens
+	OutputTokens int `json:"output_tokens"`
+
+	// CacheCreationInputTokens is the number of input tokens written to the prompt cache
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+
+	// CacheReadInputTokens is the number of input tokens read from the prompt cache
+	CacheReadInputTokens int `json:"cache_read_input_tokens,omitempty"`
+
+	// CostUSD is the cost reported by the provider itself, if any.
+	// The Claude CLI reports this directly; API providers leave it zero.
+	CostUSD float64 `json:"cost_usd,omitempty"`
+}
+
+// Add returns the sum of two usages.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTokens:              u.InputTokens + other.InputTokens,
+		OutputTokens:             u.OutputTokens + other.OutputTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens + other.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens + other.CacheReadInputTokens,
+		CostUSD:                  u.CostUSD + other.CostUSD,
+	}
+}
+
+// TotalTokens returns the sum of all input, cache and output tokens.
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// CacheHitRate returns the fraction of input tokens read from the prompt cache.
+func (u Usage) CacheHitRate() float64 {
+	input := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	if input == 0 {
+		return 0
+	}
+	return float64(u.CacheReadInputTokens) / float64(input)
+}
+
+// IsZero returns true if no usage was reported.
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write,omitempty"`
+	CacheRead  float64 `json:"cache_read,omitempty"`
+}
+
+// PriceTable maps model identifiers or prefixes to prices.
+// Lookups use the longest key that prefixes the model, so "claude-sonnet-4"
+// prices "claude-sonnet-4-20250514".
+type PriceTable map[string]ModelPrice
+
+// DefaultPrices contains list prices for commonly used models.
+// Override entries or supply a custom table for negotiated pricing.
+var DefaultPrices = PriceTable{
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50},
+	"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+	"opus":              {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50},
+	"sonnet":            {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"haiku":             {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60, CacheRead: 0.075},
+	"gpt-4o":            {Input: 2.50, Output: 10, CacheRead: 1.25},
+}
+
+// Lookup returns the price for a model using the longest matching prefix.
+func (t PriceTable) Lookup(model string) (ModelPrice, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+
+	var best string
+	for key := range t {
+		if strings.HasPrefix(model, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return t[best], true
+}
+
+// Cost returns the USD cost of usage for a model.
+// Provider-reported cost takes precedence over the table. Unknown models cost zero.
+func (t PriceTable) Cost(model string, u Usage) float64 {
+	if u.CostUSD > 0 {
+		return u.CostUSD
+	}
+
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0
+	}
+
+	return (float64(u.InputTokens)*price.Input +
+		float64(u.OutputTokens)*price.Output +
+		float64(u.CacheCreationInputTokens)*price.CacheWrite +
+		float64(u.CacheReadInputTokens)*price.CacheRead) / 1e6
+}
@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageAdd(t *testing.T) {
+	a := Usage{InputTokens: 10, OutputTokens: 5, CacheReadInputTokens: 100}
+	b := Usage{InputTokens: 3, OutputTokens: 2, CacheCreationInputTokens: 50, CostUSD: 0.01}
+
+	sum := a.Add(b)
+
+	assert.Equal(t, Usage{
+		InputTokens:              13,
+		OutputTokens:             7,
+		CacheCreationInputTokens: 50,
+		CacheReadInputTokens:     100,
+		CostUSD:                  0.01,
+	}, sum)
+	assert.Equal(t, 170, sum.TotalTokens())
+	assert.False(t, sum.IsZero())
+	assert.True(t, Usage{}.IsZero())
+}
+
+func TestPriceTableLookup(t *testing.T) {
+	tests := []struct {
+		model string
+		input float64
+		found bool
+	}{
+		{"claude-sonnet-4-20250514", 3, true},
+		{"claude-opus-4-20250514", 15, true},
+		{"claude-opus-4-5-20251101", 5, true},
+		{"claude-3-5-haiku-latest", 0.80, true},
+		{"gpt-4o-mini-2024-07-18", 0.15, true},
+		{"gpt-4o-2024-08-06", 2.50, true},
+		{"sonnet", 3, true},
+		{"unknown-model", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			price, ok := DefaultPrices.Lookup(tt.model)
+			assert.Equal(t, tt.found, ok)
+			assert.Equal(t, tt.input, price.Input)
+		})
+	}
+}
+
+func TestPriceTableCost(t *testing.T) {
+	table := PriceTable{
+		"model-a": {Input: 2, Output: 10, CacheWrite: 2.5, CacheRead: 0.2},
+	}
+
+	usage := Usage{
+		InputTokens:              1_000_000,
+		OutputTokens:             100_000,
+		CacheCreationInputTokens: 400_000,
+		CacheReadInputTokens:     5_000_000,
+	}
+
+	assert.InDelta(t, 2+1+1+1, table.Cost("model-a-v1", usage), 1e-9)
+	assert.Equal(t, 0.0, table.Cost("model-b", usage))
+
+	// Provider-reported cost wins
+	usage.CostUSD = 0.42
+	assert.Equal(t, 0.42, table.Cost("model-a", usage))
+}
+
+func TestUsageCacheHitRate(t *testing.T) {
+	assert.Equal(t, 0.0, Usage{}.CacheHitRate())
+
+	u := Usage{InputTokens: 100, CacheCreationInputTokens: 100, CacheReadInputTokens: 800, OutputTokens: 50}
+	assert.InDelta(t, 0.8, u.CacheHitRate(), 1e-9)
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
+	"github.com/lex00/wetwire-core-go/providers"
 	"gopkg.in/yaml.v3"
 )
 
@@ -52,6 +54,43 @@ func Parse(data []byte) (*ScenarioConfig, error) {
 	return &config, nil
 }
 
+// PromptsDir is the scenario subdirectory holding persona prompts and attachments.
+const PromptsDir = "prompts"
+
+// LoadAttachments returns image and document blocks for the media files in a
+// scenario's prompts directory (e.g. prompts/diagram.png), sorted by name.
+// Files are referenced by path and read when the prompt is sent.
+// A scenario without a prompts directory has no attachments.
+func LoadAttachments(scenarioPath string) ([]providers.ContentBlock, error) {
+	dir := filepath.Join(scenarioPath, PromptsDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && providers.MediaTypeByPath(entry.Name()) != "" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	blocks := make([]providers.ContentBlock, 0, len(names))
+	for _, name := range names {
+		block, err := providers.NewMediaFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
 // GetDomainOrder returns domains in dependency order (dependencies first).
 // Returns an error if there are circular dependencies.
 func GetDomainOrder(config *ScenarioConfig) ([]string, error) {
@@ -202,3 +202,25 @@ func indexOf(slice []string, s string) int {
 	}
 	return -1
 }
+
+func TestLoadAttachments(t *testing.T) {
+	dir := t.TempDir()
+	promptsDir := filepath.Join(dir, PromptsDir)
+	require.NoError(t, os.MkdirAll(promptsDir, 0755))
+
+	for _, name := range []string{"beginner.md", "expert.txt", "runbook.pdf", "diagram.png"} {
+		require.NoError(t, os.WriteFile(filepath.Join(promptsDir, name), []byte("x"), 0644))
+	}
+
+	blocks, err := LoadAttachments(dir)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "image", blocks[0].Type)
+	assert.Equal(t, filepath.Join(promptsDir, "diagram.png"), blocks[0].Path)
+	assert.Equal(t, "document", blocks[1].Type)
+	assert.Equal(t, "application/pdf", blocks[1].MediaType)
+
+	blocks, err = LoadAttachments(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, blocks)
+}
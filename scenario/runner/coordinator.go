@@ -0,0 +1,430 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/lex00/wetwire-core-go/agent/agents"
+	"github.com/lex00/wetwire-core-go/agent/events"
+	"github.com/lex00/wetwire-core-go/agent/results"
+	"github.com/lex00/wetwire-core-go/providers"
+	"github.com/lex00/wetwire-core-go/scenario"
+)
+
+// Coordinator runs a cross-domain scenario with one agent per domain.
+// A planner agent splits the prompt into a task for each domain, and each
+// task is carried out by a worker agent that has only that domain's MCP
+// tools, so it cannot reach for another domain's CLI. Workers run in
+// dependency order (see scenario.GetDomainOrder), and the outputs each one
+// generates are handed to the domains that depend on it.
+type Coordinator struct {
+	config   CoordinatorConfig
+	order    []string
+	model    string
+	session  *results.Session
+	observer events.Observer
+}
+
+// CoordinatorConfig configures a Coordinator.
+type CoordinatorConfig struct {
+	// ScenarioConfig is the loaded scenario configuration (required)
+	ScenarioConfig *scenario.ScenarioConfig
+
+	// Provider runs the planner and the workers (required)
+	Provider providers.Provider
+
+	// Model overrides the scenario's model setting (optional)
+	Model string
+
+	// Output is where to write warnings (default: os.Stdout)
+	Output io.Writer
+
+	// Servers maps each domain to the MCP server whose tools its worker
+	// gets, such as MCPManager.DomainServer (required for every domain)
+	Servers map[string]agents.MCPServer
+
+	// WorkDir is where the workers generate files. After each worker,
+	// outputs are captured from the files matching its domain's Outputs
+	// patterns and handed to dependent domains (optional)
+	WorkDir string
+
+	// Session receives the planner's transcript followed by each worker's
+	// (default: a new session for the scenario)
+	Session *results.Session
+
+	// Worker adjusts a worker's configuration before it is created, for
+	// example to add guardrails or verification (optional)
+	Worker func(domain string, config *agents.AgentConfig)
+
+	// Observer receives the planner's and workers' events (optional)
+	Observer events.Observer
+}
+
+// CoordinatorResult describes a coordinated run. It is returned even when
+// the run fails, describing what happened up to then.
+type CoordinatorResult struct {
+	// Tasks is the plan, in execution order
+	Tasks []DomainTask
+
+	// Workers are the results of the workers that ran, in order
+	Workers []WorkerResult
+
+	// Outputs are the outputs captured from each domain
+	Outputs *OutputManifest
+
+	// Session holds the planner's and every worker's transcript
+	Session *results.Session
+}
+
+// DomainTask is the part of the prompt assigned to one domain.
+type DomainTask struct {
+	// Domain is the domain name
+	Domain string
+
+	// Task is the worker's prompt
+	Task string
+}
+
+// WorkerResult is the outcome of one domain's worker.
+type WorkerResult struct {
+	// Domain is the domain name
+	Domain string
+
+	// Task is the worker's prompt
+	Task string
+
+	// Result is the worker's run
+	Result *agents.RunResult
+
+	// Session is the worker's own session, also merged into the
+	// coordinator's
+	Session *results.Session
+}
+
+// NewCoordinator creates a coordinator for a scenario.
+func NewCoordinator(cfg CoordinatorConfig) (*Coordinator, error) {
+	if cfg.ScenarioConfig == nil {
+		return nil, fmt.Errorf("scenario config is required")
+	}
+	if cfg.Provider == nil {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	order, err := scenario.GetDomainOrder(cfg.ScenarioConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to order domains: %w", err)
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("no domains in scenario")
+	}
+	for _, name := range order {
+		if cfg.Servers[name] == nil {
+			return nil, fmt.Errorf("no MCP server for domain %s", name)
+		}
+	}
+
+	output := cfg.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
+	model := cfg.Model
+	if model == "" {
+		model = cfg.ScenarioConfig.Model
+	}
+	model = scenarioModel(model, output)
+
+	session := cfg.Session
+	if session == nil {
+		session = results.NewSession("", cfg.ScenarioConfig.Name)
+	}
+
+	return &Coordinator{
+		config:   cfg,
+		order:    order,
+		model:    model,
+		session:  session,
+		observer: events.Serialized(cfg.Observer),
+	}, nil
+}
+
+// Run plans the prompt and runs each domain's worker in order. A failing
+// worker stops the run, since later domains may depend on its outputs.
+func (c *Coordinator) Run(ctx context.Context, prompt string) (*CoordinatorResult, error) {
+	result := &CoordinatorResult{
+		Outputs: NewOutputManifest(),
+		Session: c.session,
+	}
+
+	tasks, err := c.plan(ctx, prompt)
+	if err != nil {
+		return result, fmt.Errorf("planning failed: %w", err)
+	}
+	result.Tasks = tasks
+
+	for _, task := range tasks {
+		worker, err := c.runWorker(ctx, task, result.Outputs)
+		if worker != nil {
+			result.Workers = append(result.Workers, *worker)
+		}
+		if err != nil {
+			return result, fmt.Errorf("%s worker failed: %w", task.Domain, err)
+		}
+	}
+
+	return result, nil
+}
+
+// plan has the planner agent assign a task to every domain.
+func (c *Coordinator) plan(ctx context.Context, prompt string) ([]DomainTask, error) {
+	planner := &planServer{domains: c.order, tasks: make(map[string]string)}
+
+	agent, err := agents.NewAgent(agents.AgentConfig{
+		Provider:     c.config.Provider,
+		Model:        c.model,
+		MCPServer:    planner,
+		Session:      c.session,
+		SystemPrompt: buildPlannerSystemPrompt(c.config.ScenarioConfig, c.order),
+		Guardrails:   []agents.Guardrail{{BeforeFinish: planner.beforeFinish}},
+		Observer:     c.observer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := agent.RunWithResult(ctx, prompt); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]DomainTask, len(c.order))
+	for i, name := range c.order {
+		tasks[i] = DomainTask{Domain: name, Task: planner.tasks[name]}
+	}
+	return tasks, nil
+}
+
+// runWorker runs one domain's task and captures the domain's outputs. The
+// worker's session is merged into the coordinator's even if it fails.
+func (c *Coordinator) runWorker(ctx context.Context, task DomainTask, outputs *OutputManifest) (*WorkerResult, error) {
+	spec := c.config.ScenarioConfig.GetDomain(task.Domain)
+
+	session := results.NewSession(c.session.Persona, c.session.Scenario)
+	session.Prices = c.session.Prices
+	session.RedactThinking = c.session.RedactThinking
+
+	config := agents.AgentConfig{
+		Provider:     c.config.Provider,
+		Model:        c.model,
+		MCPServer:    c.config.Servers[task.Domain],
+		Session:      session,
+		SystemPrompt: buildWorkerSystemPrompt(c.config.ScenarioConfig, spec, dependencyOutputs(outputs, spec.DependsOn)),
+		Observer:     c.observer,
+	}
+	if c.config.Worker != nil {
+		c.config.Worker(task.Domain, &config)
+	}
+
+	agent, err := agents.NewAgent(config)
+	if err != nil {
+		return nil, err
+	}
+	run, err := agent.RunWithResult(ctx, task.Task)
+
+	c.session.AddMessage("system", fmt.Sprintf("%s worker: %s", task.Domain, task.Task))
+	if config.Session != nil {
+		c.session.Merge(config.Session)
+	}
+
+	worker := &WorkerResult{
+		Domain:  task.Domain,
+		Task:    task.Task,
+		Result:  run,
+		Session: config.Session,
+	}
+	if err != nil {
+		return worker, err
+	}
+
+	if c.config.WorkDir != "" && len(spec.Outputs) > 0 {
+		output, err := CaptureOutputsFromFiles(c.config.WorkDir, task.Domain, spec.Outputs)
+		if err != nil {
+			return worker, fmt.Errorf("failed to capture outputs: %w", err)
+		}
+		outputs.AddDomainOutput(task.Domain, output)
+	}
+
+	return worker, nil
+}
+
+// dependencyOutputs returns the outputs of the given domains that have
+// been captured so far.
+func dependencyOutputs(manifest *OutputManifest, domains []string) *OutputManifest {
+	deps := NewOutputManifest()
+	for _, name := range domains {
+		if output := manifest.GetDomainOutput(name); output != nil {
+			deps.AddDomainOutput(name, output)
+		}
+	}
+	return deps
+}
+
+// assignTaskTool is the planner's only tool.
+const assignTaskTool = "assign_task"
+
+// planServer gives the planner the assign_task tool and collects the tasks
+// it assigns.
+type planServer struct {
+	domains []string
+	tasks   map[string]string
+}
+
+// ExecuteTool records a task for a domain. A later call for the same
+// domain replaces its task.
+func (s *planServer) ExecuteTool(ctx context.Context, name string, args map[string]any) (string, error) {
+	if name != assignTaskTool {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+
+	domain, _ := args["domain"].(string)
+	task, _ := args["task"].(string)
+	if !slices.Contains(s.domains, domain) {
+		return "", fmt.Errorf("unknown domain %q (domains: %s)", domain, strings.Join(s.domains, ", "))
+	}
+	if strings.TrimSpace(task) == "" {
+		return "", fmt.Errorf("task is required")
+	}
+
+	s.tasks[domain] = task
+	return fmt.Sprintf("Assigned task to %s.", domain), nil
+}
+
+// GetTools returns the assign_task tool.
+func (s *planServer) GetTools() []agents.MCPToolInfo {
+	return []agents.MCPToolInfo{{
+		Name:        assignTaskTool,
+		Description: "Assign the task for one domain to its worker. Call once per domain; a later call replaces the domain's task.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"domain": map[string]any{
+					"type":        "string",
+					"enum":        s.domains,
+					"description": "The domain whose worker carries out the task",
+				},
+				"task": map[string]any{
+					"type":        "string",
+					"description": "Self-contained instructions for the worker",
+				},
+			},
+			"required": []string{"domain", "task"},
+		},
+	}}
+}
+
+// beforeFinish keeps the planner working until every domain has a task.
+func (s *planServer) beforeFinish(ctx context.Context, result *agents.RunResult) string {
+	var missing []string
+	for _, name := range s.domains {
+		if _, ok := s.tasks[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("You have not assigned a task to every domain. Call %s for: %s.", assignTaskTool, strings.Join(missing, ", "))
+}
+
+// buildPlannerSystemPrompt creates the planner's system prompt, describing
+// the domains in execution order and how they relate.
+func buildPlannerSystemPrompt(config *scenario.ScenarioConfig, order []string) string {
+	var sb strings.Builder
+
+	sb.WriteString("You are the planner for a multi-domain infrastructure scenario.\n\n")
+	if config.Description != "" {
+		sb.WriteString(fmt.Sprintf("Scenario: %s\n\n", config.Description))
+	}
+
+	sb.WriteString("Split the user's request into one task per domain and call `assign_task` once for each domain below.\n")
+	sb.WriteString("Each task is carried out by a separate worker that only has its own domain's tools and never sees the request, ")
+	sb.WriteString("so every task must be self-contained: include the names, settings and cross-domain references the worker needs.\n")
+	sb.WriteString("Workers run in the order below, and each one receives the outputs of the domains it depends on.\n")
+	sb.WriteString("Do not generate any infrastructure yourself. When every domain has a task, reply with a short summary of the plan.\n\n")
+
+	sb.WriteString("## Domains\n\n")
+	for i, name := range order {
+		d := config.GetDomain(name)
+		sb.WriteString(fmt.Sprintf("%d. %s", i+1, name))
+		if d.CLI != "" {
+			sb.WriteString(fmt.Sprintf(" (CLI: %s)", d.CLI))
+		}
+		sb.WriteString("\n")
+		if len(d.DependsOn) > 0 {
+			sb.WriteString(fmt.Sprintf("   Depends on: %s\n", strings.Join(d.DependsOn, ", ")))
+		}
+		if len(d.Outputs) > 0 {
+			sb.WriteString(fmt.Sprintf("   Expected outputs: %s\n", strings.Join(d.Outputs, ", ")))
+		}
+	}
+	sb.WriteString("\n")
+
+	writeCrossDomain(&sb, config.CrossDomain)
+
+	return sb.String()
+}
+
+// buildWorkerSystemPrompt creates the system prompt of a domain's worker,
+// including the outputs of the domains it depends on.
+func buildWorkerSystemPrompt(config *scenario.ScenarioConfig, domain *scenario.DomainSpec, dependencyOutputs *OutputManifest) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("You are the %s worker in a multi-domain infrastructure scenario.\n\n", domain.Name))
+	if config.Description != "" {
+		sb.WriteString(fmt.Sprintf("Scenario: %s\n\n", config.Description))
+	}
+
+	sb.WriteString("## Requirements\n\n")
+	sb.WriteString(fmt.Sprintf("1. You only have the %s domain's tools", domain.Name))
+	if domain.CLI != "" {
+		sb.WriteString(fmt.Sprintf(" (from %s)", domain.CLI))
+	}
+	sb.WriteString(". Other domains are handled by other workers; do not generate them.\n")
+	sb.WriteString("2. You MUST write Go code using wetwire patterns, then call the domain's build tool.\n")
+	sb.WriteString("3. Lint the code and fix lint errors until it passes.\n")
+	sb.WriteString("4. All files MUST be created in the current working directory (use relative paths).\n\n")
+
+	if len(domain.Outputs) > 0 {
+		sb.WriteString(fmt.Sprintf("Expected outputs: %s\n\n", strings.Join(domain.Outputs, ", ")))
+	}
+
+	var related []scenario.CrossDomainSpec
+	for _, cd := range config.CrossDomain {
+		if cd.From == domain.Name || cd.To == domain.Name {
+			related = append(related, cd)
+		}
+	}
+	writeCrossDomain(&sb, related)
+
+	writeDependencyOutputs(&sb, dependencyOutputs)
+
+	return sb.String()
+}
+
+// writeCrossDomain adds cross-domain relationships to a system prompt.
+func writeCrossDomain(sb *strings.Builder, crossDomain []scenario.CrossDomainSpec) {
+	if len(crossDomain) == 0 {
+		return
+	}
+
+	sb.WriteString("## Cross-Domain Integration\n\n")
+	for _, cd := range crossDomain {
+		sb.WriteString(fmt.Sprintf("- %s → %s (%s)\n", cd.From, cd.To, cd.Type))
+		if len(cd.Validation.RequiredRefs) > 0 {
+			sb.WriteString(fmt.Sprintf("  Required references: %s\n", strings.Join(cd.Validation.RequiredRefs, ", ")))
+		}
+	}
+	sb.WriteString("\n")
+}
@@ -0,0 +1,219 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lex00/wetwire-core-go/agent/agents"
+	"github.com/lex00/wetwire-core-go/agent/results"
+	"github.com/lex00/wetwire-core-go/providers"
+	"github.com/lex00/wetwire-core-go/providers/fake"
+	"github.com/lex00/wetwire-core-go/scenario"
+)
+
+// stubServer is a domain MCP server whose tools run a callback.
+type stubServer struct {
+	tools []string
+	run   func(name string) (string, error)
+}
+
+func (s *stubServer) ExecuteTool(ctx context.Context, name string, args map[string]any) (string, error) {
+	return s.run(name)
+}
+
+func (s *stubServer) GetTools() []agents.MCPToolInfo {
+	infos := make([]agents.MCPToolInfo, len(s.tools))
+	for i, name := range s.tools {
+		infos[i] = agents.MCPToolInfo{Name: name}
+	}
+	return infos
+}
+
+func coordinatorScenario() *scenario.ScenarioConfig {
+	return &scenario.ScenarioConfig{
+		Name: "aws_gitlab",
+		Domains: []scenario.DomainSpec{
+			{Name: "gitlab", CLI: "wetwire-gitlab", DependsOn: []string{"aws"}},
+			{Name: "aws", CLI: "wetwire-aws", Outputs: []string{"*.json"}},
+		},
+		CrossDomain: []scenario.CrossDomainSpec{
+			{From: "aws", To: "gitlab", Type: "artifact_reference"},
+		},
+	}
+}
+
+func TestCoordinator_Run(t *testing.T) {
+	workDir := t.TempDir()
+
+	aws := &stubServer{tools: []string{"aws_build"}, run: func(name string) (string, error) {
+		template := `{"Outputs": {"BucketName": "artifacts-bucket"}}`
+		return "built", os.WriteFile(filepath.Join(workDir, "template.json"), []byte(template), 0644)
+	}}
+	gitlab := &stubServer{tools: []string{"gitlab_build"}, run: func(name string) (string, error) {
+		return "built", nil
+	}}
+
+	provider := fake.New().
+		ExpectTools(assignTaskTool).
+		ExpectSystem("1. aws").
+		CallTool(assignTaskTool, map[string]any{"domain": "aws", "task": "Create the artifacts bucket"}).
+		ReplyText("Planned").
+		Expect(func(req providers.MessageRequest) error {
+			last := req.Messages[len(req.Messages)-1]
+			if !strings.Contains(last.Content[0].Text, "Call assign_task for: gitlab.") {
+				return fmt.Errorf("planner was not asked to assign gitlab: %v", last.Content)
+			}
+			return nil
+		}).
+		CallTool(assignTaskTool, map[string]any{"domain": "gitlab", "task": "Deploy to the artifacts bucket"}).
+		ReplyText("Planned").
+		ExpectTools("aws_build").
+		ExpectSystem("You are the aws worker").
+		CallTool("aws_build", nil).
+		ReplyText("Bucket created").
+		ExpectTools("gitlab_build").
+		ExpectSystem("artifacts-bucket").
+		CallTool("gitlab_build", nil).
+		ReplyText("Pipeline created")
+
+	session := results.NewSession("expert", "aws_gitlab")
+	coordinator, err := NewCoordinator(CoordinatorConfig{
+		ScenarioConfig: coordinatorScenario(),
+		Provider:       provider,
+		Servers:        map[string]agents.MCPServer{"aws": aws, "gitlab": gitlab},
+		WorkDir:        workDir,
+		Session:        session,
+	})
+	if err != nil {
+		t.Fatalf("NewCoordinator() error = %v", err)
+	}
+
+	result, err := coordinator.Run(context.Background(), "Build a bucket and a pipeline that deploys to it")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if err := provider.Err(); err != nil {
+		t.Fatalf("provider error = %v", err)
+	}
+
+	if len(result.Tasks) != 2 || result.Tasks[0].Domain != "aws" || result.Tasks[1].Task != "Deploy to the artifacts bucket" {
+		t.Errorf("Tasks = %v, want aws then gitlab", result.Tasks)
+	}
+	if len(result.Workers) != 2 || result.Workers[1].Result.FinalText != "Pipeline created" {
+		t.Errorf("Workers = %v, want both to finish", result.Workers)
+	}
+	if got := result.Outputs.GetResourceOutput("aws", "cloudformation", "BucketName"); got != "artifacts-bucket" {
+		t.Errorf("aws BucketName output = %v, want artifacts-bucket", got)
+	}
+
+	if result.Session != session {
+		t.Error("Session should be the configured session")
+	}
+	var transcript []string
+	for _, m := range session.Messages {
+		transcript = append(transcript, m.Content)
+	}
+	joined := strings.Join(transcript, "\n")
+	for _, want := range []string{"Planned", "aws worker: Create the artifacts bucket", "Bucket created", "gitlab worker:", "Pipeline created"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("session transcript missing %q:\n%s", want, joined)
+		}
+	}
+}
+
+func TestCoordinator_WorkerFailure(t *testing.T) {
+	failed := errors.New("provider down")
+	provider := fake.New().
+		CallTools(
+			fake.Call(assignTaskTool, map[string]any{"domain": "aws", "task": "Create a bucket"}),
+			fake.Call(assignTaskTool, map[string]any{"domain": "gitlab", "task": "Create a pipeline"}),
+		).
+		ReplyText("Planned").
+		Fail(failed)
+
+	server := &stubServer{run: func(name string) (string, error) { return "", nil }}
+	coordinator, err := NewCoordinator(CoordinatorConfig{
+		ScenarioConfig: coordinatorScenario(),
+		Provider:       provider,
+		Servers:        map[string]agents.MCPServer{"aws": server, "gitlab": server},
+	})
+	if err != nil {
+		t.Fatalf("NewCoordinator() error = %v", err)
+	}
+
+	result, err := coordinator.Run(context.Background(), "Build a bucket and a pipeline")
+	if !errors.Is(err, failed) || !strings.Contains(err.Error(), "aws worker failed") {
+		t.Errorf("Run() error = %v, want aws worker failure", err)
+	}
+	if len(result.Workers) != 1 || result.Workers[0].Result.Termination != agents.TerminationError {
+		t.Errorf("Workers = %v, want only the failed aws worker", result.Workers)
+	}
+}
+
+func TestNewCoordinator_Errors(t *testing.T) {
+	server := &stubServer{}
+
+	tests := []struct {
+		name string
+		cfg  CoordinatorConfig
+		want string
+	}{
+		{
+			name: "missing scenario",
+			cfg:  CoordinatorConfig{Provider: fake.New()},
+			want: "scenario config is required",
+		},
+		{
+			name: "missing provider",
+			cfg:  CoordinatorConfig{ScenarioConfig: coordinatorScenario()},
+			want: "provider is required",
+		},
+		{
+			name: "missing domain server",
+			cfg: CoordinatorConfig{
+				ScenarioConfig: coordinatorScenario(),
+				Provider:       fake.New(),
+				Servers:        map[string]agents.MCPServer{"aws": server},
+			},
+			want: "no MCP server for domain gitlab",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCoordinator(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewCoordinator() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanServer(t *testing.T) {
+	planner := &planServer{domains: []string{"aws", "gitlab"}, tasks: make(map[string]string)}
+	ctx := context.Background()
+
+	if _, err := planner.ExecuteTool(ctx, assignTaskTool, map[string]any{"domain": "k8s", "task": "Deploy"}); err == nil {
+		t.Error("assigning an unknown domain should error")
+	}
+	if _, err := planner.ExecuteTool(ctx, assignTaskTool, map[string]any{"domain": "aws", "task": " "}); err == nil {
+		t.Error("assigning an empty task should error")
+	}
+	if msg := planner.beforeFinish(ctx, nil); !strings.Contains(msg, "aws, gitlab") {
+		t.Errorf("beforeFinish() = %q, want both domains missing", msg)
+	}
+
+	for _, domain := range []string{"aws", "gitlab"} {
+		if _, err := planner.ExecuteTool(ctx, assignTaskTool, map[string]any{"domain": domain, "task": "Build"}); err != nil {
+			t.Fatalf("ExecuteTool(%s) error = %v", domain, err)
+		}
+	}
+	if msg := planner.beforeFinish(ctx, nil); msg != "" {
+		t.Errorf("beforeFinish() = %q, want no veto", msg)
+	}
+}
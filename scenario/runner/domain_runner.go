@@ -104,6 +104,7 @@ func NewDomainRunner(ctx context.Context, cfg DomainRunnerConfig) (*DomainRunner
 	if model == "" {
 		model = cfg.ScenarioConfig.Model
 	}
+	model = scenarioModel(model, output)
 
 	// Create Claude provider with MCP config
 	provider, err := claude.New(claude.Config{
@@ -243,50 +244,49 @@ func buildDomainSystemPrompt(config *scenario.ScenarioConfig, dependencyOutputs
 	}
 
 	// Add cross-domain requirements
-	if len(config.CrossDomain) > 0 {
-		sb.WriteString("## Cross-Domain Integration\n\n")
-		for _, cd := range config.CrossDomain {
-			sb.WriteString(fmt.Sprintf("- %s → %s (%s)\n", cd.From, cd.To, cd.Type))
-			if len(cd.Validation.RequiredRefs) > 0 {
-				sb.WriteString(fmt.Sprintf("  Required references: %s\n", strings.Join(cd.Validation.RequiredRefs, ", ")))
-			}
-		}
-		sb.WriteString("\n")
-	}
+	writeCrossDomain(&sb, config.CrossDomain)
 
 	// Add dependency outputs if available
-	if dependencyOutputs != nil && len(dependencyOutputs.Domains) > 0 {
-		sb.WriteString("## Available Dependency Outputs\n\n")
-		sb.WriteString("The following outputs from dependency domains are available for reference:\n\n")
+	writeDependencyOutputs(&sb, dependencyOutputs)
 
-		for domainName, domainOutput := range dependencyOutputs.Domains {
-			if domainOutput == nil || len(domainOutput.Resources) == 0 {
-				continue
-			}
+	return sb.String()
+}
+
+// writeDependencyOutputs adds the outputs of dependency domains to a system
+// prompt. It writes nothing if the manifest has no outputs.
+func writeDependencyOutputs(sb *strings.Builder, manifest *OutputManifest) {
+	if manifest == nil || len(manifest.Domains) == 0 {
+		return
+	}
+
+	sb.WriteString("## Available Dependency Outputs\n\n")
+	sb.WriteString("The following outputs from dependency domains are available for reference:\n\n")
+
+	for domainName, domainOutput := range manifest.Domains {
+		if domainOutput == nil || len(domainOutput.Resources) == 0 {
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("### %s Domain Outputs\n\n", strings.ToUpper(domainName[:1])+domainName[1:]))
 
-			sb.WriteString(fmt.Sprintf("### %s Domain Outputs\n\n", strings.ToUpper(domainName[:1])+domainName[1:]))
-
-			for resourceName, resourceOutput := range domainOutput.Resources {
-				sb.WriteString(fmt.Sprintf("**%s** (type: %s)\n", resourceName, resourceOutput.Type))
-				if len(resourceOutput.Outputs) > 0 {
-					// Format outputs as JSON for readability
-					outputJSON, err := json.MarshalIndent(resourceOutput.Outputs, "  ", "  ")
-					if err == nil {
-						sb.WriteString("```json\n")
-						sb.WriteString("  ")
-						sb.WriteString(string(outputJSON))
-						sb.WriteString("\n```\n")
-					}
+		for resourceName, resourceOutput := range domainOutput.Resources {
+			sb.WriteString(fmt.Sprintf("**%s** (type: %s)\n", resourceName, resourceOutput.Type))
+			if len(resourceOutput.Outputs) > 0 {
+				// Format outputs as JSON for readability
+				outputJSON, err := json.MarshalIndent(resourceOutput.Outputs, "  ", "  ")
+				if err == nil {
+					sb.WriteString("```json\n")
+					sb.WriteString("  ")
+					sb.WriteString(string(outputJSON))
+					sb.WriteString("\n```\n")
 				}
-				sb.WriteString("\n")
 			}
+			sb.WriteString("\n")
 		}
-
-		sb.WriteString("Use these outputs when referencing resources from dependency domains.\n")
-		sb.WriteString("Reference syntax: `${domain.resource.outputs.field}`\n\n")
 	}
 
-	return sb.String()
+	sb.WriteString("Use these outputs when referencing resources from dependency domains.\n")
+	sb.WriteString("Reference syntax: `${domain.resource.outputs.field}`\n\n")
 }
 
 // resolveCLIPath attempts to find the full path to a CLI command.
@@ -3,9 +3,12 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 
+	"github.com/lex00/wetwire-core-go/agent/agents"
 	"github.com/lex00/wetwire-core-go/mcp"
 	"github.com/lex00/wetwire-core-go/scenario"
 )
@@ -157,6 +160,52 @@ func (m *MCPManager) IsConnected(domain string) bool {
 	return ok
 }
 
+// DomainServer returns an agents.MCPServer with only the tools of one
+// domain, unprefixed, for an agent that works on that domain alone.
+func (m *MCPManager) DomainServer(domain string) agents.MCPServer {
+	return &domainServer{manager: m, domain: domain}
+}
+
+// domainServer is the agents.MCPServer returned by DomainServer.
+type domainServer struct {
+	manager *MCPManager
+	domain  string
+}
+
+// ExecuteTool calls a tool on the domain's MCP server. A result flagged as
+// an error is returned as one.
+func (s *domainServer) ExecuteTool(ctx context.Context, name string, args map[string]any) (string, error) {
+	result, err := s.manager.CallTool(ctx, s.domain, name, args)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for _, block := range result.Content {
+		text.WriteString(block.Text)
+	}
+	if result.IsError {
+		return "", errors.New(text.String())
+	}
+	return text.String(), nil
+}
+
+// GetTools returns the domain's tools.
+func (s *domainServer) GetTools() []agents.MCPToolInfo {
+	tools := s.manager.GetTools(s.domain)
+	infos := make([]agents.MCPToolInfo, len(tools))
+	for i, t := range tools {
+		infos[i] = agents.MCPToolInfo{
+			Name:        t.Name,
+			Description: t.Description,
+			InputSchema: t.InputSchema,
+			Parallel:    t.Parallel,
+			ConflictKey: t.ConflictKey,
+		}
+	}
+	return infos
+}
+
 // parsePrefixedTool splits "domain.toolName" into its components.
 func parsePrefixedTool(prefixed string) (domain, toolName string, err error) {
 	for i := 0; i < len(prefixed); i++ {
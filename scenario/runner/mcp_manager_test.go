@@ -3,6 +3,8 @@ package runner
 import (
 	"context"
 	"testing"
+
+	"github.com/lex00/wetwire-core-go/mcp"
 )
 
 func TestParsePrefixedTool(t *testing.T) {
@@ -131,3 +133,19 @@ func TestMCPManager_CallToolNotConnected(t *testing.T) {
 		t.Error("CallTool on non-existent domain should error")
 	}
 }
+
+func TestMCPManager_DomainServer(t *testing.T) {
+	mgr := NewMCPManager("/tmp/test", false)
+	mgr.tools["domain-a"] = []mcp.ToolInfo{{Name: "wetwire_build", Description: "Build"}}
+	mgr.tools["domain-b"] = []mcp.ToolInfo{{Name: "wetwire_lint"}}
+
+	server := mgr.DomainServer("domain-a")
+	tools := server.GetTools()
+	if len(tools) != 1 || tools[0].Name != "wetwire_build" {
+		t.Errorf("GetTools() = %v, want only wetwire_build", tools)
+	}
+
+	if _, err := server.ExecuteTool(context.Background(), "wetwire_build", nil); err == nil {
+		t.Error("ExecuteTool on a domain without a client should error")
+	}
+}
@@ -14,13 +14,20 @@ package runner
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/lex00/wetwire-core-go/agent/agents"
+	"github.com/lex00/wetwire-core-go/agent/events"
+	"github.com/lex00/wetwire-core-go/agent/results"
 	"github.com/lex00/wetwire-core-go/agent/scoring"
 	"github.com/lex00/wetwire-core-go/providers"
 	"github.com/lex00/wetwire-core-go/providers/claude"
@@ -53,6 +60,36 @@ type Config struct {
 
 	// Validate enables validation against scenario rules and expected files
 	Validate bool
+
+	// Resume keeps the existing output and continues each persona that was
+	// interrupted in an earlier run, resuming the Claude Code session saved
+	// in its checkpoint. Personas without a checkpoint run from the start.
+	Resume bool
+
+	// Observer receives lifecycle events from every persona, tagged with
+	// the persona and its session ID. Claude Code runs its own loop, so a
+	// persona's run is reported as a single turn (optional)
+	Observer events.Observer
+
+	// Prices costs usage the CLI reports without a cost, for example for
+	// negotiated pricing (default: providers.DefaultPrices)
+	Prices providers.PriceTable
+}
+
+// checkpointFile is the file in a persona's output directory that records
+// an unfinished run. It is removed once the run completes.
+const checkpointFile = "checkpoint.json"
+
+// resumePrompt asks a resumed session to finish its task.
+const resumePrompt = `The previous run was interrupted before you finished.
+Check which files already exist in the output directory, then complete the task.`
+
+// personaCheckpoint records an unfinished persona run, so that Config.Resume
+// can continue its Claude Code session.
+type personaCheckpoint struct {
+	SessionID string    `json:"session_id"`
+	Prompt    string    `json:"prompt"`
+	StartedAt time.Time `json:"started_at"`
 }
 
 // Result holds the result of a single persona scenario run.
@@ -65,6 +102,9 @@ type Result struct {
 	OutputDir        string
 	Score            *scoring.Score
 	ValidationReport *validator.ValidationReport
+	Usage            providers.Usage
+	CostUSD          float64
+	Session          *results.Session // tool calls and lint cycles reported by the CLI
 }
 
 // Run executes a scenario with all configured personas.
@@ -93,22 +133,36 @@ func Run(ctx context.Context, cfg Config) ([]Result, error) {
 		personas = []string{cfg.SinglePersona}
 	}
 
-	// Clean and create output directory
-	_ = os.RemoveAll(cfg.OutputDir)
+	// Attach images and documents from the scenario's prompts directory
+	attachments, err := scenariopkg.LoadAttachments(cfg.ScenarioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+
+	// Clean and create output directory, keeping it when resuming
+	if !cfg.Resume {
+		_ = os.RemoveAll(cfg.OutputDir)
+	}
 	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	model := scenarioConfig.Model
+	model := scenarioModel(scenarioConfig.Model, os.Stdout)
 	if model != "" {
 		fmt.Printf("Model: %s\n", model)
 	}
 
+	// Personas run in parallel but report to the observer one at a time
+	cfg.Observer = events.Serialized(cfg.Observer)
+	if cfg.Prices == nil {
+		cfg.Prices = providers.DefaultPrices
+	}
+
 	var results []Result
 
 	if len(personas) == 1 {
 		// Single persona: run directly with streaming
-		result := runPersona(ctx, cfg, personas[0], model, scenarioConfig, cfg.Verbose)
+		result := runPersona(ctx, cfg, personas[0], model, scenarioConfig, attachments, cfg.Verbose)
 		results = []Result{result}
 	} else {
 		// Multiple personas: run in parallel without streaming (would be interleaved)
@@ -126,7 +180,7 @@ func Run(ctx context.Context, cfg Config) ([]Result, error) {
 				fmt.Printf("  [%s] Starting...\n", p)
 				mu.Unlock()
 
-				result := runPersona(ctx, cfg, p, model, scenarioConfig, false) // no streaming for parallel
+				result := runPersona(ctx, cfg, p, model, scenarioConfig, attachments, false) // no streaming for parallel
 
 				mu.Lock()
 				results[idx] = result
@@ -149,7 +203,7 @@ func Run(ctx context.Context, cfg Config) ([]Result, error) {
 	return results, nil
 }
 
-func runPersona(ctx context.Context, cfg Config, personaName, model string, scenarioConfig *scenariopkg.ScenarioConfig, verbose bool) Result {
+func runPersona(ctx context.Context, cfg Config, personaName, model string, scenarioConfig *scenariopkg.ScenarioConfig, attachments []providers.ContentBlock, verbose bool) Result {
 	result := Result{
 		Persona: personaName,
 		Files:   make(map[string]string),
@@ -161,7 +215,15 @@ func runPersona(ctx context.Context, cfg Config, personaName, model string, scen
 	if err != nil {
 		return result
 	}
-	_ = os.RemoveAll(absPersonaDir)
+
+	// Resume an interrupted run if asked to and there is one to resume
+	var checkpoint *personaCheckpoint
+	if cfg.Resume {
+		checkpoint, _ = loadCheckpoint(absPersonaDir)
+	}
+	if checkpoint == nil {
+		_ = os.RemoveAll(absPersonaDir)
+	}
 	if err := os.MkdirAll(absPersonaDir, 0755); err != nil {
 		return result
 	}
@@ -184,6 +246,62 @@ Use the Write tool to create files. Use mkdir via Bash if directories are needed
 `, absPersonaDir))
 
 	prompt := promptBuilder.String()
+	startedAt := time.Now()
+	if checkpoint != nil {
+		// Send the session the prompt it was started with
+		prompt = checkpoint.Prompt
+		startedAt = checkpoint.StartedAt
+		if verbose {
+			fmt.Printf("Resuming session %s from %s\n", checkpoint.SessionID, checkpoint.StartedAt.Format(time.RFC3339))
+		}
+	}
+
+	session := results.NewSession(personaName, cfg.ScenarioPath)
+	session.Prices = cfg.Prices
+	emit := func(e events.Event) {
+		if cfg.Observer != nil {
+			e.Time, e.SessionID, e.Persona = time.Now(), session.ID, personaName
+			cfg.Observer.Observe(e)
+		}
+	}
+
+	// Show tool activity alongside streamed text, and report it
+	toolCalls := make(map[string]providers.ContentBlock)
+	toolStarts := make(map[string]time.Time)
+	eventHandler := func(block providers.ContentBlock) {
+		switch block.Type {
+		case "tool_use":
+			if verbose {
+				fmt.Printf("\n[Tool: %s]\n", block.Name)
+			}
+			toolCalls[block.ID], toolStarts[block.ID] = block, time.Now()
+			emit(events.Event{Type: events.ToolStarted, Turn: 1, ToolID: block.ID, Tool: block.Name, Input: block.Input})
+		case "tool_result":
+			call, ok := toolCalls[block.ToolUseID]
+			if !ok {
+				return
+			}
+			emit(events.Event{
+				Type:     events.ToolFinished,
+				Turn:     1,
+				ToolID:   call.ID,
+				Tool:     call.Name,
+				Input:    call.Input,
+				Output:   block.Content,
+				IsError:  block.IsError,
+				Duration: time.Since(toolStarts[call.ID]),
+			})
+		}
+	}
+
+	// Save the session as soon as it starts, so an interrupted run can resume it
+	sessionHandler := func(sessionID string) {
+		_ = saveCheckpoint(absPersonaDir, personaCheckpoint{
+			SessionID: sessionID,
+			Prompt:    prompt,
+			StartedAt: startedAt,
+		})
+	}
 
 	// Create Claude provider
 	provider, err := claude.New(claude.Config{
@@ -192,6 +310,8 @@ Use the Write tool to create files. Use mkdir via Bash if directories are needed
 		Model:          model,
 		AllowedTools:   []string{"Write", "Bash", "Read", "Glob"},
 		PermissionMode: "acceptEdits",
+		EventHandler:   eventHandler,
+		SessionHandler: sessionHandler,
 	})
 	if err != nil {
 		return result
@@ -207,14 +327,26 @@ Use the Write tool to create files. Use mkdir via Bash if directories are needed
 			fmt.Print(text)
 		}
 		responseText.WriteString(text)
+		emit(events.Event{Type: events.TextDelta, Turn: 1, Text: text})
 	}
 
+	message := providers.NewUserMessage(prompt)
+	message.Content = append(message.Content, attachments...)
+
+	messages := []providers.Message{message}
+	if checkpoint != nil {
+		provider.Resume(checkpoint.SessionID, messages)
+		messages = append(messages, providers.NewUserMessage(resumePrompt))
+	}
+
+	emit(events.Event{Type: events.RunStarted, Text: userPrompt})
+	emit(events.Event{Type: events.TurnStarted, Turn: 1})
+	emit(events.Event{Type: events.RequestSent, Turn: 1, Messages: len(messages)})
 	resp, err := provider.StreamMessage(ctx, providers.MessageRequest{
-		Messages: []providers.Message{
-			providers.NewUserMessage(prompt),
-		},
+		Messages: messages,
 	}, streamHandler)
 	result.Duration = time.Since(start)
+	emitFinished(emit, resp, err, result.Duration)
 
 	if verbose {
 		fmt.Println() // newline after streaming
@@ -223,6 +355,10 @@ Use the Write tool to create files. Use mkdir via Bash if directories are needed
 	if err != nil {
 		return result
 	}
+	_ = os.Remove(filepath.Join(absPersonaDir, checkpointFile))
+
+	result.Usage = resp.Usage
+	result.CostUSD = cfg.Prices.Cost(providers.ServedModel(model, resp), resp.Usage)
 
 	// Use streamed text, or extract from response if empty
 	if responseText.Len() > 0 {
@@ -240,6 +376,9 @@ Use the Write tool to create files. Use mkdir via Bash if directories are needed
 	result.Files = findGeneratedFiles(absPersonaDir)
 	result.Success = len(result.Files) > 0
 
+	// Record the tool calls and lint cycles Claude Code reported
+	result.Session = buildSession(session, result, userPrompt, prompt, providers.ServedBy(provider, resp), providers.ServedModel(model, resp), resp)
+
 	// Calculate score
 	result.Score = calculateScore(result, personaName, cfg.ScenarioPath)
 
@@ -257,8 +396,15 @@ Use the Write tool to create files. Use mkdir via Bash if directories are needed
 		}
 	}
 
+	if result.Score != nil {
+		result.Score.TotalTokens = result.Usage.TotalTokens()
+		result.Score.CostUSD = result.CostUSD
+	}
+
 	// Write outputs
+	result.Session.Score = result.Score
 	saveConversation(result, userPrompt, filepath.Join(absPersonaDir, "conversation.txt"))
+	saveSession(result.Session, filepath.Join(absPersonaDir, "session.json"))
 	writePersonaResults(absPersonaDir, result)
 
 	// Generate recording if requested
@@ -280,7 +426,7 @@ func findGeneratedFiles(dir string) map[string]string {
 
 		// Skip our own output files
 		name := info.Name()
-		if name == "conversation.txt" || name == "RESULTS.md" || strings.HasSuffix(name, ".svg") {
+		if name == "conversation.txt" || name == "RESULTS.md" || name == "session.json" || name == checkpointFile || strings.HasSuffix(name, ".svg") {
 			return nil
 		}
 
@@ -301,6 +447,167 @@ func findGeneratedFiles(dir string) map[string]string {
 	return files
 }
 
+// loadCheckpoint reads the checkpoint of an unfinished persona run.
+func loadCheckpoint(dir string) (*personaCheckpoint, error) {
+	data, err := os.ReadFile(filepath.Join(dir, checkpointFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var cp personaCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	if cp.SessionID == "" {
+		return nil, fmt.Errorf("checkpoint has no session")
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint writes the checkpoint of a persona run.
+func saveCheckpoint(dir string, cp personaCheckpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, checkpointFile), data, 0644)
+}
+
+// scenarioModel resolves a scenario model alias to its model ID. Models
+// missing from providers.DefaultModels, such as newer CLI model IDs, are
+// passed through unchanged with a warning written to output.
+func scenarioModel(model string, output io.Writer) string {
+	if model == "" {
+		return ""
+	}
+	if err := providers.DefaultModels.Validate(model); err != nil {
+		_, _ = fmt.Fprintf(output, "Warning: %v; passing it to the provider unchanged\n", err)
+		return model
+	}
+	return providers.DefaultModels.Resolve(model)
+}
+
+// emitFinished reports the end of a persona's single CLI turn and run.
+func emitFinished(emit func(events.Event), resp *providers.MessageResponse, err error, duration time.Duration) {
+	turn := events.Event{Type: events.TurnFinished, Turn: 1, Duration: duration}
+	run := events.Event{Type: events.RunFinished, Duration: duration, Termination: string(agents.TerminationCompleted)}
+	if err != nil {
+		turn.Error, run.Error, run.Termination = err.Error(), err.Error(), string(agents.TerminationError)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			run.Termination = string(agents.TerminationCancelled)
+		}
+	} else {
+		turn.StopReason, turn.Usage, run.Usage = resp.StopReason, resp.Usage, resp.Usage
+	}
+	emit(turn)
+	emit(run)
+}
+
+// buildSession records the prompt, tool calls, lint cycles and usage of a
+// CLI-backed run in session. Tool calls are matched to their results by ID,
+// and any call that runs a linter counts as a lint cycle.
+func buildSession(session *results.Session, result Result, userPrompt, prompt, provider, model string, resp *providers.MessageResponse) *results.Session {
+	session.InitialPrompt = userPrompt
+	session.AddMessage("developer", prompt)
+
+	msg := results.Message{
+		Role:      "runner",
+		Content:   result.Response,
+		Timestamp: time.Now(),
+	}
+	calls := make(map[string]int)
+	var lastIssues int
+	if n := len(session.LintCycles); n > 0 {
+		lastIssues = session.LintCycles[n-1].IssueCount
+	}
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "tool_use":
+			calls[block.ID] = len(msg.ToolCalls)
+			msg.ToolCalls = append(msg.ToolCalls, results.ToolCall{
+				Name:  block.Name,
+				Input: string(block.Input),
+			})
+		case "tool_result":
+			idx, ok := calls[block.ToolUseID]
+			if !ok {
+				continue
+			}
+			call := &msg.ToolCalls[idx]
+			call.Output = block.Content
+			if isLintCall(*call) {
+				var issues []string
+				if block.IsError {
+					issues = lintIssues(block.Content)
+				}
+				session.AddLintCycle(issues, max(lastIssues-len(issues), 0), !block.IsError)
+				lastIssues = len(issues)
+			}
+		}
+	}
+	session.Messages = append(session.Messages, msg)
+
+	if !resp.Usage.IsZero() {
+		session.AddUsage(provider, model, resp.Usage)
+	}
+	for file := range result.Files {
+		session.GeneratedFiles = append(session.GeneratedFiles, file)
+	}
+	sort.Strings(session.GeneratedFiles)
+
+	session.Complete()
+	return session
+}
+
+// isLintCall reports whether a tool call runs a linter, either as a
+// dedicated tool (e.g. wetwire_lint) or as a shell command.
+func isLintCall(call results.ToolCall) bool {
+	if strings.Contains(strings.ToLower(call.Name), "lint") {
+		return true
+	}
+	if call.Name != "Bash" {
+		return false
+	}
+
+	var input struct {
+		Command string `json:"command"`
+	}
+	if err := json.Unmarshal([]byte(call.Input), &input); err != nil {
+		return false
+	}
+	return strings.Contains(input.Command, "lint")
+}
+
+// lintIssues extracts issue messages from failed lint output.
+// JSON lint results are parsed; other output is kept as a single issue.
+func lintIssues(output string) []string {
+	var lintResult struct {
+		Issues []struct {
+			Message string `json:"message"`
+		} `json:"issues"`
+	}
+	if json.Unmarshal([]byte(output), &lintResult) == nil && len(lintResult.Issues) > 0 {
+		issues := make([]string, len(lintResult.Issues))
+		for i, issue := range lintResult.Issues {
+			issues[i] = issue.Message
+		}
+		return issues
+	}
+
+	if output = strings.TrimSpace(output); output != "" {
+		return []string{output}
+	}
+	return nil
+}
+
+// lintCycles returns the lint cycles of a session, or nil if there is none.
+func lintCycles(session *results.Session) []results.LintCycle {
+	if session == nil {
+		return nil
+	}
+	return session.LintCycles
+}
+
 func loadSystemPrompt(scenarioPath string) string {
 	defaultPrompt := `You are a helpful infrastructure engineer assistant.
 Your task is to help users create infrastructure files based on their requirements.
@@ -356,8 +663,12 @@ func calculateScore(result Result, persona, scenarioPath string) *scoring.Score
 	score.Completeness.Rating = rating
 	score.Completeness.Notes = notes
 
-	// Lint Quality: Deferred to domain tools
-	if len(result.Files) > 0 {
+	// Lint Quality: Based on lint runs observed in the session, if any
+	if cycles := lintCycles(result.Session); len(cycles) > 0 {
+		rating, notes := scoring.ScoreLintQuality(len(cycles), cycles[len(cycles)-1].Passed)
+		score.LintQuality.Rating = rating
+		score.LintQuality.Notes = notes
+	} else if len(result.Files) > 0 {
 		score.LintQuality.Rating = scoring.RatingExcellent
 		score.LintQuality.Notes = "Deferred to domain tools"
 	} else {
@@ -463,12 +774,21 @@ func saveConversation(result Result, userPrompt, outputPath string) {
 	_ = os.WriteFile(outputPath, buf.Bytes(), 0644)
 }
 
+func saveSession(session *results.Session, outputPath string) {
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(outputPath, data, 0644)
+}
+
 func writePersonaResults(dir string, result Result) {
 	var buf bytes.Buffer
 
 	buf.WriteString(fmt.Sprintf("# Scenario Results: %s\n\n", result.Persona))
 	buf.WriteString(fmt.Sprintf("**Status:** %s\n", map[bool]string{true: "SUCCESS", false: "FAILED"}[result.Success]))
-	buf.WriteString(fmt.Sprintf("**Duration:** %s\n\n", result.Duration.Round(time.Millisecond)))
+	buf.WriteString(fmt.Sprintf("**Duration:** %s\n", result.Duration.Round(time.Millisecond)))
+	buf.WriteString(fmt.Sprintf("**Usage:** %d tokens ($%.4f)\n\n", result.Usage.TotalTokens(), result.CostUSD))
 
 	if result.Score != nil {
 		buf.WriteString("## Score\n\n")
@@ -487,6 +807,32 @@ func writePersonaResults(dir string, result Result) {
 		buf.WriteString("\n")
 	}
 
+	if result.Session != nil && len(result.Session.Messages) > 0 {
+		calls := result.Session.Messages[len(result.Session.Messages)-1].ToolCalls
+		if len(calls) > 0 || len(result.Session.LintCycles) > 0 {
+			buf.WriteString("## Tool Calls\n\n")
+			counts := make(map[string]int)
+			var names []string
+			for _, call := range calls {
+				if counts[call.Name] == 0 {
+					names = append(names, call.Name)
+				}
+				counts[call.Name]++
+			}
+			for _, name := range names {
+				buf.WriteString(fmt.Sprintf("- %s: %d\n", name, counts[name]))
+			}
+			for _, cycle := range result.Session.LintCycles {
+				status := "❌"
+				if cycle.Passed {
+					status = "✅"
+				}
+				buf.WriteString(fmt.Sprintf("- Lint cycle %d: %s (%d issues)\n", cycle.Cycle, status, cycle.IssueCount))
+			}
+			buf.WriteString("\n")
+		}
+	}
+
 	buf.WriteString("## Generated Files\n\n")
 	for file := range result.Files {
 		buf.WriteString(fmt.Sprintf("- [%s](%s)\n", file, file))
@@ -551,10 +897,15 @@ func writeSummary(outputDir string, results []Result, generateRecordings bool) {
 	buf.WriteString(fmt.Sprintf("**Date:** %s\n\n", time.Now().Format(time.RFC3339)))
 
 	buf.WriteString("## Results by Persona\n\n")
-	buf.WriteString("| Persona | Status | Score | Duration |\n")
-	buf.WriteString("|---------|--------|-------|----------|\n")
+	buf.WriteString("| Persona | Status | Score | Duration | Tokens | Cost |\n")
+	buf.WriteString("|---------|--------|-------|----------|--------|------|\n")
 
+	var totalUsage providers.Usage
+	var totalCost float64
 	for _, r := range results {
+		totalUsage = totalUsage.Add(r.Usage)
+		totalCost += r.CostUSD
+
 		status := "FAILED"
 		if r.Success {
 			status = "SUCCESS"
@@ -565,10 +916,12 @@ func writeSummary(outputDir string, results []Result, generateRecordings bool) {
 			scoreStr = fmt.Sprintf("%d/12", r.Score.Total())
 		}
 
-		buf.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
-			r.Persona, status, scoreStr, r.Duration.Round(time.Millisecond)))
+		buf.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %d | $%.4f |\n",
+			r.Persona, status, scoreStr, r.Duration.Round(time.Millisecond), r.Usage.TotalTokens(), r.CostUSD))
 	}
 
+	buf.WriteString(fmt.Sprintf("\n**Total usage:** %d tokens ($%.4f)\n", totalUsage.TotalTokens(), totalCost))
+
 	buf.WriteString("\n## Output Directories\n\n")
 	for _, r := range results {
 		buf.WriteString(fmt.Sprintf("- [%s](./%s/RESULTS.md)\n", r.Persona, r.Persona))
@@ -1,9 +1,19 @@
 package runner
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/lex00/wetwire-core-go/agent/events"
+	"github.com/lex00/wetwire-core-go/agent/results"
+	"github.com/lex00/wetwire-core-go/providers"
 )
 
 func TestDefaultPersonas(t *testing.T) {
@@ -134,6 +144,7 @@ func TestFindGeneratedFiles(t *testing.T) {
 		_ = os.WriteFile(filepath.Join(tmpDir, "conversation.txt"), []byte("excluded"), 0644)
 		_ = os.WriteFile(filepath.Join(tmpDir, "RESULTS.md"), []byte("excluded"), 0644)
 		_ = os.WriteFile(filepath.Join(tmpDir, "test.svg"), []byte("excluded"), 0644)
+		_ = os.WriteFile(filepath.Join(tmpDir, checkpointFile), []byte("excluded"), 0644)
 
 		files := findGeneratedFiles(tmpDir)
 		if _, ok := files["conversation.txt"]; ok {
@@ -145,6 +156,9 @@ func TestFindGeneratedFiles(t *testing.T) {
 		if _, ok := files["test.svg"]; ok {
 			t.Error("test.svg should be excluded")
 		}
+		if _, ok := files[checkpointFile]; ok {
+			t.Error("checkpoint should be excluded")
+		}
 	})
 
 	t.Run("finds nested files", func(t *testing.T) {
@@ -159,6 +173,31 @@ func TestFindGeneratedFiles(t *testing.T) {
 	})
 }
 
+func TestCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := loadCheckpoint(dir); !os.IsNotExist(err) {
+		t.Errorf("expected no checkpoint, got %v", err)
+	}
+
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := saveCheckpoint(dir, personaCheckpoint{SessionID: "session-1", Prompt: "Create a bucket", StartedAt: started}); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := loadCheckpoint(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.SessionID != "session-1" || cp.Prompt != "Create a bucket" || !cp.StartedAt.Equal(started) {
+		t.Errorf("unexpected checkpoint %+v", cp)
+	}
+
+	_ = os.WriteFile(filepath.Join(dir, checkpointFile), []byte(`{"prompt":"Create a bucket"}`), 0644)
+	if _, err := loadCheckpoint(dir); err == nil {
+		t.Error("expected an error for a checkpoint without a session")
+	}
+}
+
 func TestResultSuccess(t *testing.T) {
 	t.Run("success when files generated", func(t *testing.T) {
 		r := Result{
@@ -231,3 +270,130 @@ func TestConversationSession(t *testing.T) {
 		t.Errorf("expected second message role 'runner', got %q", messages[1].Role)
 	}
 }
+
+func TestBuildSession(t *testing.T) {
+	result := Result{
+		Persona:  "expert",
+		Response: "Done",
+		Files:    map[string]string{"b.go": "", "a.go": ""},
+	}
+	resp := &providers.MessageResponse{
+		Content: []providers.ContentBlock{
+			{Type: "tool_use", ID: "t1", Name: "Write", Input: json.RawMessage(`{"file_path":"a.go"}`)},
+			providers.NewToolResult("t1", "File created", false),
+			{Type: "tool_use", ID: "t2", Name: "Bash", Input: json.RawMessage(`{"command":"wetwire-aws lint ."}`)},
+			providers.NewToolResult("t2", `{"success":false,"issues":[{"message":"missing tag"}]}`, true),
+			{Type: "tool_use", ID: "t3", Name: "mcp__wetwire__wetwire_lint", Input: json.RawMessage(`{}`)},
+			providers.NewToolResult("t3", "ok", false),
+			{Type: "text", Text: "Done"},
+		},
+		Usage: providers.Usage{InputTokens: 10, OutputTokens: 5},
+	}
+
+	session := buildSession(results.NewSession("expert", "./scenario"), result, "user prompt", "full prompt", "claude", "sonnet", resp)
+
+	if session.InitialPrompt != "user prompt" {
+		t.Errorf("expected initial prompt 'user prompt', got %q", session.InitialPrompt)
+	}
+	if len(session.Messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(session.Messages))
+	}
+
+	calls := session.Messages[1].ToolCalls
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 tool calls, got %d", len(calls))
+	}
+	if calls[0].Name != "Write" || calls[0].Output != "File created" {
+		t.Errorf("unexpected first tool call: %+v", calls[0])
+	}
+
+	if len(session.LintCycles) != 2 {
+		t.Fatalf("expected 2 lint cycles, got %d", len(session.LintCycles))
+	}
+	if session.LintCycles[0].Passed || len(session.LintCycles[0].Issues) != 1 || session.LintCycles[0].Issues[0] != "missing tag" {
+		t.Errorf("unexpected first lint cycle: %+v", session.LintCycles[0])
+	}
+	if session.LintCycles[0].FixedCount != 0 {
+		t.Errorf("expected first lint cycle to fix nothing, got %d", session.LintCycles[0].FixedCount)
+	}
+	if !session.LintCycles[1].Passed {
+		t.Error("expected second lint cycle to pass")
+	}
+	if session.LintCycles[1].FixedCount != 1 {
+		t.Errorf("expected second lint cycle to fix 1 issue, got %d", session.LintCycles[1].FixedCount)
+	}
+
+	if len(session.GeneratedFiles) != 2 || session.GeneratedFiles[0] != "a.go" {
+		t.Errorf("unexpected generated files: %v", session.GeneratedFiles)
+	}
+	if session.TotalUsage().TotalTokens() != 15 {
+		t.Errorf("expected 15 tokens, got %d", session.TotalUsage().TotalTokens())
+	}
+
+	result.Session = session
+	score := calculateScore(result, "expert", "./scenario")
+	if score.LintQuality.Notes != "Passed on cycle 2" {
+		t.Errorf("unexpected lint quality notes: %q", score.LintQuality.Notes)
+	}
+}
+
+func TestScenarioModel(t *testing.T) {
+	tests := []struct {
+		model   string
+		want    string
+		warning bool
+	}{
+		{model: "", want: ""},
+		{model: "sonnet", want: "claude-sonnet-4-5-20250929"},
+		{model: "claude-sonnet-4-20250514", want: "claude-sonnet-4-20250514"},
+		{model: "claude-future-9", want: "claude-future-9", warning: true},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if got := scenarioModel(tt.model, &out); got != tt.want {
+			t.Errorf("scenarioModel(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+		if warned := strings.Contains(out.String(), "Warning"); warned != tt.warning {
+			t.Errorf("scenarioModel(%q) output = %q, want warning %v", tt.model, out.String(), tt.warning)
+		}
+	}
+}
+
+func TestEmitFinished(t *testing.T) {
+	var got []events.Event
+	emit := func(e events.Event) { got = append(got, e) }
+
+	resp := &providers.MessageResponse{StopReason: providers.StopReasonEndTurn, Usage: providers.Usage{InputTokens: 10, OutputTokens: 5}}
+	emitFinished(emit, resp, nil, time.Second)
+	if len(got) != 2 || got[0].Type != events.TurnFinished || got[1].Type != events.RunFinished {
+		t.Fatalf("expected turn and run finished events, got %+v", got)
+	}
+	if got[1].Termination != "completed" || got[1].Usage.TotalTokens() != 15 || got[1].Duration != time.Second {
+		t.Errorf("unexpected run finished event %+v", got[1])
+	}
+
+	got = nil
+	emitFinished(emit, nil, fmt.Errorf("claude failed: %w", context.Canceled), time.Second)
+	if got[0].Error == "" || got[1].Termination != "cancelled" {
+		t.Errorf("expected a cancelled run, got %+v", got)
+	}
+}
+
+func TestIsLintCall(t *testing.T) {
+	tests := []struct {
+		call results.ToolCall
+		want bool
+	}{
+		{results.ToolCall{Name: "wetwire_lint"}, true},
+		{results.ToolCall{Name: "Bash", Input: `{"command":"golangci-lint run"}`}, true},
+		{results.ToolCall{Name: "Bash", Input: `{"command":"mkdir -p infra"}`}, false},
+		{results.ToolCall{Name: "Write", Input: `{"file_path":"lint.go"}`}, false},
+	}
+
+	for _, tc := range tests {
+		if got := isLintCall(tc.call); got != tc.want {
+			t.Errorf("isLintCall(%+v) = %v, want %v", tc.call, got, tc.want)
+		}
+	}
+}
@@ -3,10 +3,13 @@ package scenario
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"time"
 
+	"github.com/lex00/wetwire-core-go/agent/agents"
+	"github.com/lex00/wetwire-core-go/agent/events"
 	"github.com/lex00/wetwire-core-go/agent/results"
 	"github.com/lex00/wetwire-core-go/mcp"
 	"github.com/lex00/wetwire-core-go/providers"
@@ -19,6 +22,7 @@ type ScenarioAgent struct {
 	output    io.Writer
 	model     string
 	session   *results.Session
+	observer  events.Observer
 }
 
 // ScenarioAgentConfig configures the ScenarioAgent.
@@ -28,6 +32,7 @@ type ScenarioAgentConfig struct {
 	Output    io.Writer
 	Model     string           // Optional, defaults to claude-sonnet-4-20250514
 	Session   *results.Session // Optional, for result tracking
+	Observer  events.Observer  // Optional, receives lifecycle events
 }
 
 // NewScenarioAgent creates a new ScenarioAgent.
@@ -48,6 +53,7 @@ func NewScenarioAgent(config ScenarioAgentConfig) *ScenarioAgent {
 		output:    output,
 		model:     model,
 		session:   config.Session,
+		observer:  config.Observer,
 	}
 }
 
@@ -58,7 +64,35 @@ func (a *ScenarioAgent) Session() *results.Session {
 
 // Run executes the agent with the given prompt.
 // The agent runs autonomously without developer interaction.
+// Responses are not streamed, so the observer receives the text of each
+// response as a single delta.
 func (a *ScenarioAgent) Run(ctx context.Context, prompt string) error {
+	start := time.Now()
+	a.emit(events.Event{Type: events.RunStarted, Text: prompt})
+
+	usage, err := a.run(ctx, prompt)
+
+	termination := agents.TerminationCompleted
+	var errText string
+	if err != nil {
+		termination = agents.TerminationError
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			termination = agents.TerminationCancelled
+		}
+		errText = err.Error()
+	}
+	a.emit(events.Event{
+		Type:        events.RunFinished,
+		Termination: string(termination),
+		Usage:       usage,
+		Duration:    time.Since(start),
+		Error:       errText,
+	})
+	return err
+}
+
+// run executes the agentic loop and returns the total usage.
+func (a *ScenarioAgent) run(ctx context.Context, prompt string) (providers.Usage, error) {
 	systemPrompt := `You are an autonomous infrastructure code generator for multi-domain scenarios.
 
 Your job is to generate infrastructure code across multiple domains using the wetwire framework.
@@ -86,33 +120,56 @@ Follow the execution plan provided in the user prompt, respecting domain depende
 	messages := []providers.Message{
 		providers.NewUserMessage(prompt),
 	}
+	var usage providers.Usage
 
 	// Agentic loop
-	for {
+	for turn := 1; ; turn++ {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return usage, ctx.Err()
 		default:
 		}
+		a.emit(events.Event{Type: events.TurnStarted, Turn: turn})
 
 		req := providers.MessageRequest{
 			Model:     a.model,
-			MaxTokens: 4096,
+			MaxTokens: providers.DefaultMaxTokens,
 			System:    systemPrompt,
 			Messages:  messages,
 			Tools:     tools,
 		}
 
+		a.emit(events.Event{Type: events.RequestSent, Turn: turn, Messages: len(messages)})
+		turnStart := time.Now()
 		resp, err := a.provider.CreateMessage(ctx, req)
 		if err != nil {
-			return fmt.Errorf("API call failed: %w", err)
+			a.emit(events.Event{Type: events.TurnFinished, Turn: turn, Duration: time.Since(turnStart), Error: err.Error()})
+			return usage, fmt.Errorf("API call failed: %w", err)
 		}
+		usage = usage.Add(resp.Usage)
+		for _, block := range resp.Content {
+			if block.Type == "text" && block.Text != "" {
+				a.emit(events.Event{Type: events.TextDelta, Turn: turn, Text: block.Text})
+			}
+		}
+		a.emit(events.Event{
+			Type:       events.TurnFinished,
+			Turn:       turn,
+			StopReason: resp.StopReason,
+			Usage:      resp.Usage,
+			Duration:   time.Since(turnStart),
+		})
 
 		// Add assistant response to messages
 		messages = append(messages, providers.NewAssistantMessage(resp.Content))
 
 		// Track assistant response in session
 		if a.session != nil {
+			if !resp.Usage.IsZero() {
+				a.session.AddUsage(providers.ServedBy(a.provider, resp), providers.ServedModel(a.model, resp), resp.Usage)
+			}
+			a.session.AddThinking(resp.Content)
+
 			// Extract text content from response
 			var textContent string
 			var toolCalls []results.ToolCall
@@ -148,10 +205,22 @@ Follow the execution plan provided in the user prompt, respecting domain depende
 
 			for _, block := range resp.Content {
 				if block.Type == "tool_use" {
+					a.emit(events.Event{Type: events.ToolStarted, Turn: turn, ToolID: block.ID, Tool: block.Name, Input: block.Input})
+					toolStart := time.Now()
 					result, err := a.executeMCPTool(ctx, block.Name, block.Input)
 					if err != nil {
 						result = fmt.Sprintf("Error: %v", err)
 					}
+					a.emit(events.Event{
+						Type:     events.ToolFinished,
+						Turn:     turn,
+						ToolID:   block.ID,
+						Tool:     block.Name,
+						Input:    block.Input,
+						Output:   result,
+						IsError:  err != nil,
+						Duration: time.Since(toolStart),
+					})
 					toolResults = append(toolResults, providers.NewToolResult(
 						block.ID,
 						result,
@@ -164,7 +233,20 @@ Follow the execution plan provided in the user prompt, respecting domain depende
 		}
 	}
 
-	return nil
+	return usage, nil
+}
+
+// emit stamps an event with the time and the session, and delivers it to
+// the observer, if any.
+func (a *ScenarioAgent) emit(e events.Event) {
+	if a.observer == nil {
+		return
+	}
+	e.Time = time.Now()
+	if a.session != nil {
+		e.SessionID, e.Persona = a.session.ID, a.session.Persona
+	}
+	a.observer.Observe(e)
 }
 
 // getMCPTools converts MCP server tools to provider tools.
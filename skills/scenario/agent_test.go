@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/lex00/wetwire-core-go/agent/events"
+	"github.com/lex00/wetwire-core-go/agent/results"
 	"github.com/lex00/wetwire-core-go/mcp"
 	"github.com/lex00/wetwire-core-go/providers"
 	"github.com/stretchr/testify/assert"
@@ -128,6 +130,56 @@ func TestScenarioAgent_Run_WithToolCalls(t *testing.T) {
 	assert.Equal(t, 2, provider.callCount)
 }
 
+func TestScenarioAgent_Run_Observer(t *testing.T) {
+	provider := &mockProvider{
+		responses: []*providers.MessageResponse{
+			{
+				Content: []providers.ContentBlock{
+					{Type: "text", Text: "Initializing"},
+					{Type: "tool_use", ID: "tool-1", Name: "test_tool", Input: json.RawMessage(`{}`)},
+				},
+				StopReason: providers.StopReasonToolUse,
+				Usage:      providers.Usage{InputTokens: 10, OutputTokens: 5},
+			},
+		},
+	}
+
+	server := mcp.NewServer(mcp.Config{Name: "test"})
+	server.RegisterTool("test_tool", "Test tool", func(ctx context.Context, args map[string]any) (string, error) {
+		return "tool result", nil
+	})
+
+	var got []events.Event
+	session := results.NewSession("beginner", "multi-domain")
+	agent := NewScenarioAgent(ScenarioAgentConfig{
+		Provider:  provider,
+		MCPServer: server,
+		Session:   session,
+		Observer:  events.Func(func(e events.Event) { got = append(got, e) }),
+	})
+
+	require.NoError(t, agent.Run(context.Background(), "test prompt"))
+
+	var types []events.Type
+	for _, e := range got {
+		types = append(types, e.Type)
+		assert.Equal(t, session.ID, e.SessionID)
+		assert.Equal(t, "beginner", e.Persona)
+	}
+	assert.Equal(t, []events.Type{
+		events.RunStarted,
+		events.TurnStarted, events.RequestSent, events.TextDelta, events.TurnFinished,
+		events.ToolStarted, events.ToolFinished,
+		events.TurnStarted, events.RequestSent, events.TextDelta, events.TurnFinished,
+		events.RunFinished,
+	}, types)
+
+	assert.Equal(t, "tool result", got[6].Output)
+	last := got[len(got)-1]
+	assert.Equal(t, "completed", last.Termination)
+	assert.Equal(t, 15, last.Usage.TotalTokens())
+}
+
 func TestScenarioAgent_GetMCPTools(t *testing.T) {
 	provider := &mockProvider{}
 	server := mcp.NewServer(mcp.Config{Name: "test"})
@@ -129,6 +129,7 @@ validation:
 
 	// Create skill WITH provider and MCP server
 	skill := New(provider, server)
+	skill.SetOutputDir(filepath.Join(tmpDir, "output"))
 	var buf bytes.Buffer
 	skill.SetOutput(&buf)
 
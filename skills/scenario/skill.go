@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/lex00/wetwire-core-go/agent/events"
 	"github.com/lex00/wetwire-core-go/agent/results"
 	"github.com/lex00/wetwire-core-go/mcp"
 	"github.com/lex00/wetwire-core-go/providers"
@@ -22,6 +23,7 @@ type Skill struct {
 	mcpServer *mcp.Server
 	outputDir string // Directory for results output
 	persona   string // Persona name for results tracking
+	observer  events.Observer
 }
 
 // New creates a new scenario skill.
@@ -45,6 +47,11 @@ func (s *Skill) SetPersona(persona string) {
 	s.persona = persona
 }
 
+// SetObserver sets the observer that receives the agent's lifecycle events.
+func (s *Skill) SetObserver(observer events.Observer) {
+	s.observer = observer
+}
+
 // SetOutput sets the output writer for the skill.
 func (s *Skill) SetOutput(w io.Writer) {
 	s.output = w
@@ -170,6 +177,7 @@ func (s *Skill) executeScenario(ctx context.Context, config *scenarioPkg.Scenari
 		MCPServer: s.mcpServer,
 		Output:    s.output,
 		Session:   session,
+		Observer:  s.observer,
 	})
 
 	// Run the agent autonomously (no developer interaction)